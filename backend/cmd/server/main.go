@@ -3,6 +3,7 @@ package main
 import (
 	"log"
 	"os"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -10,9 +11,21 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/recover"
 	"github.com/gofiber/swagger"
 	"github.com/joho/godotenv"
+	"github.com/ollama-web-api/internal/backends"
+	"github.com/ollama-web-api/internal/batches"
+	"github.com/ollama-web-api/internal/clientip"
 	"github.com/ollama-web-api/internal/database"
 	"github.com/ollama-web-api/internal/handlers"
+	"github.com/ollama-web-api/internal/jobs"
+	"github.com/ollama-web-api/internal/jwtkeys"
+	"github.com/ollama-web-api/internal/loginguard"
 	"github.com/ollama-web-api/internal/middleware"
+	"github.com/ollama-web-api/internal/oidc"
+	"github.com/ollama-web-api/internal/queue"
+	"github.com/ollama-web-api/internal/ratelimit"
+	"github.com/ollama-web-api/internal/roles"
+	"github.com/ollama-web-api/internal/sessions"
+	"github.com/ollama-web-api/internal/users"
 
 	_ "github.com/ollama-web-api/docs" // Import swagger docs
 )
@@ -43,13 +56,56 @@ func main() {
 		log.Println("No .env file found, using environment variables")
 	}
 
+	// Load the keys that sign and verify admin tokens
+	if err := jwtkeys.Setup(); err != nil {
+		log.Fatal("Invalid JWT key configuration:", err)
+	}
+
 	// Connect to database
 	if err := database.ConnectDB(); err != nil {
 		log.Fatal("Failed to connect to database:", err)
 	}
 
+	// Create the first user from ADMIN_USER and ADMIN_PASSWORD
+	if err := users.Bootstrap(); err != nil {
+		log.Fatal("Failed to create the first user:", err)
+	}
+
+	// Configure single sign-on through an OpenID Connect provider
+	if err := oidc.Setup(); err != nil {
+		log.Fatal("Invalid OpenID Connect configuration:", err)
+	}
+
+	// Configure how long access tokens and sessions last
+	sessions.Setup()
+
+	// Configure the backoff and lockout after failed logins
+	loginguard.Setup()
+
+	// Configure the proxies whose X-Forwarded-For is trusted
+	clientip.Setup()
+
+	// Select the rate limit store (in-process or shared through Postgres)
+	ratelimit.Setup()
+
+	// Build the Ollama backend pool and start health checks
+	backends.Setup()
+
+	// Configure the request queue in front of the backends
+	queue.Setup()
+
+	// Start the workers for background generation jobs
+	jobs.Setup(handlers.RunGenerationJob)
+
+	// Start the worker for JSONL batches
+	batches.Setup(handlers.RunBatchItem)
+
 	// Create Fiber app
 	app := fiber.New(fiber.Config{
+		// Bodies are streamed and read by the BodyLimit middleware, so only
+		// batch uploads may be larger than the default limit
+		StreamRequestBody:            true,
+		DisablePreParseMultipartForm: true,
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
 			code := fiber.StatusInternalServerError
 			if e, ok := err.(*fiber.Error); ok {
@@ -66,11 +122,19 @@ func main() {
 	app.Use(recover.New())
 	app.Use(logger.New())
 	app.Use(cors.New(cors.Config{
-		AllowOrigins: "*",
-		AllowHeaders: "Origin, Content-Type, Accept, Authorization, X-API-Key",
-		AllowMethods: "GET, POST, PUT, PATCH, DELETE, OPTIONS",
+		AllowOrigins:  "*",
+		AllowHeaders:  "Origin, Content-Type, Accept, Authorization, X-API-Key",
+		ExposeHeaders: "Retry-After, X-RateLimit-Limit-Requests, X-RateLimit-Remaining-Requests, X-RateLimit-Reset-Requests, X-RateLimit-Limit-Tokens, X-RateLimit-Remaining-Tokens, X-RateLimit-Reset-Tokens, X-Quota-Warning, X-Queue-Position, Location",
+		AllowMethods:  "GET, POST, PUT, PATCH, DELETE, OPTIONS",
+	}))
+	// Batch uploads are read by their route once the API key is checked
+	app.Use(middleware.BodyLimit(middleware.DefaultBodyLimit, func(c *fiber.Ctx) bool {
+		return c.Method() == fiber.MethodPost && strings.TrimSuffix(c.Path(), "/") == "/api/ollama/batches"
 	}))
 
+	// Public keys for other services to verify admin tokens
+	app.Get("/.well-known/jwks.json", handlers.JWKS)
+
 	// API routes
 	api := app.Group("/api")
 
@@ -87,31 +151,118 @@ func main() {
 	// Auth routes (no authentication required)
 	auth := api.Group("/auth")
 	auth.Post("/login", handlers.Login)
+	auth.Post("/refresh", handlers.RefreshToken)
+	auth.Post("/logout", middleware.AuthRequired(), handlers.Logout)
+	auth.Post("/2fa/verify", handlers.VerifyTwoFactor)
+	auth.Get("/oidc/config", handlers.OIDCConfig)
+	auth.Get("/oidc/login", handlers.OIDCLogin)
+	auth.Get("/oidc/callback", handlers.OIDCCallback)
+
+	// Two-factor routes need no role, so users the security policy requires to
+	// set up two-factor authentication can still do so
+	auth.Get("/2fa", middleware.AuthRequired(), handlers.GetTwoFactorStatus)
+	auth.Post("/2fa/setup", middleware.AuthRequired(), handlers.SetupTwoFactor)
+	auth.Post("/2fa/enable", middleware.AuthRequired(), handlers.EnableTwoFactor)
+	auth.Post("/2fa/disable", middleware.AuthRequired(), handlers.DisableTwoFactor)
+	auth.Post("/2fa/recovery-codes", middleware.AuthRequired(), handlers.RegenerateRecoveryCodes)
 
 	// Validate API key (project key) - used by frontend to check validity
 	api.Get("/validate_key", middleware.ValidateAPIKey(), handlers.ValidateProjectKey)
 
-	// Project routes (admin authentication required)
+	// Admin routes require a JWT and at least the role given per route
+	viewer := middleware.RequireRole(roles.Viewer)
+	operator := middleware.RequireRole(roles.Operator)
+	admin := middleware.RequireRole(roles.Admin)
+	owner := middleware.RequireRole(roles.Owner)
+
+	// Project routes
 	projects := api.Group("/projects", middleware.AuthRequired())
-	projects.Get("/", handlers.ListProjects)
-	projects.Post("/", handlers.CreateProject)
-	projects.Get("/:id", handlers.GetProject)
-	projects.Put("/:id", handlers.UpdateProject)
-	projects.Patch("/:id/toggle", handlers.ToggleProjectStatus)
-	projects.Delete("/:id", handlers.DeleteProject)
-
-	// Model assignment routes (admin authentication required)
-	projects.Get("/:id/models", handlers.ListProjectModels)
-	projects.Post("/:id/models", handlers.AssignModel)
-	projects.Delete("/:id/models/:modelId", handlers.UnassignModel)
+	projects.Get("/", viewer, handlers.ListProjects)
+	projects.Post("/", admin, handlers.CreateProject)
+	projects.Get("/:id", viewer, handlers.GetProject)
+	projects.Put("/:id", admin, handlers.UpdateProject)
+	projects.Patch("/:id/toggle", admin, handlers.ToggleProjectStatus)
+	projects.Post("/:id/webhook/rotate", admin, handlers.RotateWebhookSecret)
+	projects.Delete("/:id", admin, handlers.DeleteProject)
+	projects.Get("/:id/quota", viewer, handlers.GetProjectQuota)
+	projects.Post("/:id/quota/topups", admin, handlers.GrantQuotaTopUp)
+
+	// API key routes
+	projects.Get("/:id/keys", viewer, handlers.ListAPIKeys)
+	projects.Post("/:id/keys", admin, handlers.CreateAPIKey)
+	projects.Delete("/:id/keys/:keyId", admin, handlers.RevokeAPIKey)
+	projects.Post("/:id/keys/:keyId/rotate", admin, handlers.RotateAPIKey)
+
+	// Model assignment routes
+	projects.Get("/:id/models", viewer, handlers.ListProjectModels)
+	projects.Post("/:id/models", operator, handlers.AssignModel)
+	projects.Put("/:id/models/:modelId", operator, handlers.UpdateModelSettings)
+	projects.Delete("/:id/models/:modelId", operator, handlers.UnassignModel)
 
 	// Ollama routes
 	ollama := api.Group("/ollama")
-	ollama.Get("/models", middleware.AuthRequired(), handlers.ListOllamaModels)
-	ollama.Get("/models/running", middleware.AuthRequired(), handlers.ListRunningOllamaModels)
-	ollama.Post("/models/pull", middleware.AuthRequired(), handlers.PullOllamaModel)
-	ollama.Delete("/models/delete", middleware.AuthRequired(), handlers.DeleteOllamaModel)
+	ollama.Get("/models", middleware.AuthRequired(), viewer, handlers.ListOllamaModels)
+	ollama.Get("/models/running", middleware.AuthRequired(), viewer, handlers.ListRunningOllamaModels)
+	ollama.Post("/models/pull", middleware.AuthRequired(), operator, handlers.PullOllamaModel)
+	ollama.Post("/models/unload", middleware.AuthRequired(), operator, handlers.UnloadOllamaModel)
+	ollama.Delete("/models/delete", middleware.AuthRequired(), admin, handlers.DeleteOllamaModel)
 	ollama.Post("/generate", middleware.ValidateAPIKey(), handlers.OllamaGenerate)
+	ollama.Post("/chat", middleware.ValidateAPIKey(), handlers.OllamaChat)
+	ollama.Post("/embed", middleware.ValidateAPIKey(), handlers.OllamaEmbed)
+	ollama.Post("/jobs", middleware.ValidateAPIKey(), handlers.CreateGenerationJob)
+	ollama.Get("/jobs/:id", middleware.ValidateAPIKey(), handlers.GetGenerationJob)
+	ollama.Post("/jobs/:id/cancel", middleware.ValidateAPIKey(), handlers.CancelGenerationJob)
+	ollama.Post("/batches", middleware.ValidateAPIKey(), middleware.BodyLimit(batches.MaxFileSize(), nil), handlers.CreateBatch)
+	ollama.Get("/batches/:id", middleware.ValidateAPIKey(), handlers.GetBatch)
+	ollama.Post("/batches/:id/cancel", middleware.ValidateAPIKey(), handlers.CancelBatch)
+	ollama.Get("/batches/:id/results", middleware.ValidateAPIKey(), handlers.DownloadBatchResults)
+
+	// Backend pool routes
+	backendPool := api.Group("/backends", middleware.AuthRequired())
+	backendPool.Get("/", viewer, handlers.ListBackends)
+	backendPool.Post("/", admin, handlers.CreateBackend)
+	backendPool.Delete("/:id", admin, handlers.DeleteBackend)
+
+	// Request queue depth
+	api.Get("/queue", middleware.AuthRequired(), viewer, handlers.GetQueueStatus)
+
+	// Usage reporting routes
+	usage := api.Group("/usage", middleware.AuthRequired())
+	usage.Get("/", viewer, handlers.GetUsage)
+	usage.Get("/export", viewer, handlers.ExportUsage)
+
+	// User management routes
+	userRoutes := api.Group("/users", middleware.AuthRequired())
+	userRoutes.Get("/", admin, handlers.ListUsers)
+	userRoutes.Post("/", owner, handlers.CreateUser)
+	userRoutes.Patch("/:id/toggle", owner, handlers.ToggleUserStatus)
+	userRoutes.Put("/:id/role", owner, handlers.UpdateUserRole)
+	userRoutes.Post("/:id/password", owner, handlers.ResetUserPassword)
+	userRoutes.Delete("/:id/2fa", owner, handlers.ResetUserTwoFactor)
+
+	// Security policy
+	api.Get("/security/policy", middleware.AuthRequired(), admin, handlers.GetSecurityPolicy)
+	api.Put("/security/policy", middleware.AuthRequired(), owner, handlers.UpdateSecurityPolicy)
+
+	// Session routes
+	sessionRoutes := api.Group("/sessions", middleware.AuthRequired())
+	sessionRoutes.Get("/", admin, handlers.ListSessions)
+	sessionRoutes.Delete("/:id", admin, handlers.RevokeSession)
+
+	// Login lockout routes
+	lockouts := api.Group("/lockouts", middleware.AuthRequired())
+	lockouts.Get("/", admin, handlers.ListLockouts)
+	lockouts.Delete("/:id", admin, handlers.ClearLockout)
+
+	// Audit log
+	api.Get("/audit", middleware.AuthRequired(), admin, handlers.ListAuditEvents)
+
+	// OpenAI-compatible routes (project API key via Bearer token or X-API-Key)
+	v1 := app.Group("/v1", middleware.ValidateOpenAIKey())
+	v1.Post("/chat/completions", handlers.OpenAIChatCompletions)
+	v1.Post("/completions", handlers.OpenAICompletions)
+	v1.Post("/embeddings", handlers.OpenAIEmbeddings)
+	v1.Get("/models", handlers.OpenAIListModels)
 
 	// Start server
 	port := os.Getenv("PORT")
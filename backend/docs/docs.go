@@ -23,82 +23,82 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/api/auth/login": {
-            "post": {
-                "description": "Login with admin credentials to get JWT token",
-                "consumes": [
-                    "application/json"
-                ],
+        "/.well-known/jwks.json": {
+            "get": {
+                "description": "Get the public keys that verify admin JWT tokens as a JSON Web Key Set, for other services to check tokens by their kid header. Empty when tokens are signed with JWT_SECRET, which is never published.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "auth"
                 ],
-                "summary": "Admin login",
-                "parameters": [
-                    {
-                        "description": "Login credentials",
-                        "name": "credentials",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.LoginRequest"
-                        }
-                    }
-                ],
+                "summary": "Keys that verify admin tokens",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.LoginResponse"
-                        }
-                    },
-                    "401": {
-                        "description": "Unauthorized",
-                        "schema": {
-                            "$ref": "#/definitions/models.ErrorResponse"
+                            "$ref": "#/definitions/models.JSONWebKeySet"
                         }
                     }
                 }
             }
         },
-        "/api/ollama/generate": {
-            "post": {
-                "description": "Send a prompt to Ollama for text generation. Requires a valid project API key and model assignment.",
-                "consumes": [
-                    "application/json"
+        "/api/audit": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
                 ],
+                "description": "List security-relevant events, newest first, such as API key calls refused by an IP allowlist and changes to users",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "ollama"
+                    "audit"
                 ],
-                "summary": "Generate text using Ollama",
+                "summary": "List audit events",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Project API Key",
-                        "name": "X-API-Key",
-                        "in": "header",
-                        "required": true
+                        "description": "Filter by action, e.g. ip_denied",
+                        "name": "action",
+                        "in": "query"
                     },
                     {
-                        "description": "Ollama request",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.OllamaRequest"
-                        }
+                        "type": "integer",
+                        "description": "Filter by project ID",
+                        "name": "project_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Start (RFC3339 or YYYY-MM-DD)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "End (RFC3339 or YYYY-MM-DD)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 100,
+                        "description": "Maximum number of events",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.OllamaResponse"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.AuditEvent"
+                            }
                         }
                     },
                     "400": {
@@ -106,57 +106,43 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
-                    },
-                    "401": {
-                        "description": "Unauthorized",
-                        "schema": {
-                            "$ref": "#/definitions/models.ErrorResponse"
-                        }
-                    },
-                    "403": {
-                        "description": "Forbidden",
-                        "schema": {
-                            "$ref": "#/definitions/models.ErrorResponse"
-                        }
                     }
                 }
             }
         },
-        "/api/ollama/models": {
+        "/api/auth/2fa": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Get a list of all models available on the Ollama server",
+                "description": "Tell whether the logged-in user has two-factor authentication, whether the policy requires it and how many recovery codes are left",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "ollama"
+                    "two-factor"
                 ],
-                "summary": "List available Ollama models",
+                "summary": "Two-factor status",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
-                        }
-                    },
-                    "502": {
-                        "description": "Bad Gateway",
-                        "schema": {
-                            "$ref": "#/definitions/models.ErrorResponse"
+                            "$ref": "#/definitions/models.TwoFactorStatusResponse"
                         }
                     }
                 }
             }
         },
-        "/api/ollama/models/delete": {
-            "delete": {
-                "description": "Remove a model from the local Ollama instance",
+        "/api/auth/2fa/disable": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Turn off two-factor authentication of the logged-in user, confirmed with a code from the authenticator app or a recovery code. Not possible while the security policy requires two-factor authentication.",
                 "consumes": [
                     "application/json"
                 ],
@@ -164,20 +150,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "ollama"
+                    "two-factor"
                 ],
-                "summary": "Delete an Ollama model",
+                "summary": "Turn off two-factor authentication",
                 "parameters": [
                     {
-                        "description": "Model delete request",
+                        "description": "Code from the authenticator app or a recovery code",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/models.TwoFactorCodeRequest"
                         }
                     }
                 ],
@@ -185,8 +168,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/models.SuccessResponse"
                         }
                     },
                     "400": {
@@ -195,8 +177,14 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
-                    "502": {
-                        "description": "Bad Gateway",
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
@@ -204,9 +192,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/ollama/models/pull": {
+        "/api/auth/2fa/enable": {
             "post": {
-                "description": "Download and install a model from the Ollama library",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Confirm a code of the secret from /api/auth/2fa/setup to turn on two-factor authentication. The response holds recovery codes, each of which replaces a code once; they are only shown here.",
                 "consumes": [
                     "application/json"
                 ],
@@ -214,20 +207,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "ollama"
+                    "two-factor"
                 ],
-                "summary": "Pull an Ollama model",
+                "summary": "Turn on two-factor authentication",
                 "parameters": [
                     {
-                        "description": "Model pull request",
+                        "description": "Code from the authenticator app",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/models.TwoFactorCodeRequest"
                         }
                     }
                 ],
@@ -235,8 +225,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/models.RecoveryCodesResponse"
                         }
                     },
                     "400": {
@@ -245,8 +234,14 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
-                    "502": {
-                        "description": "Bad Gateway",
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
@@ -254,26 +249,50 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/ollama/models/running": {
-            "get": {
-                "description": "Get a list of currently loaded/running models",
+        "/api/auth/2fa/recovery-codes": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Replace the recovery codes of the logged-in user, confirmed with a code from the authenticator app or a recovery code. The old codes stop working.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "ollama"
+                    "two-factor"
+                ],
+                "summary": "Replace recovery codes",
+                "parameters": [
+                    {
+                        "description": "Code from the authenticator app or a recovery code",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.TwoFactorCodeRequest"
+                        }
+                    }
                 ],
-                "summary": "List running Ollama models",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/models.RecoveryCodesResponse"
                         }
                     },
-                    "502": {
-                        "description": "Bad Gateway",
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
@@ -281,46 +300,46 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/projects": {
-            "get": {
+        "/api/auth/2fa/setup": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Get a list of all projects",
+                "description": "Create a new secret for the logged-in user to add to an authenticator app, usually by showing the otpauth URI as a QR code. Two-factor authentication is turned on once a code is confirmed at /api/auth/2fa/enable.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "projects"
+                    "two-factor"
                 ],
-                "summary": "List all projects",
+                "summary": "Start setting up two-factor authentication",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.Project"
-                            }
+                            "$ref": "#/definitions/models.TwoFactorSetupResponse"
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
-            },
+            }
+        },
+        "/api/auth/2fa/verify": {
             "post": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "description": "Create a new project with a unique API key",
+                "description": "Complete the challenge of a login with a code from the authenticator app or a recovery code. Wrong codes count as failed logins; after five the challenge ends and the login has to start over.",
                 "consumes": [
                     "application/json"
                 ],
@@ -328,29 +347,35 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "projects"
+                    "auth"
                 ],
-                "summary": "Create a new project",
+                "summary": "Complete a login with a two-factor code",
                 "parameters": [
                     {
-                        "description": "Project details",
-                        "name": "project",
+                        "description": "Challenge and code",
+                        "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.CreateProjectRequest"
+                            "$ref": "#/definitions/models.TwoFactorVerifyRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Project"
+                            "$ref": "#/definitions/models.LoginResponse"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
@@ -358,127 +383,197 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/projects/{id}": {
-            "get": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
+        "/api/auth/login": {
+            "post": {
+                "description": "Login with the credentials of an admin user to get a JWT token. Users with two-factor authentication get a challenge instead, to be completed with a code at /api/auth/2fa/verify. After a failed login the client IP and the username have to wait before trying again, twice as long after every further failure, and are locked out for a while after too many failures.",
+                "consumes": [
+                    "application/json"
                 ],
-                "description": "Get detailed information about a specific project",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "projects"
+                    "auth"
                 ],
-                "summary": "Get a project by ID",
+                "summary": "Admin login",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Project ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Login credentials",
+                        "name": "credentials",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.LoginRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Project"
+                            "$ref": "#/definitions/models.LoginResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
-            },
-            "put": {
+            }
+        },
+        "/api/auth/logout": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Update project details",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "End the session of the token. Its refresh token stops working and the token itself is revoked.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "projects"
+                    "auth"
                 ],
-                "summary": "Update a project",
+                "summary": "Log out",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SuccessResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/auth/oidc/callback": {
+            "get": {
+                "description": "Redeem the authorization code from the OpenID Connect provider and redirect to the admin UI with a JWT token and a refresh token in the URL fragment (#token=...\u0026refresh_token=...), or with #error=... when the login failed. Users are created on their first login; their role comes from the configured group and email domain mappings.",
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Finish a single sign-on login",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Project ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "type": "string",
+                        "description": "Authorization code",
+                        "name": "code",
+                        "in": "query"
                     },
                     {
-                        "description": "Project details",
-                        "name": "project",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.CreateProjectRequest"
-                        }
+                        "type": "string",
+                        "description": "State of the login",
+                        "name": "state",
+                        "in": "query",
+                        "required": true
                     }
                 ],
+                "responses": {
+                    "302": {
+                        "description": "Found"
+                    }
+                }
+            }
+        },
+        "/api/auth/oidc/config": {
+            "get": {
+                "description": "Tell whether logging in through OpenID Connect is available",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Single sign-on configuration",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Project"
+                            "$ref": "#/definitions/models.OIDCConfigResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/auth/oidc/login": {
+            "get": {
+                "description": "Redirect to the OpenID Connect provider to log in with the authorization code flow and PKCE. The provider sends the browser back to the callback.",
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Start a single sign-on login",
+                "responses": {
+                    "302": {
+                        "description": "Found"
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "502": {
+                        "description": "Bad Gateway",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
-            },
-            "delete": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
+            }
+        },
+        "/api/auth/refresh": {
+            "post": {
+                "description": "Exchange a refresh token for a new access token and a new refresh token. Each refresh token works once; using a replaced one again ends the session.",
+                "consumes": [
+                    "application/json"
                 ],
-                "description": "Soft delete a project",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "projects"
+                    "auth"
                 ],
-                "summary": "Delete a project",
+                "summary": "Refresh tokens",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Project ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Refresh token",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.RefreshRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.SuccessResponse"
+                            "$ref": "#/definitions/models.LoginResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
@@ -486,28 +581,27 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/projects/{id}/models": {
+        "/api/backends": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Get all models assigned to a specific project",
+                "description": "Get the health, installed models and loaded models of every Ollama backend in the pool",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "models"
+                    "backends"
                 ],
-                "summary": "List models assigned to a project",
+                "summary": "List Ollama backends",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Project ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "type": "boolean",
+                        "description": "Run a health check before responding",
+                        "name": "refresh",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -516,15 +610,9 @@ const docTemplate = `{
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/models.ProjectModel"
+                                "$ref": "#/definitions/models.BackendStatus"
                             }
                         }
-                    },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "$ref": "#/definitions/models.ErrorResponse"
-                        }
                     }
                 }
             },
@@ -534,7 +622,7 @@ const docTemplate = `{
                         "BearerAuth": []
                     }
                 ],
-                "description": "Add an available LLM model to a project",
+                "description": "Add an Ollama server to the backend pool",
                 "consumes": [
                     "application/json"
                 ],
@@ -542,24 +630,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "models"
+                    "backends"
                 ],
-                "summary": "Assign a model to a project",
+                "summary": "Register an Ollama backend",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Project ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "Model name",
-                        "name": "model",
+                        "description": "Backend details",
+                        "name": "backend",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.AssignModelRequest"
+                            "$ref": "#/definitions/models.CreateBackendRequest"
                         }
                     }
                 ],
@@ -567,7 +648,7 @@ const docTemplate = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/models.ProjectModel"
+                            "$ref": "#/definitions/models.OllamaBackend"
                         }
                     },
                     "400": {
@@ -579,35 +660,28 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/projects/{id}/models/{modelId}": {
+        "/api/backends/{id}": {
             "delete": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Remove a model assignment from a project",
+                "description": "Remove a registered Ollama server from the backend pool. Backends from OLLAMA_BACKENDS cannot be removed here.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "models"
+                    "backends"
                 ],
-                "summary": "Unassign a model from a project",
+                "summary": "Remove an Ollama backend",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Project ID",
+                        "description": "Backend ID",
                         "name": "id",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Model assignment ID",
-                        "name": "modelId",
-                        "in": "path",
-                        "required": true
                     }
                 ],
                 "responses": {
@@ -626,25 +700,53 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/projects/{id}/toggle": {
-            "patch": {
+        "/api/lockouts": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Activate or deactivate a project",
+                "description": "List the client IPs and usernames with recent failed logins, most recent first. Those with blocked_until in the future cannot log in until then; locked ones reached the failure limit.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "projects"
+                    "lockouts"
                 ],
-                "summary": "Toggle project active status",
+                "summary": "List login throttles",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.LoginThrottle"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/lockouts/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Forget the failed logins of a client IP or username, so it can log in again right away",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "lockouts"
+                ],
+                "summary": "Clear a login throttle",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Project ID",
+                        "description": "Throttle ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -654,7 +756,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Project"
+                            "$ref": "#/definitions/models.SuccessResponse"
                         }
                     },
                     "404": {
@@ -665,111 +767,4264 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "models.AssignModelRequest": {
-            "type": "object",
-            "properties": {
-                "model_name": {
-                    "type": "string",
-                    "example": "llama2"
-                }
-            }
         },
-        "models.CreateProjectRequest": {
-            "type": "object",
-            "properties": {
-                "description": {
-                    "type": "string",
-                    "example": "A test project"
-                },
-                "name": {
-                    "type": "string",
-                    "example": "My Project"
+        "/api/ollama/batches": {
+            "post": {
+                "description": "Upload a JSONL file of generate and chat requests to run in the background. Every line is a models.BatchRequestLine with a unique custom_id and a url of /api/ollama/generate or /api/ollama/chat. Requests run under the model assignments, settings, quotas and rate limits of the project; download the results once the batch finished.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "batches"
+                ],
+                "summary": "Upload a batch",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Project API Key",
+                        "name": "X-API-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "JSONL file of requests",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.Batch"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
                 }
             }
         },
-        "models.ErrorResponse": {
-            "type": "object",
-            "properties": {
-                "error": {
-                    "type": "string",
-                    "example": "Invalid request"
-                },
-                "message": {
+        "/api/ollama/batches/{id}": {
+            "get": {
+                "description": "Get the status and progress of a batch",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "batches"
+                ],
+                "summary": "Get a batch",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Project API Key",
+                        "name": "X-API-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Batch ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Batch"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/ollama/batches/{id}/cancel": {
+            "post": {
+                "description": "Cancel a queued or running batch. Requests in flight are stopped and the remaining ones never run; results of finished requests stay available.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "batches"
+                ],
+                "summary": "Cancel a batch",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Project API Key",
+                        "name": "X-API-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Batch ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Batch"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/ollama/batches/{id}/results": {
+            "get": {
+                "description": "Download a JSONL file with a models.BatchResultLine for every finished request of the batch, in the order of the uploaded file. Failed and cancelled requests carry an error. The file is partial while the batch runs.",
+                "produces": [
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "batches"
+                ],
+                "summary": "Download batch results",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Project API Key",
+                        "name": "X-API-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Batch ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "JSONL results",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/ollama/chat": {
+            "post": {
+                "description": "Send a multi-turn conversation to Ollama. Requires a valid project API key and model assignment.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ollama"
+                ],
+                "summary": "Chat using Ollama",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Project API Key",
+                        "name": "X-API-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Ollama chat request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.OllamaChatRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.OllamaChatResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/ollama/embed": {
+            "post": {
+                "description": "Generate embeddings for one or more inputs. Requires a valid project API key and model assignment.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ollama"
+                ],
+                "summary": "Generate embeddings using Ollama",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Project API Key",
+                        "name": "X-API-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Ollama embed request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.OllamaEmbedRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.OllamaEmbedResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/ollama/generate": {
+            "post": {
+                "description": "Send a prompt to Ollama for text generation. Requires a valid project API key and model assignment.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ollama"
+                ],
+                "summary": "Generate text using Ollama",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Project API Key",
+                        "name": "X-API-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Ollama request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.OllamaRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.OllamaResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/ollama/jobs": {
+            "post": {
+                "description": "Accept a generate request and run it in the background, without the time limit of synchronous requests. Poll the job or configure a project webhook to get the result. Requires a valid project API key and model assignment.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "jobs"
+                ],
+                "summary": "Queue a generation job",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Project API Key",
+                        "name": "X-API-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Ollama request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.OllamaRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.GenerationJob"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/ollama/jobs/{id}": {
+            "get": {
+                "description": "Get the status of a generation job and, once it succeeded, its response",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "jobs"
+                ],
+                "summary": "Get a generation job",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Project API Key",
+                        "name": "X-API-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Job ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.GenerationJob"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/ollama/jobs/{id}/cancel": {
+            "post": {
+                "description": "Cancel a queued or running generation job",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "jobs"
+                ],
+                "summary": "Cancel a generation job",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Project API Key",
+                        "name": "X-API-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Job ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.GenerationJob"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/ollama/models": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get the models installed on the healthy Ollama backends, or on the one named by backend. A model installed on several backends is listed once.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ollama"
+                ],
+                "summary": "List available Ollama models",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Name of the backend to list",
+                        "name": "backend",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/ollama.ListResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/ollama/models/delete": {
+            "delete": {
+                "description": "Remove a model from the healthy Ollama backends, or from the one named by backend. Backends that do not have the model are skipped.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ollama"
+                ],
+                "summary": "Delete an Ollama model",
+                "parameters": [
+                    {
+                        "description": "Model delete request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Name of the backend to delete from",
+                        "name": "backend",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/ollama/models/pull": {
+            "post": {
+                "description": "Download and install a model from the Ollama library on the healthy backends, or on the one named by backend. Progress is streamed as newline-delimited JSON, each update naming its backend.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ollama"
+                ],
+                "summary": "Pull an Ollama model",
+                "parameters": [
+                    {
+                        "description": "Model pull request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Name of the backend to pull on",
+                        "name": "backend",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/ollama.ProgressResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/ollama/models/running": {
+            "get": {
+                "description": "Get the models loaded on the healthy Ollama backends, or on the one named by backend. A model loaded on several backends is listed for each.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ollama"
+                ],
+                "summary": "List running Ollama models",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Name of the backend to list",
+                        "name": "backend",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/ollama.ProcessResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/ollama/models/unload": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Unload a model from the memory of the healthy Ollama backends, or of the one named by backend, right away, keeping it installed",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ollama"
+                ],
+                "summary": "Unload an Ollama model",
+                "parameters": [
+                    {
+                        "description": "Model unload request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Name of the backend to unload from",
+                        "name": "backend",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/projects": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get a list of all projects",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "projects"
+                ],
+                "summary": "List all projects",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Project"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create a new project with a first API key named \"default\". The key is only returned in this response; afterwards only its prefix is shown. So is the webhook secret when a webhook_url is set.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "projects"
+                ],
+                "summary": "Create a new project",
+                "parameters": [
+                    {
+                        "description": "Project details",
+                        "name": "project",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CreateProjectRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Project"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/projects/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get detailed information about a specific project",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "projects"
+                ],
+                "summary": "Get a project by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Project ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Project"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Update project details. Settings, rate limits, quotas, scheduling, the webhook URL and the allowed CIDRs are only replaced when provided. When the first webhook URL is set, its secret is only returned in this response.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "projects"
+                ],
+                "summary": "Update a project",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Project ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Project details",
+                        "name": "project",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CreateProjectRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Project"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Soft delete a project",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "projects"
+                ],
+                "summary": "Delete a project",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Project ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SuccessResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/projects/{id}/keys": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get the keys of a project, including revoked and expired ones. Only their prefixes are shown.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "api-keys"
+                ],
+                "summary": "List the API keys of a project",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Project ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.APIKey"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create a named API key for a project, optionally expiring and limited to scopes, a subset of the project's models and the networks it may be used from. The key is only returned in this response.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "api-keys"
+                ],
+                "summary": "Create an API key",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Project ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Key details",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CreateAPIKeyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.APIKey"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/projects/{id}/keys/{keyId}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Revoke an API key right away",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "api-keys"
+                ],
+                "summary": "Revoke an API key",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Project ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "API key ID",
+                        "name": "keyId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SuccessResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/projects/{id}/keys/{keyId}/rotate": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Replace an API key with a new one of the same name, scopes, models and allowed CIDRs. The old key keeps working for the grace period so consumers can switch over. The new key is only returned in this response.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "api-keys"
+                ],
+                "summary": "Rotate an API key",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Project ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "API key ID",
+                        "name": "keyId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Rotation details",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/models.RotateAPIKeyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.APIKey"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/projects/{id}/models": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get all models assigned to a specific project",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "models"
+                ],
+                "summary": "List models assigned to a project",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Project ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.ProjectModel"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Add an available LLM model to a project",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "models"
+                ],
+                "summary": "Assign a model to a project",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Project ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Model name",
+                        "name": "model",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.AssignModelRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProjectModel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/projects/{id}/models/{modelId}": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Replace the per-model default parameters and limits, which override the project settings",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "models"
+                ],
+                "summary": "Update model settings for a project",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Project ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Model assignment ID",
+                        "name": "modelId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Model settings",
+                        "name": "settings",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.GenerationSettings"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProjectModel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Remove a model assignment from a project",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "models"
+                ],
+                "summary": "Unassign a model from a project",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Project ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Model assignment ID",
+                        "name": "modelId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SuccessResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/projects/{id}/quota": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get the token consumption, top-ups and remaining quota of a project for the current daily and monthly periods",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "projects"
+                ],
+                "summary": "Get project quota status",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Project ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.QuotaStatus"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/projects/{id}/quota/topups": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Grant a project extra tokens for the current daily or monthly period. The top-up expires when the period resets.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "projects"
+                ],
+                "summary": "Grant a quota top-up",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Project ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Top-up details",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.GrantTopUpRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.QuotaTopUp"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/projects/{id}/toggle": {
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Activate or deactivate a project",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "projects"
+                ],
+                "summary": "Toggle project active status",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Project ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Project"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/projects/{id}/webhook/rotate": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Replace the secret that signs the project's webhook deliveries. Deliveries are signed with the new secret right away. The secret is only returned in this response.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "projects"
+                ],
+                "summary": "Rotate the webhook secret of a project",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Project ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Project"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/queue": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get the queued and running requests per model and project",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "queue"
+                ],
+                "summary": "Get request queue depth",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.QueueStatus"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/security/policy": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get the settings that apply to all users",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "security"
+                ],
+                "summary": "Get the security policy",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SecurityPolicy"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Change the settings that apply to all users. With require_2fa, users without two-factor authentication can only set it up until they have; single sign-on users are exempt.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "security"
+                ],
+                "summary": "Change the security policy",
+                "parameters": [
+                    {
+                        "description": "Policy",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UpdateSecurityPolicyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SecurityPolicy"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/sessions": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "List the sessions that are neither expired nor revoked, most recently used first, with their users",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sessions"
+                ],
+                "summary": "List active sessions",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Filter by user ID",
+                        "name": "user_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Session"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/sessions/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Revoke a session right away. Its refresh token stops working and its current access token is revoked.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sessions"
+                ],
+                "summary": "End a session",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Session ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SuccessResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/usage": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Aggregate token usage and durations by time bucket, project and model",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "usage"
+                ],
+                "summary": "Get aggregated usage",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Start (RFC3339 or YYYY-MM-DD), defaults to 30 days ago",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "End (RFC3339 or YYYY-MM-DD), defaults to now",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "day",
+                        "description": "Time bucket: hour, day or month",
+                        "name": "bucket",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Filter by project ID",
+                        "name": "project_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by model name",
+                        "name": "model",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.UsageSummary"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/usage/export": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Same aggregation as GET /api/usage, returned as a CSV file for chargeback",
+                "produces": [
+                    "text/csv"
+                ],
+                "tags": [
+                    "usage"
+                ],
+                "summary": "Export aggregated usage as CSV",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Start (RFC3339 or YYYY-MM-DD), defaults to 30 days ago",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "End (RFC3339 or YYYY-MM-DD), defaults to now",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "day",
+                        "description": "Time bucket: hour, day or month",
+                        "name": "bucket",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Filter by project ID",
+                        "name": "project_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by model name",
+                        "name": "model",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "CSV file",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/users": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get all admin users, including disabled ones",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "List users",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.User"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create an admin user with a password of at least 12 characters and a role of viewer (the default), operator, admin or owner",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Create a user",
+                "parameters": [
+                    {
+                        "description": "User details",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CreateUserRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.User"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/users/{id}/2fa": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Turn off two-factor authentication of a user who lost their authenticator and recovery codes. When the security policy requires it, they have to set it up again after logging in.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Reset the two-factor authentication of a user",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SuccessResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/users/{id}/password": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Set a new password of at least 12 characters for a user and end their sessions. Single sign-on users have no password.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Reset the password of a user",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New password",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ResetPasswordRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/users/{id}/role": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Change the role of a user to viewer, operator, admin or owner. The user's tokens stop working and refreshing them issues tokens with the new role. Users cannot change their own role. Single sign-on users get their role from the role mappings again on their next login.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Change the role of a user",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New role",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UpdateUserRoleRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.User"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/users/{id}/toggle": {
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Disable an active user or enable a disabled one. A disabled user cannot log in and their sessions end. Users cannot disable themselves.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Disable or enable a user",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.User"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/validate_key": {
+            "get": {
+                "description": "Check whether the provided X-API-Key belongs to an active project",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Validate project API key",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Project API Key",
+                        "name": "X-API-Key",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/chat/completions": {
+            "post": {
+                "description": "Create a chat completion using the OpenAI request and response format. Accepts the project API key as a Bearer token or X-API-Key header.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "openai"
+                ],
+                "summary": "OpenAI-compatible chat completions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003cproject API key\u003e",
+                        "name": "Authorization",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Chat completion request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.OpenAIChatCompletionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.OpenAIChatCompletionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.OpenAIErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.OpenAIErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.OpenAIErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/completions": {
+            "post": {
+                "description": "Create a text completion using the OpenAI (legacy) completions format. Accepts the project API key as a Bearer token or X-API-Key header.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "openai"
+                ],
+                "summary": "OpenAI-compatible completions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003cproject API key\u003e",
+                        "name": "Authorization",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Completion request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.OpenAICompletionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.OpenAICompletionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.OpenAIErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.OpenAIErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.OpenAIErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/embeddings": {
+            "post": {
+                "description": "Create embeddings using the OpenAI request and response format. Accepts the project API key as a Bearer token or X-API-Key header.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "openai"
+                ],
+                "summary": "OpenAI-compatible embeddings",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003cproject API key\u003e",
+                        "name": "Authorization",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Embedding request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.OpenAIEmbeddingRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.OpenAIEmbeddingResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.OpenAIErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.OpenAIErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.OpenAIErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/models": {
+            "get": {
+                "description": "List the models assigned to the calling project in the OpenAI format",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "openai"
+                ],
+                "summary": "OpenAI-compatible model list",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003cproject API key\u003e",
+                        "name": "Authorization",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.OpenAIModelList"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.OpenAIErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.OpenAIErrorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "models.APIKey": {
+            "type": "object",
+            "properties": {
+                "allowed_cidrs": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "203.0.113.0/24"
+                    ]
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "created_by": {
+                    "type": "string",
+                    "example": "admin"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "key": {
+                    "type": "string"
+                },
+                "last_used_at": {
+                    "type": "string"
+                },
+                "models": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "llama2"
+                    ]
+                },
+                "name": {
+                    "type": "string",
+                    "example": "production"
+                },
+                "prefix": {
+                    "type": "string",
+                    "example": "3f9a1c2b"
+                },
+                "project_id": {
+                    "type": "integer"
+                },
+                "revoked_at": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "description": "generate, chat, embed, models:read or batches",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "generate",
+                        "chat"
+                    ]
+                }
+            }
+        },
+        "models.AssignModelRequest": {
+            "type": "object",
+            "properties": {
+                "model_name": {
+                    "type": "string",
+                    "example": "llama2"
+                },
+                "settings": {
+                    "$ref": "#/definitions/models.GenerationSettings"
+                }
+            }
+        },
+        "models.AuditEvent": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string",
+                    "example": "ip_denied"
+                },
+                "actor": {
+                    "type": "string",
+                    "example": "admin"
+                },
+                "api_key_id": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "detail": {
+                    "type": "string",
+                    "example": "POST /api/ollama/generate"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "ip": {
+                    "type": "string",
+                    "example": "203.0.113.7"
+                },
+                "project_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.BackendStatus": {
+            "type": "object",
+            "properties": {
+                "healthy": {
+                    "type": "boolean"
+                },
+                "id": {
+                    "description": "zero for backends from OLLAMA_BACKENDS",
+                    "type": "integer"
+                },
+                "in_flight": {
+                    "type": "integer"
+                },
+                "last_checked": {
+                    "type": "string"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "loaded_models": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "models": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "name": {
+                    "type": "string",
+                    "example": "gpu-1"
+                },
+                "url": {
+                    "type": "string",
+                    "example": "http://10.0.0.11:11434"
+                }
+            }
+        },
+        "models.Batch": {
+            "type": "object",
+            "properties": {
+                "api_key_id": {
+                    "type": "integer"
+                },
+                "completed": {
+                    "type": "integer",
+                    "example": 750
+                },
+                "completed_at": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "failed": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "filename": {
+                    "type": "string",
+                    "example": "evals.jsonl"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "batch-5f1c0d3a9b2e4c7d8e6f0a1b"
+                },
+                "project_id": {
+                    "type": "integer"
+                },
+                "started_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "description": "queued, in_progress, completed, failed or cancelled",
+                    "type": "string",
+                    "example": "in_progress"
+                },
+                "total": {
+                    "type": "integer",
+                    "example": 1000
+                }
+            }
+        },
+        "models.ChatMessage": {
+            "type": "object",
+            "properties": {
+                "content": {
+                    "type": "string",
+                    "example": "Why is the sky blue?"
+                },
+                "images": {
+                    "description": "base64-encoded images for vision models",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "role": {
+                    "description": "system, user, assistant or tool",
+                    "type": "string",
+                    "example": "user"
+                },
+                "tool_call_id": {
+                    "description": "ToolCallID and ToolName identify the call a tool message answers",
+                    "type": "string"
+                },
+                "tool_calls": {
+                    "type": "array",
+                    "items": {}
+                },
+                "tool_name": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.CreateAPIKeyRequest": {
+            "type": "object",
+            "properties": {
+                "allowed_cidrs": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "203.0.113.0/24"
+                    ]
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "models": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "llama2"
+                    ]
+                },
+                "name": {
+                    "type": "string",
+                    "example": "production"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "generate",
+                        "chat"
+                    ]
+                }
+            }
+        },
+        "models.CreateBackendRequest": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string",
+                    "example": "gpu-1"
+                },
+                "url": {
+                    "type": "string",
+                    "example": "http://10.0.0.11:11434"
+                }
+            }
+        },
+        "models.CreateProjectRequest": {
+            "type": "object",
+            "properties": {
+                "allowed_cidrs": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "10.0.0.0/8"
+                    ]
+                },
+                "description": {
+                    "type": "string",
+                    "example": "A test project"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "My Project"
+                },
+                "quotas": {
+                    "$ref": "#/definitions/models.QuotaSettings"
+                },
+                "rate_limits": {
+                    "$ref": "#/definitions/models.RateLimitSettings"
+                },
+                "scheduling": {
+                    "$ref": "#/definitions/models.SchedulingSettings"
+                },
+                "settings": {
+                    "$ref": "#/definitions/models.GenerationSettings"
+                },
+                "webhook_url": {
+                    "type": "string",
+                    "example": "https://example.com/hooks/ollama"
+                }
+            }
+        },
+        "models.CreateUserRequest": {
+            "type": "object",
+            "properties": {
+                "password": {
+                    "type": "string",
+                    "example": "correct horse battery staple"
+                },
+                "role": {
+                    "description": "defaults to viewer",
+                    "type": "string",
+                    "example": "operator"
+                },
+                "username": {
+                    "type": "string",
+                    "example": "alice"
+                }
+            }
+        },
+        "models.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "machine-readable code for errors clients must tell apart",
+                    "type": "string",
+                    "example": "quota_exceeded"
+                },
+                "error": {
+                    "type": "string",
+                    "example": "Invalid request"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "Detailed error message"
+                }
+            }
+        },
+        "models.GenerateOptions": {
+            "type": "object",
+            "properties": {
+                "frequency_penalty": {
+                    "type": "number"
+                },
+                "main_gpu": {
+                    "type": "integer"
+                },
+                "min_p": {
+                    "type": "number"
+                },
+                "num_batch": {
+                    "type": "integer"
+                },
+                "num_ctx": {
+                    "type": "integer",
+                    "example": 4096
+                },
+                "num_gpu": {
+                    "type": "integer"
+                },
+                "num_keep": {
+                    "type": "integer"
+                },
+                "num_predict": {
+                    "type": "integer",
+                    "example": 128
+                },
+                "num_thread": {
+                    "type": "integer"
+                },
+                "numa": {
+                    "type": "boolean"
+                },
+                "penalize_newline": {
+                    "type": "boolean"
+                },
+                "presence_penalty": {
+                    "type": "number"
+                },
+                "repeat_last_n": {
+                    "type": "integer"
+                },
+                "repeat_penalty": {
+                    "type": "number"
+                },
+                "seed": {
+                    "type": "integer",
+                    "example": 42
+                },
+                "stop": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "temperature": {
+                    "type": "number",
+                    "example": 0.7
+                },
+                "top_k": {
+                    "type": "integer"
+                },
+                "top_p": {
+                    "type": "number"
+                },
+                "typical_p": {
+                    "type": "number"
+                },
+                "use_mmap": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.GenerationJob": {
+            "type": "object",
+            "properties": {
+                "api_key_id": {
+                    "type": "integer"
+                },
+                "completed_at": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "job-5f1c0d3a9b2e4c7d8e6f0a1b"
+                },
+                "model": {
+                    "type": "string",
+                    "example": "llama2"
+                },
+                "project_id": {
+                    "type": "integer"
+                },
+                "response": {
+                    "$ref": "#/definitions/models.OllamaResponse"
+                },
+                "started_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "description": "queued, running, succeeded, failed or cancelled",
+                    "type": "string",
+                    "example": "queued"
+                },
+                "webhook_status": {
+                    "description": "delivered or failed",
+                    "type": "string",
+                    "example": "delivered"
+                }
+            }
+        },
+        "models.GenerationSettings": {
+            "type": "object",
+            "properties": {
+                "clamp_to_limits": {
+                    "description": "clamp num_predict/num_ctx instead of rejecting",
+                    "type": "boolean",
+                    "example": false
+                },
+                "default_num_ctx": {
+                    "type": "integer",
+                    "example": 4096
+                },
+                "default_system": {
+                    "type": "string",
+                    "example": "You are a helpful assistant."
+                },
+                "default_temperature": {
+                    "type": "number",
+                    "example": 0.7
+                },
+                "max_images": {
+                    "type": "integer",
+                    "example": 4
+                },
+                "max_num_ctx": {
+                    "type": "integer",
+                    "example": 8192
+                },
+                "max_num_predict": {
+                    "type": "integer",
+                    "example": 1024
+                },
+                "max_prompt_length": {
+                    "description": "in characters",
+                    "type": "integer",
+                    "example": 32000
+                }
+            }
+        },
+        "models.GrantTopUpRequest": {
+            "type": "object",
+            "properties": {
+                "note": {
+                    "type": "string",
+                    "example": "Release week"
+                },
+                "period": {
+                    "description": "daily or monthly",
+                    "type": "string",
+                    "example": "monthly"
+                },
+                "tokens": {
+                    "type": "integer",
+                    "example": 100000
+                }
+            }
+        },
+        "models.JSONWebKey": {
+            "type": "object",
+            "properties": {
+                "alg": {
+                    "type": "string",
+                    "example": "RS256"
+                },
+                "crv": {
+                    "type": "string"
+                },
+                "e": {
+                    "type": "string",
+                    "example": "AQAB"
+                },
+                "kid": {
+                    "type": "string"
+                },
+                "kty": {
+                    "type": "string",
+                    "example": "RSA"
+                },
+                "n": {
+                    "type": "string"
+                },
+                "use": {
+                    "type": "string",
+                    "example": "sig"
+                },
+                "x": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.JSONWebKeySet": {
+            "type": "object",
+            "properties": {
+                "keys": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.JSONWebKey"
+                    }
+                }
+            }
+        },
+        "models.LoginRequest": {
+            "type": "object",
+            "properties": {
+                "password": {
+                    "type": "string",
+                    "example": "password"
+                },
+                "username": {
+                    "type": "string",
+                    "example": "admin"
+                }
+            }
+        },
+        "models.LoginResponse": {
+            "type": "object",
+            "properties": {
+                "challenge": {
+                    "type": "string",
+                    "example": "8c1d..."
+                },
+                "expires_in": {
+                    "type": "integer",
+                    "example": 900
+                },
+                "refresh_token": {
+                    "type": "string",
+                    "example": "3f9a..."
+                },
+                "role": {
+                    "type": "string",
+                    "example": "admin"
+                },
+                "token": {
+                    "type": "string",
+                    "example": "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."
+                },
+                "two_factor_required": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.LoginThrottle": {
+            "type": "object",
+            "properties": {
+                "blocked_until": {
+                    "type": "string"
+                },
+                "failures": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "key": {
+                    "type": "string",
+                    "example": "admin"
+                },
+                "kind": {
+                    "description": "ip or username",
+                    "type": "string",
+                    "example": "username"
+                },
+                "last_failure_at": {
+                    "type": "string"
+                },
+                "locked": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.OIDCConfigResponse": {
+            "type": "object",
+            "properties": {
+                "enabled": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.OllamaBackend": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "enabled": {
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "gpu-1"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string",
+                    "example": "http://10.0.0.11:11434"
+                }
+            }
+        },
+        "models.OllamaChatRequest": {
+            "type": "object",
+            "properties": {
+                "format": {
+                    "description": "\"json\" or a JSON schema",
+                    "type": "object"
+                },
+                "keep_alive": {
+                    "description": "duration string or seconds",
+                    "type": "string",
+                    "example": "5m"
+                },
+                "messages": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ChatMessage"
+                    }
+                },
+                "model": {
+                    "type": "string",
+                    "example": "llama2"
+                },
+                "options": {
+                    "$ref": "#/definitions/models.GenerateOptions"
+                },
+                "stream": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "tools": {
+                    "type": "array",
+                    "items": {}
+                }
+            }
+        },
+        "models.OllamaChatResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "done": {
+                    "type": "boolean"
+                },
+                "done_reason": {
+                    "type": "string"
+                },
+                "eval_count": {
+                    "type": "integer"
+                },
+                "eval_duration": {
+                    "type": "integer"
+                },
+                "load_duration": {
+                    "type": "integer"
+                },
+                "message": {
+                    "$ref": "#/definitions/models.ChatMessage"
+                },
+                "model": {
+                    "type": "string"
+                },
+                "prompt_eval_count": {
+                    "type": "integer"
+                },
+                "prompt_eval_duration": {
+                    "type": "integer"
+                },
+                "total_duration": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.OllamaEmbedRequest": {
+            "type": "object",
+            "properties": {
+                "dimensions": {
+                    "type": "integer"
+                },
+                "input": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "Why is the sky blue?"
+                    ]
+                },
+                "keep_alive": {
+                    "description": "duration string or seconds",
+                    "type": "string",
+                    "example": "5m"
+                },
+                "model": {
+                    "type": "string",
+                    "example": "nomic-embed-text"
+                },
+                "options": {
+                    "$ref": "#/definitions/models.GenerateOptions"
+                },
+                "truncate": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.OllamaEmbedResponse": {
+            "type": "object",
+            "properties": {
+                "embeddings": {
+                    "type": "array",
+                    "items": {
+                        "type": "array",
+                        "items": {
+                            "type": "number",
+                            "format": "float64"
+                        }
+                    }
+                },
+                "load_duration": {
+                    "type": "integer"
+                },
+                "model": {
+                    "type": "string"
+                },
+                "prompt_eval_count": {
+                    "type": "integer"
+                },
+                "total_duration": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.OllamaRequest": {
+            "type": "object",
+            "properties": {
+                "context": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "format": {
+                    "description": "\"json\" or a JSON schema",
+                    "type": "object"
+                },
+                "images": {
+                    "description": "base64-encoded images for vision models",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "keep_alive": {
+                    "description": "duration string or seconds",
+                    "type": "string",
+                    "example": "5m"
+                },
+                "model": {
+                    "type": "string",
+                    "example": "llama2"
+                },
+                "options": {
+                    "$ref": "#/definitions/models.GenerateOptions"
+                },
+                "prompt": {
+                    "type": "string",
+                    "example": "Why is the sky blue?"
+                },
+                "raw": {
+                    "type": "boolean"
+                },
+                "stream": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "suffix": {
+                    "type": "string"
+                },
+                "system": {
+                    "type": "string"
+                },
+                "template": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.OllamaResponse": {
+            "type": "object",
+            "properties": {
+                "context": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "done": {
+                    "type": "boolean"
+                },
+                "done_reason": {
+                    "type": "string"
+                },
+                "eval_count": {
+                    "type": "integer"
+                },
+                "eval_duration": {
+                    "type": "integer"
+                },
+                "load_duration": {
+                    "type": "integer"
+                },
+                "model": {
+                    "type": "string"
+                },
+                "prompt_eval_count": {
+                    "type": "integer"
+                },
+                "prompt_eval_duration": {
+                    "type": "integer"
+                },
+                "response": {
+                    "type": "string"
+                },
+                "total_duration": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.OpenAIChatChoice": {
+            "type": "object",
+            "properties": {
+                "delta": {
+                    "$ref": "#/definitions/models.OpenAIChatMessage"
+                },
+                "finish_reason": {
+                    "type": "string"
+                },
+                "index": {
+                    "type": "integer"
+                },
+                "message": {
+                    "$ref": "#/definitions/models.OpenAIChatMessage"
+                }
+            }
+        },
+        "models.OpenAIChatCompletionRequest": {
+            "type": "object",
+            "properties": {
+                "frequency_penalty": {
+                    "type": "number"
+                },
+                "max_tokens": {
+                    "type": "integer"
+                },
+                "messages": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.OpenAIChatMessage"
+                    }
+                },
+                "model": {
+                    "type": "string",
+                    "example": "llama2"
+                },
+                "presence_penalty": {
+                    "type": "number"
+                },
+                "response_format": {
+                    "$ref": "#/definitions/models.OpenAIResponseFormat"
+                },
+                "seed": {
+                    "type": "integer"
+                },
+                "stop": {},
+                "stream": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "stream_options": {
+                    "$ref": "#/definitions/models.OpenAIStreamOptions"
+                },
+                "temperature": {
+                    "type": "number"
+                },
+                "tools": {
+                    "type": "array",
+                    "items": {}
+                },
+                "top_p": {
+                    "type": "number"
+                }
+            }
+        },
+        "models.OpenAIChatCompletionResponse": {
+            "type": "object",
+            "properties": {
+                "choices": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.OpenAIChatChoice"
+                    }
+                },
+                "created": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "chatcmpl-3f1c2a9b"
+                },
+                "model": {
+                    "type": "string",
+                    "example": "llama2"
+                },
+                "object": {
+                    "type": "string",
+                    "example": "chat.completion"
+                },
+                "usage": {
+                    "$ref": "#/definitions/models.OpenAIUsage"
+                }
+            }
+        },
+        "models.OpenAIChatMessage": {
+            "type": "object",
+            "properties": {
+                "content": {},
+                "name": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string",
+                    "example": "user"
+                },
+                "tool_call_id": {
+                    "type": "string"
+                },
+                "tool_calls": {
+                    "type": "array",
+                    "items": {}
+                }
+            }
+        },
+        "models.OpenAICompletionChoice": {
+            "type": "object",
+            "properties": {
+                "finish_reason": {
+                    "type": "string"
+                },
+                "index": {
+                    "type": "integer"
+                },
+                "logprobs": {},
+                "text": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.OpenAICompletionRequest": {
+            "type": "object",
+            "properties": {
+                "frequency_penalty": {
+                    "type": "number"
+                },
+                "max_tokens": {
+                    "type": "integer"
+                },
+                "model": {
+                    "type": "string",
+                    "example": "llama2"
+                },
+                "presence_penalty": {
+                    "type": "number"
+                },
+                "prompt": {
+                    "type": "string",
+                    "example": "Why is the sky blue?"
+                },
+                "seed": {
+                    "type": "integer"
+                },
+                "stop": {},
+                "stream": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "stream_options": {
+                    "$ref": "#/definitions/models.OpenAIStreamOptions"
+                },
+                "suffix": {
+                    "type": "string"
+                },
+                "temperature": {
+                    "type": "number"
+                },
+                "top_p": {
+                    "type": "number"
+                }
+            }
+        },
+        "models.OpenAICompletionResponse": {
+            "type": "object",
+            "properties": {
+                "choices": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.OpenAICompletionChoice"
+                    }
+                },
+                "created": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "cmpl-3f1c2a9b"
+                },
+                "model": {
+                    "type": "string",
+                    "example": "llama2"
+                },
+                "object": {
+                    "type": "string",
+                    "example": "text_completion"
+                },
+                "usage": {
+                    "$ref": "#/definitions/models.OpenAIUsage"
+                }
+            }
+        },
+        "models.OpenAIEmbedding": {
+            "type": "object",
+            "properties": {
+                "embedding": {
+                    "type": "array",
+                    "items": {
+                        "type": "number"
+                    }
+                },
+                "index": {
+                    "type": "integer"
+                },
+                "object": {
+                    "type": "string",
+                    "example": "embedding"
+                }
+            }
+        },
+        "models.OpenAIEmbeddingRequest": {
+            "type": "object",
+            "properties": {
+                "dimensions": {
+                    "type": "integer"
+                },
+                "encoding_format": {
+                    "type": "string",
+                    "example": "float"
+                },
+                "input": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "Why is the sky blue?"
+                    ]
+                },
+                "model": {
+                    "type": "string",
+                    "example": "nomic-embed-text"
+                }
+            }
+        },
+        "models.OpenAIEmbeddingResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.OpenAIEmbedding"
+                    }
+                },
+                "model": {
+                    "type": "string",
+                    "example": "nomic-embed-text"
+                },
+                "object": {
+                    "type": "string",
+                    "example": "list"
+                },
+                "usage": {
+                    "$ref": "#/definitions/models.OpenAIUsage"
+                }
+            }
+        },
+        "models.OpenAIErrorDetail": {
+            "type": "object",
+            "properties": {
+                "code": {},
+                "message": {
+                    "type": "string"
+                },
+                "param": {},
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.OpenAIErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "$ref": "#/definitions/models.OpenAIErrorDetail"
+                }
+            }
+        },
+        "models.OpenAIModel": {
+            "type": "object",
+            "properties": {
+                "created": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "llama2"
+                },
+                "object": {
+                    "type": "string",
+                    "example": "model"
+                },
+                "owned_by": {
+                    "type": "string",
+                    "example": "ollama"
+                }
+            }
+        },
+        "models.OpenAIModelList": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.OpenAIModel"
+                    }
+                },
+                "object": {
+                    "type": "string",
+                    "example": "list"
+                }
+            }
+        },
+        "models.OpenAIResponseFormat": {
+            "type": "object",
+            "properties": {
+                "json_schema": {},
+                "type": {
+                    "type": "string",
+                    "example": "json_object"
+                }
+            }
+        },
+        "models.OpenAIStreamOptions": {
+            "type": "object",
+            "properties": {
+                "include_usage": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.OpenAIUsage": {
+            "type": "object",
+            "properties": {
+                "completion_tokens": {
+                    "type": "integer"
+                },
+                "prompt_tokens": {
+                    "type": "integer"
+                },
+                "total_tokens": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Project": {
+            "type": "object",
+            "properties": {
+                "allowed_cidrs": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "10.0.0.0/8"
+                    ]
+                },
+                "api_key": {
+                    "type": "string"
+                },
+                "api_keys": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.APIKey"
+                    }
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "is_active": {
+                    "type": "boolean"
+                },
+                "models": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ProjectModel"
+                    }
+                },
+                "name": {
+                    "type": "string"
+                },
+                "quotas": {
+                    "$ref": "#/definitions/models.QuotaSettings"
+                },
+                "rate_limits": {
+                    "$ref": "#/definitions/models.RateLimitSettings"
+                },
+                "scheduling": {
+                    "$ref": "#/definitions/models.SchedulingSettings"
+                },
+                "settings": {
+                    "$ref": "#/definitions/models.GenerationSettings"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "webhook_secret": {
+                    "type": "string"
+                },
+                "webhook_url": {
+                    "type": "string",
+                    "example": "https://example.com/hooks/ollama"
+                }
+            }
+        },
+        "models.ProjectModel": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "model_name": {
+                    "type": "string"
+                },
+                "project_id": {
+                    "type": "integer"
+                },
+                "settings": {
+                    "$ref": "#/definitions/models.GenerationSettings"
+                }
+            }
+        },
+        "models.QueueProjectStatus": {
+            "type": "object",
+            "properties": {
+                "in_flight": {
+                    "type": "integer"
+                },
+                "priority": {
+                    "type": "string",
+                    "example": "normal"
+                },
+                "project_id": {
+                    "type": "integer"
+                },
+                "waiting": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.QueueStatus": {
+            "type": "object",
+            "properties": {
+                "in_flight": {
+                    "type": "integer"
+                },
+                "model": {
+                    "type": "string",
+                    "example": "llama2:latest"
+                },
+                "oldest_wait_seconds": {
+                    "type": "number"
+                },
+                "projects": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.QueueProjectStatus"
+                    }
+                },
+                "waiting": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.QuotaSettings": {
+            "type": "object",
+            "properties": {
+                "daily_hard_limit": {
+                    "type": "integer",
+                    "example": 1000000
+                },
+                "daily_soft_limit": {
+                    "type": "integer",
+                    "example": 800000
+                },
+                "monthly_hard_limit": {
+                    "type": "integer",
+                    "example": 20000000
+                },
+                "monthly_soft_limit": {
+                    "type": "integer",
+                    "example": 16000000
+                }
+            }
+        },
+        "models.QuotaStatus": {
+            "type": "object",
+            "properties": {
+                "hard_exceeded": {
+                    "type": "boolean"
+                },
+                "hard_limit": {
+                    "type": "integer",
+                    "example": 1000000
+                },
+                "period": {
+                    "type": "string",
+                    "example": "daily"
+                },
+                "period_start": {
+                    "type": "string"
+                },
+                "remaining": {
+                    "description": "until the hard limit, including top-ups",
+                    "type": "integer",
+                    "example": 580000
+                },
+                "resets_at": {
+                    "type": "string"
+                },
+                "soft_exceeded": {
+                    "type": "boolean"
+                },
+                "soft_limit": {
+                    "type": "integer",
+                    "example": 800000
+                },
+                "top_up": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "used": {
+                    "type": "integer",
+                    "example": 420000
+                }
+            }
+        },
+        "models.QuotaTopUp": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "note": {
                     "type": "string",
-                    "example": "Detailed error message"
+                    "example": "Release week"
+                },
+                "period": {
+                    "description": "daily or monthly",
+                    "type": "string",
+                    "example": "daily"
+                },
+                "period_start": {
+                    "type": "string"
+                },
+                "project_id": {
+                    "type": "integer"
+                },
+                "tokens": {
+                    "type": "integer",
+                    "example": 100000
                 }
             }
         },
-        "models.LoginRequest": {
+        "models.RateLimitSettings": {
+            "type": "object",
+            "properties": {
+                "max_concurrent": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "requests_per_minute": {
+                    "type": "integer",
+                    "example": 60
+                },
+                "tokens_per_minute": {
+                    "type": "integer",
+                    "example": 100000
+                }
+            }
+        },
+        "models.RecoveryCodesResponse": {
+            "type": "object",
+            "properties": {
+                "recovery_codes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.RefreshRequest": {
+            "type": "object",
+            "properties": {
+                "refresh_token": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ResetPasswordRequest": {
             "type": "object",
             "properties": {
                 "password": {
                     "type": "string",
-                    "example": "password"
+                    "example": "correct horse battery staple"
+                }
+            }
+        },
+        "models.RotateAPIKeyRequest": {
+            "type": "object",
+            "properties": {
+                "expires_at": {
+                    "type": "string"
                 },
-                "username": {
+                "grace_period": {
+                    "type": "string",
+                    "example": "24h"
+                }
+            }
+        },
+        "models.SchedulingSettings": {
+            "type": "object",
+            "properties": {
+                "priority": {
+                    "description": "high, normal or low (default normal)",
+                    "type": "string",
+                    "example": "normal"
+                },
+                "weight": {
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "models.SecurityPolicy": {
+            "type": "object",
+            "properties": {
+                "require_2fa": {
+                    "type": "boolean"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "updated_by": {
                     "type": "string",
                     "example": "admin"
                 }
             }
         },
-        "models.LoginResponse": {
+        "models.Session": {
             "type": "object",
             "properties": {
-                "token": {
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "ip": {
                     "type": "string",
-                    "example": "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."
+                    "example": "203.0.113.7"
+                },
+                "last_used_at": {
+                    "type": "string"
+                },
+                "revoked_at": {
+                    "type": "string"
+                },
+                "user": {
+                    "$ref": "#/definitions/models.User"
+                },
+                "user_agent": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
                 }
             }
         },
-        "models.OllamaRequest": {
+        "models.SuccessResponse": {
             "type": "object",
             "properties": {
-                "model": {
+                "data": {},
+                "message": {
                     "type": "string",
-                    "example": "llama2"
+                    "example": "Operation successful"
+                }
+            }
+        },
+        "models.TwoFactorCodeRequest": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "123456"
+                }
+            }
+        },
+        "models.TwoFactorSetupResponse": {
+            "type": "object",
+            "properties": {
+                "otpauth_uri": {
+                    "type": "string",
+                    "example": "otpauth://totp/Ollama%20Web%20API:alice?secret=JBSWY3DPEHPK3PXP\u0026issuer=Ollama%20Web%20API"
                 },
-                "prompt": {
+                "secret": {
                     "type": "string",
-                    "example": "Why is the sky blue?"
+                    "example": "JBSWY3DPEHPK3PXP"
+                }
+            }
+        },
+        "models.TwoFactorStatusResponse": {
+            "type": "object",
+            "properties": {
+                "enabled": {
+                    "type": "boolean"
                 },
-                "stream": {
-                    "type": "boolean",
-                    "example": false
+                "recovery_codes_left": {
+                    "type": "integer"
+                },
+                "required": {
+                    "type": "boolean"
                 }
             }
         },
-        "models.OllamaResponse": {
+        "models.TwoFactorVerifyRequest": {
             "type": "object",
             "properties": {
-                "created_at": {
+                "challenge": {
                     "type": "string"
                 },
-                "done": {
+                "code": {
+                    "type": "string",
+                    "example": "123456"
+                }
+            }
+        },
+        "models.UpdateSecurityPolicyRequest": {
+            "type": "object",
+            "properties": {
+                "require_2fa": {
                     "type": "boolean"
+                }
+            }
+        },
+        "models.UpdateUserRoleRequest": {
+            "type": "object",
+            "properties": {
+                "role": {
+                    "type": "string",
+                    "example": "admin"
+                }
+            }
+        },
+        "models.UsageSummary": {
+            "type": "object",
+            "properties": {
+                "bucket": {
+                    "type": "string"
+                },
+                "completion_tokens": {
+                    "type": "integer"
+                },
+                "errors": {
+                    "type": "integer"
+                },
+                "eval_duration": {
+                    "description": "nanoseconds",
+                    "type": "integer"
                 },
                 "model": {
                     "type": "string"
                 },
-                "response": {
+                "project_id": {
+                    "type": "integer"
+                },
+                "project_name": {
                     "type": "string"
+                },
+                "prompt_tokens": {
+                    "type": "integer"
+                },
+                "requests": {
+                    "type": "integer"
+                },
+                "total_duration": {
+                    "description": "nanoseconds",
+                    "type": "integer"
+                },
+                "total_tokens": {
+                    "type": "integer"
                 }
             }
         },
-        "models.Project": {
+        "models.User": {
             "type": "object",
             "properties": {
-                "api_key": {
-                    "type": "string"
-                },
                 "created_at": {
                     "type": "string"
                 },
-                "description": {
-                    "type": "string"
+                "created_by": {
+                    "type": "string",
+                    "example": "admin"
                 },
                 "id": {
                     "type": "integer"
@@ -777,44 +5032,142 @@ const docTemplate = `{
                 "is_active": {
                     "type": "boolean"
                 },
+                "last_login_at": {
+                    "type": "string"
+                },
+                "provider": {
+                    "description": "local or oidc",
+                    "type": "string",
+                    "example": "local"
+                },
+                "role": {
+                    "description": "viewer, operator, admin or owner",
+                    "type": "string",
+                    "example": "operator"
+                },
+                "totp_enabled": {
+                    "type": "boolean"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string",
+                    "example": "alice"
+                }
+            }
+        },
+        "ollama.ListModel": {
+            "type": "object",
+            "properties": {
+                "details": {
+                    "$ref": "#/definitions/ollama.ModelDetails"
+                },
+                "digest": {
+                    "type": "string"
+                },
+                "model": {
+                    "type": "string"
+                },
+                "modified_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "size": {
+                    "type": "integer"
+                }
+            }
+        },
+        "ollama.ListResponse": {
+            "type": "object",
+            "properties": {
                 "models": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/models.ProjectModel"
+                        "$ref": "#/definitions/ollama.ListModel"
+                    }
+                }
+            }
+        },
+        "ollama.ModelDetails": {
+            "type": "object",
+            "properties": {
+                "families": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
                     }
                 },
-                "name": {
+                "family": {
                     "type": "string"
                 },
-                "updated_at": {
+                "format": {
+                    "type": "string"
+                },
+                "parameter_size": {
+                    "type": "string"
+                },
+                "parent_model": {
+                    "type": "string"
+                },
+                "quantization_level": {
                     "type": "string"
                 }
             }
         },
-        "models.ProjectModel": {
+        "ollama.ProcessModel": {
             "type": "object",
             "properties": {
-                "created_at": {
+                "details": {
+                    "$ref": "#/definitions/ollama.ModelDetails"
+                },
+                "digest": {
                     "type": "string"
                 },
-                "id": {
-                    "type": "integer"
+                "expires_at": {
+                    "type": "string"
                 },
-                "model_name": {
+                "model": {
                     "type": "string"
                 },
-                "project_id": {
+                "name": {
+                    "type": "string"
+                },
+                "size": {
+                    "type": "integer"
+                },
+                "size_vram": {
                     "type": "integer"
                 }
             }
         },
-        "models.SuccessResponse": {
+        "ollama.ProcessResponse": {
             "type": "object",
             "properties": {
-                "data": {},
-                "message": {
-                    "type": "string",
-                    "example": "Operation successful"
+                "models": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/ollama.ProcessModel"
+                    }
+                }
+            }
+        },
+        "ollama.ProgressResponse": {
+            "type": "object",
+            "properties": {
+                "completed": {
+                    "type": "integer"
+                },
+                "digest": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "total": {
+                    "type": "integer"
                 }
             }
         }
@@ -0,0 +1,85 @@
+package apikeys
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+)
+
+// PrefixLength is the number of leading characters of a key that are stored
+// in plaintext to find its project and to tell keys apart in listings
+const PrefixLength = 8
+
+// Prefix returns the visible prefix of a key
+func Prefix(key string) string {
+	if len(key) < PrefixLength {
+		return key
+	}
+	return key[:PrefixLength]
+}
+
+// Hash returns a random salt and the hex SHA-256 of the salt and key. Keys
+// carry 256 bits of randomness, so a fast hash is enough; the salt keeps
+// equal keys from having equal hashes.
+func Hash(key string) (salt, hash string, err error) {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", "", err
+	}
+	salt = hex.EncodeToString(bytes)
+	return salt, digest(salt, key), nil
+}
+
+// Verify reports in constant time whether key matches the salt and hash
+func Verify(key, salt, hash string) bool {
+	return subtle.ConstantTimeCompare([]byte(digest(salt, key)), []byte(hash)) == 1
+}
+
+func digest(salt, key string) string {
+	sum := sha256.Sum256([]byte(salt + key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Scopes a key can be limited to
+const (
+	ScopeGenerate   = "generate"
+	ScopeChat       = "chat"
+	ScopeEmbed      = "embed"
+	ScopeModelsRead = "models:read"
+	ScopeBatches    = "batches"
+)
+
+// AllScopes lists every scope
+var AllScopes = []string{ScopeGenerate, ScopeChat, ScopeEmbed, ScopeModelsRead, ScopeBatches}
+
+// ValidScope reports whether s is a known scope
+func ValidScope(s string) bool {
+	for _, scope := range AllScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Allows reports whether a key limited to scopes may use scope. A key
+// without scopes may use all of them.
+func Allows(scopes []string, scope string) bool {
+	return len(scopes) == 0 || contains(scopes, scope)
+}
+
+// AllowsModel reports whether a key limited to models may use model. A key
+// without models may use every model of its project.
+func AllowsModel(models []string, model string) bool {
+	return len(models) == 0 || contains(models, model)
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
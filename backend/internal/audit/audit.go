@@ -0,0 +1,49 @@
+package audit
+
+import (
+	"log"
+
+	"github.com/ollama-web-api/internal/database"
+	"github.com/ollama-web-api/internal/models"
+)
+
+// Actions recorded in the audit log
+const (
+	ActionIPDenied           = "ip_denied"
+	ActionUserCreated        = "user_created"
+	ActionUserDisabled       = "user_disabled"
+	ActionUserEnabled        = "user_enabled"
+	ActionPasswordReset      = "password_reset"
+	ActionRoleChanged        = "role_changed"
+	ActionSSODenied          = "sso_denied"
+	ActionSessionRevoked     = "session_revoked"
+	ActionRefreshTokenReused = "refresh_token_reused"
+	ActionLoginFailed        = "login_failed"
+	ActionLoginLocked        = "login_locked"
+	ActionLockoutCleared     = "lockout_cleared"
+	ActionTwoFactorEnabled   = "two_factor_enabled"
+	ActionTwoFactorDisabled  = "two_factor_disabled"
+	ActionTwoFactorReset     = "two_factor_reset"
+	ActionRecoveryCodeUsed   = "recovery_code_used"
+	ActionPolicyChanged      = "policy_changed"
+	ActionWebhookRotated     = "webhook_secret_rotated"
+	ActionAPIKeyRevoked      = "api_key_revoked"
+	ActionAPIKeyRotated      = "api_key_rotated"
+)
+
+// Record writes an event to the audit log and the server log. Failing to
+// store it is only logged, so auditing never fails the request.
+func Record(event *models.AuditEvent) {
+	log.Printf("Audit: %s actor=%q project=%v key=%v ip=%s %s",
+		event.Action, event.Actor, deref(event.ProjectID), deref(event.APIKeyID), event.IP, event.Detail)
+	if err := database.DB.Create(event).Error; err != nil {
+		log.Printf("Failed to store audit event %s: %v", event.Action, err)
+	}
+}
+
+func deref(id *uint) interface{} {
+	if id == nil {
+		return "-"
+	}
+	return *id
+}
@@ -0,0 +1,275 @@
+package backends
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ollama-web-api/internal/database"
+	"github.com/ollama-web-api/internal/models"
+	"github.com/ollama-web-api/internal/ollama"
+)
+
+// Backend is a single Ollama server in the pool
+type Backend struct {
+	ID     uint
+	Name   string
+	URL    string
+	Client *ollama.Client
+
+	inflight atomic.Int64
+
+	mu          sync.RWMutex
+	healthy     bool
+	models      map[string]bool
+	loaded      map[string]bool
+	lastChecked time.Time
+	lastError   string
+}
+
+func newBackend(id uint, name, url string) *Backend {
+	client := ollama.NewClient(url)
+	return &Backend{ID: id, Name: name, URL: client.BaseURL(), Client: client}
+}
+
+// Begin counts a request routed to the backend. The returned function must
+// be called once the response has been consumed.
+func (b *Backend) Begin() func() {
+	b.inflight.Add(1)
+	var once sync.Once
+	return func() { once.Do(func() { b.inflight.Add(-1) }) }
+}
+
+// MarkDown flags the backend as unhealthy until the next successful check
+func (b *Backend) MarkDown(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.healthy {
+		log.Printf("Ollama backend %s marked unhealthy: %v", b.Name, err)
+	}
+	b.healthy = false
+	b.lastError = err.Error()
+}
+
+// Healthy reports whether the last health check succeeded
+func (b *Backend) Healthy() bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.healthy
+}
+
+// hasModel reports whether the backend has the model installed and loaded
+func (b *Backend) hasModel(model string) (installed, loaded bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.models[model], b.loaded[model]
+}
+
+// Status returns a snapshot of the backend state
+func (b *Backend) Status() models.BackendStatus {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return models.BackendStatus{
+		ID:           b.ID,
+		Name:         b.Name,
+		URL:          b.URL,
+		Healthy:      b.healthy,
+		Models:       sortedKeys(b.models),
+		LoadedModels: sortedKeys(b.loaded),
+		InFlight:     b.inflight.Load(),
+		LastChecked:  b.lastChecked,
+		LastError:    b.lastError,
+	}
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+var (
+	pool []*Backend
+	mu   sync.RWMutex
+	next atomic.Uint64
+)
+
+// NormalizeModel adds the implicit ":latest" tag Ollama uses for untagged
+// model names so that "llama2" matches "llama2:latest"
+func NormalizeModel(name string) string {
+	if name != "" && !strings.Contains(name, ":") {
+		return name + ":latest"
+	}
+	return name
+}
+
+// configBackends parses OLLAMA_BACKENDS, a comma-separated list of URLs or
+// name=URL pairs. Without it the pool is the single OLLAMA_BASE_URL.
+func configBackends() []*Backend {
+	var backends []*Backend
+	for i, entry := range strings.Split(os.Getenv("OLLAMA_BACKENDS"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, url, found := strings.Cut(entry, "=")
+		if !found {
+			name, url = fmt.Sprintf("backend-%d", i+1), entry
+		}
+		backends = append(backends, newBackend(0, strings.TrimSpace(name), strings.TrimSpace(url)))
+	}
+	if len(backends) > 0 {
+		return backends
+	}
+
+	url := os.Getenv("OLLAMA_BASE_URL")
+	if url == "" {
+		url = "http://localhost:11434"
+	}
+	return []*Backend{newBackend(0, "default", url)}
+}
+
+// Setup builds the pool from configuration and the database, runs an
+// initial health check and keeps checking every BACKEND_HEALTH_INTERVAL
+// (default 15s)
+func Setup() {
+	if err := Reload(); err != nil {
+		log.Printf("Failed to load Ollama backends from the database: %v", err)
+	}
+	CheckAll()
+
+	interval := 15 * time.Second
+	if v := os.Getenv("BACKEND_HEALTH_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			interval = d
+		} else {
+			log.Printf("Invalid BACKEND_HEALTH_INTERVAL %q, using %s", v, interval)
+		}
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			CheckAll()
+		}
+	}()
+}
+
+// Reload rebuilds the pool from configuration and the enabled backends in
+// the database. Backends that stay in the pool keep their health state.
+func Reload() error {
+	backends := configBackends()
+
+	var rows []models.OllamaBackend
+	var err error
+	if database.DB != nil {
+		err = database.DB.Where("enabled = ?", true).Order("id").Find(&rows).Error
+	}
+	for _, row := range rows {
+		backends = append(backends, newBackend(row.ID, row.Name, row.URL))
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	key := func(b *Backend) string { return fmt.Sprintf("%d|%s|%s", b.ID, b.Name, b.URL) }
+	existing := make(map[string]*Backend, len(pool))
+	for _, b := range pool {
+		existing[key(b)] = b
+	}
+	for i, b := range backends {
+		if old, ok := existing[key(b)]; ok {
+			backends[i] = old
+		}
+	}
+	pool = backends
+	return err
+}
+
+// All returns every backend in the pool
+func All() []*Backend {
+	mu.RLock()
+	defer mu.RUnlock()
+	return append([]*Backend(nil), pool...)
+}
+
+// Find returns the backend with the given name, or nil
+func Find(name string) *Backend {
+	for _, b := range All() {
+		if b.Name == name {
+			return b
+		}
+	}
+	return nil
+}
+
+// Available returns the healthy backends. Administrative calls such as
+// pulling a model apply to all of them.
+func Available() []*Backend {
+	var healthy []*Backend
+	for _, b := range All() {
+		if b.Healthy() {
+			healthy = append(healthy, b)
+		}
+	}
+	return healthy
+}
+
+// Candidates returns the backends to try for a request for model, in order
+// of preference: healthy backends that have the model loaded, then healthy
+// backends that have it installed. Backends are rotated and ordered by load
+// within each tier. When no healthy backend has the model, all healthy
+// backends are returned so the caller gets Ollama's own error; when none is
+// healthy, every backend is returned as a last resort.
+func Candidates(model string) []*Backend {
+	backends := All()
+	if len(backends) == 0 {
+		return nil
+	}
+
+	// Rotate so that equally loaded backends take turns
+	offset := int(next.Add(1) % uint64(len(backends)))
+	rotated := make([]*Backend, 0, len(backends))
+	rotated = append(rotated, backends[offset:]...)
+	backends = append(rotated, backends[:offset]...)
+
+	model = NormalizeModel(model)
+	var loaded, installed, healthy []*Backend
+	for _, b := range backends {
+		if !b.Healthy() {
+			continue
+		}
+		healthy = append(healthy, b)
+		switch has, isLoaded := b.hasModel(model); {
+		case isLoaded:
+			loaded = append(loaded, b)
+		case has:
+			installed = append(installed, b)
+		}
+	}
+
+	byLoad := func(list []*Backend) {
+		sort.SliceStable(list, func(i, j int) bool {
+			return list[i].inflight.Load() < list[j].inflight.Load()
+		})
+	}
+	byLoad(loaded)
+	byLoad(installed)
+	byLoad(healthy)
+
+	switch {
+	case len(loaded)+len(installed) > 0:
+		return append(loaded, installed...)
+	case len(healthy) > 0:
+		return healthy
+	default:
+		return backends
+	}
+}
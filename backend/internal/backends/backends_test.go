@@ -0,0 +1,201 @@
+package backends
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/ollama-web-api/internal/ollama"
+)
+
+// testBackend returns a backend in the given state
+func testBackend(name string, healthy bool, inflight int64, installed, loaded []string) *Backend {
+	b := newBackend(0, name, "http://"+name+".invalid")
+	b.healthy = healthy
+	b.inflight.Store(inflight)
+	b.models = map[string]bool{}
+	b.loaded = map[string]bool{}
+	for _, m := range installed {
+		b.models[NormalizeModel(m)] = true
+	}
+	for _, m := range loaded {
+		b.loaded[NormalizeModel(m)] = true
+	}
+	return b
+}
+
+// setPool replaces the pool for the duration of a test
+func setPool(t *testing.T, backends ...*Backend) {
+	t.Helper()
+	mu.Lock()
+	previous := pool
+	pool = backends
+	mu.Unlock()
+	t.Cleanup(func() {
+		mu.Lock()
+		pool = previous
+		mu.Unlock()
+	})
+}
+
+func names(backends []*Backend) []string {
+	var list []string
+	for _, b := range backends {
+		list = append(list, b.Name)
+	}
+	return list
+}
+
+func TestCandidates(t *testing.T) {
+	tests := []struct {
+		name  string
+		pool  []*Backend
+		model string
+		want  []string
+	}{
+		{
+			name: "loaded before installed",
+			pool: []*Backend{
+				testBackend("a", true, 0, []string{"llama2"}, nil),
+				testBackend("b", true, 5, []string{"llama2"}, []string{"llama2"}),
+			},
+			model: "llama2",
+			want:  []string{"b", "a"},
+		},
+		{
+			name: "least busy first within a tier",
+			pool: []*Backend{
+				testBackend("a", true, 3, []string{"llama2"}, nil),
+				testBackend("b", true, 1, []string{"llama2"}, nil),
+				testBackend("c", true, 2, []string{"llama2"}, nil),
+			},
+			model: "llama2",
+			want:  []string{"b", "c", "a"},
+		},
+		{
+			name: "untagged model matches latest",
+			pool: []*Backend{
+				testBackend("a", true, 0, []string{"mistral:7b"}, nil),
+				testBackend("b", true, 0, []string{"llama2:latest"}, nil),
+			},
+			model: "llama2",
+			want:  []string{"b"},
+		},
+		{
+			name: "unhealthy backends are skipped",
+			pool: []*Backend{
+				testBackend("a", false, 0, []string{"llama2"}, []string{"llama2"}),
+				testBackend("b", true, 4, []string{"llama2"}, nil),
+			},
+			model: "llama2",
+			want:  []string{"b"},
+		},
+		{
+			name: "all healthy backends when none has the model",
+			pool: []*Backend{
+				testBackend("a", true, 2, nil, nil),
+				testBackend("b", false, 0, nil, nil),
+				testBackend("c", true, 1, nil, nil),
+			},
+			model: "llama2",
+			want:  []string{"c", "a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setPool(t, tt.pool...)
+			if got := names(Candidates(tt.model)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Candidates(%q) = %v, want %v", tt.model, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCandidatesWithoutHealthyBackends(t *testing.T) {
+	setPool(t,
+		testBackend("a", false, 0, nil, nil),
+		testBackend("b", false, 0, nil, nil),
+	)
+	if got := Candidates("llama2"); len(got) != 2 {
+		t.Errorf("Candidates returned %v, want every backend as a last resort", names(got))
+	}
+
+	setPool(t)
+	if got := Candidates("llama2"); got != nil {
+		t.Errorf("Candidates of an empty pool = %v, want nil", names(got))
+	}
+}
+
+func TestCandidatesRotateEquallyLoaded(t *testing.T) {
+	setPool(t,
+		testBackend("a", true, 0, []string{"llama2"}, nil),
+		testBackend("b", true, 0, []string{"llama2"}, nil),
+	)
+	first := Candidates("llama2")[0].Name
+	second := Candidates("llama2")[0].Name
+	if first == second {
+		t.Errorf("equally loaded backends did not take turns: %s twice", first)
+	}
+}
+
+func TestFailoverOnConnectError(t *testing.T) {
+	live := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"models":[]}`))
+	}))
+	defer live.Close()
+	dead := httptest.NewServer(http.NotFoundHandler())
+	deadURL := dead.URL
+	dead.Close()
+
+	down := newBackend(0, "down", deadURL)
+	up := newBackend(0, "up", live.URL)
+	for _, b := range []*Backend{down, up} {
+		b.healthy = true
+		b.models = map[string]bool{"llama2:latest": true}
+	}
+	// The dead backend is preferred, so the request has to fail over
+	down.loaded = map[string]bool{"llama2:latest": true}
+	setPool(t, down, up)
+
+	var served string
+	for _, b := range Candidates("llama2") {
+		_, err := b.Client.Tags(context.Background())
+		if err == nil {
+			served = b.Name
+			break
+		}
+		if !ollama.IsConnectError(err) {
+			t.Fatalf("backend %s: %v, want a connection error", b.Name, err)
+		}
+		b.MarkDown(err)
+	}
+
+	if served != "up" {
+		t.Fatalf("request served by %q, want up", served)
+	}
+	if down.Healthy() || down.Status().LastError == "" {
+		t.Errorf("unreachable backend was not marked down: %+v", down.Status())
+	}
+	if got := names(Candidates("llama2")); !reflect.DeepEqual(got, []string{"up"}) {
+		t.Errorf("Candidates after failover = %v, want [up]", got)
+	}
+}
+
+func TestFindAndAvailable(t *testing.T) {
+	setPool(t,
+		testBackend("a", true, 0, nil, nil),
+		testBackend("b", false, 0, nil, nil),
+		testBackend("c", true, 0, nil, nil),
+	)
+	if b := Find("b"); b == nil || b.Name != "b" {
+		t.Errorf("Find(b) = %v", b)
+	}
+	if b := Find("zz"); b != nil {
+		t.Errorf("Find(zz) = %s, want nil", b.Name)
+	}
+	if got := names(Available()); !reflect.DeepEqual(got, []string{"a", "c"}) {
+		t.Errorf("Available() = %v, want [a c]", got)
+	}
+}
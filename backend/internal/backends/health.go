@@ -0,0 +1,67 @@
+package backends
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ollama-web-api/internal/ollama"
+)
+
+// healthTimeout bounds a single health check
+const healthTimeout = 5 * time.Second
+
+// Check refreshes the health, installed models and loaded models of the
+// backend from /api/tags and /api/ps
+func (b *Backend) Check() {
+	ctx, cancel := context.WithTimeout(context.Background(), healthTimeout)
+	defer cancel()
+
+	tags, err := b.Client.Tags(ctx)
+	var installed, loaded map[string]bool
+	if err == nil {
+		installed = make(map[string]bool, len(tags.Models))
+		for _, m := range tags.Models {
+			installed[NormalizeModel(m.Name)] = true
+			installed[NormalizeModel(m.Model)] = true
+		}
+
+		var ps *ollama.ProcessResponse
+		ps, err = b.Client.Ps(ctx)
+		if err == nil {
+			loaded = make(map[string]bool, len(ps.Models))
+			for _, m := range ps.Models {
+				loaded[NormalizeModel(m.Name)] = true
+				loaded[NormalizeModel(m.Model)] = true
+			}
+		}
+	}
+	delete(installed, "")
+	delete(loaded, "")
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lastChecked = time.Now()
+	if err != nil {
+		b.healthy = false
+		b.lastError = err.Error()
+		return
+	}
+	b.healthy = true
+	b.lastError = ""
+	b.models = installed
+	b.loaded = loaded
+}
+
+// CheckAll checks every backend in the pool concurrently
+func CheckAll() {
+	var wg sync.WaitGroup
+	for _, b := range All() {
+		wg.Add(1)
+		go func(b *Backend) {
+			defer wg.Done()
+			b.Check()
+		}(b)
+	}
+	wg.Wait()
+}
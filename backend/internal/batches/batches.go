@@ -0,0 +1,496 @@
+package batches
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ollama-web-api/internal/database"
+	"github.com/ollama-web-api/internal/models"
+	"gorm.io/gorm"
+)
+
+// Batch states
+const (
+	StatusQueued     = "queued"
+	StatusInProgress = "in_progress"
+	StatusCompleted  = "completed"
+	StatusFailed     = "failed"
+	StatusCancelled  = "cancelled"
+)
+
+// Item states
+const (
+	ItemPending   = "pending"
+	ItemSucceeded = "succeeded"
+	ItemFailed    = "failed"
+	ItemCancelled = "cancelled"
+)
+
+const (
+	// pollInterval is how often the worker looks for queued batches and a
+	// running batch refreshes its heartbeat
+	pollInterval = 2 * time.Second
+	// staleAfter is how long a batch may go without a heartbeat before it
+	// is considered abandoned by a crashed replica and requeued
+	staleAfter = time.Minute
+	// pageSize is how many pending items are loaded at a time
+	pageSize = 100
+)
+
+// ErrCancelled is the cancellation cause of the context of a batch that was
+// cancelled through the API
+var ErrCancelled = errors.New("batch cancelled")
+
+// Result is the outcome of a single batch request
+type Result struct {
+	StatusCode int
+	// Body is the response, or the error response of a failed request
+	Body interface{}
+	// Error describes a failed request
+	Error string
+}
+
+// Runner runs a single batch request for the project with the API key that
+// uploaded the batch, which is nil for batches of earlier versions. It must
+// stop when ctx is done.
+type Runner func(ctx context.Context, project *models.Project, key *models.APIKey, item *models.BatchItem) Result
+
+// accessError is the cancellation cause of a batch whose project or API key
+// may no longer run it
+type accessError struct {
+	reason string
+}
+
+func (e *accessError) Error() string {
+	return e.reason
+}
+
+var (
+	runner      Runner
+	concurrency = 4
+	maxRequests = 50000
+	maxFileSize = 100 << 20
+	wake        = make(chan struct{}, 1)
+
+	// running holds the cancel functions of the batches running in this process
+	running   = make(map[string]context.CancelCauseFunc)
+	runningMu sync.Mutex
+)
+
+// NewID returns a random batch ID
+func NewID() (string, error) {
+	bytes := make([]byte, 12)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return "batch-" + hex.EncodeToString(bytes), nil
+}
+
+// Setup starts the batch worker, which runs one batch at a time with up to
+// BATCH_CONCURRENCY requests in flight (default 4). Batches are claimed
+// through the database, so several replicas can share the work. Uploads are
+// limited to BATCH_MAX_REQUESTS requests (default 50000) and
+// BATCH_MAX_FILE_MB megabytes (default 100).
+func Setup(run Runner) {
+	runner = run
+
+	if v := os.Getenv("BATCH_CONCURRENCY"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			concurrency = n
+		} else {
+			log.Printf("Invalid BATCH_CONCURRENCY %q, using %d", v, concurrency)
+		}
+	}
+	if v := os.Getenv("BATCH_MAX_REQUESTS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			maxRequests = n
+		} else {
+			log.Printf("Invalid BATCH_MAX_REQUESTS %q, using %d", v, maxRequests)
+		}
+	}
+	if v := os.Getenv("BATCH_MAX_FILE_MB"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			maxFileSize = n << 20
+		} else {
+			log.Printf("Invalid BATCH_MAX_FILE_MB %q, using %d", v, maxFileSize>>20)
+		}
+	}
+
+	go work()
+	log.Printf("Started batch worker with %d concurrent requests", concurrency)
+}
+
+// MaxFileSize returns the largest batch file that may be uploaded, in bytes
+func MaxFileSize() int {
+	return maxFileSize
+}
+
+// Notify wakes the worker to pick up a newly queued batch
+func Notify() {
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+}
+
+// work runs queued batches until the process exits
+func work() {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		requeueStale()
+
+		batch, err := claim()
+		if err != nil {
+			log.Printf("Failed to claim batch: %v", err)
+		}
+		if batch != nil {
+			process(batch)
+			continue
+		}
+
+		select {
+		case <-wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// claim marks the oldest queued batch as in progress and returns it, or nil
+// when no batch is queued
+func claim() (*models.Batch, error) {
+	for {
+		var queued []models.Batch
+		if err := database.DB.Where("status = ?", StatusQueued).Order("created_at").Limit(1).Find(&queued).Error; err != nil {
+			return nil, err
+		}
+		if len(queued) == 0 {
+			return nil, nil
+		}
+		batch := queued[0]
+
+		now := time.Now()
+		updates := map[string]interface{}{"status": StatusInProgress, "heartbeat_at": now}
+		if batch.StartedAt == nil {
+			updates["started_at"] = now
+		}
+		result := database.DB.Model(&models.Batch{}).
+			Where("id = ? AND status = ?", batch.ID, StatusQueued).
+			Updates(updates)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 1 {
+			batch.Status = StatusInProgress
+			return &batch, nil
+		}
+		// Another replica got there first; try the next batch
+	}
+}
+
+// requeueStale puts batches whose worker stopped sending heartbeats back in
+// the queue. Their pending items run again; finished items are kept.
+func requeueStale() {
+	result := database.DB.Model(&models.Batch{}).
+		Where("status = ? AND heartbeat_at < ?", StatusInProgress, time.Now().Add(-staleAfter)).
+		Updates(map[string]interface{}{"status": StatusQueued, "heartbeat_at": nil})
+	if result.Error != nil {
+		log.Printf("Failed to requeue stale batches: %v", result.Error)
+	} else if result.RowsAffected > 0 {
+		log.Printf("Requeued %d abandoned batches", result.RowsAffected)
+	}
+}
+
+// process runs the pending items of a claimed batch
+func process(batch *models.Batch) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+
+	runningMu.Lock()
+	running[batch.ID] = cancel
+	runningMu.Unlock()
+	defer func() {
+		runningMu.Lock()
+		delete(running, batch.ID)
+		runningMu.Unlock()
+	}()
+
+	go heartbeat(ctx, batch, cancel)
+
+	log.Printf("Running batch %s for project %d", batch.ID, batch.ProjectID)
+	sem := make(chan struct{}, concurrency)
+	for ctx.Err() == nil {
+		// The project and key are loaded again for every page, so changes to
+		// them apply to the rest of the batch
+		project, key, err := access(batch)
+		var denied *accessError
+		if errors.As(err, &denied) {
+			fail(batch, denied.reason)
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to load project of batch %s: %v", batch.ID, err)
+			return
+		}
+
+		var items []models.BatchItem
+		if err := database.DB.Where("batch_id = ? AND status = ?", batch.ID, ItemPending).
+			Order("line").Limit(pageSize).Find(&items).Error; err != nil {
+			// Leave the batch to be requeued once its heartbeat goes stale
+			log.Printf("Failed to load items of batch %s: %v", batch.ID, err)
+			return
+		}
+		if len(items) == 0 {
+			break
+		}
+
+		var wg sync.WaitGroup
+		var progressed sync.Map
+		for i := range items {
+			if ctx.Err() != nil {
+				break
+			}
+			sem <- struct{}{}
+			wg.Add(1)
+			go func(item *models.BatchItem) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				if runItem(ctx, batch, project, key, item) {
+					progressed.Store(item.ID, true)
+				}
+			}(&items[i])
+		}
+		wg.Wait()
+
+		if _, ok := progressed.Load(items[0].ID); !ok && ctx.Err() == nil {
+			// The first item could not be stored; stop instead of retrying it
+			// in a loop and let the batch be requeued
+			log.Printf("Batch %s is not making progress, stopping", batch.ID)
+			return
+		}
+	}
+
+	var denied *accessError
+	if errors.As(context.Cause(ctx), &denied) {
+		fail(batch, denied.reason)
+		return
+	}
+	if errors.Is(context.Cause(ctx), ErrCancelled) {
+		// Cancel already stored the outcome
+		return
+	}
+
+	now := time.Now()
+	result := database.DB.Model(&models.Batch{}).
+		Where("id = ? AND status = ?", batch.ID, StatusInProgress).
+		Updates(map[string]interface{}{"status": StatusCompleted, "completed_at": now})
+	if result.Error != nil {
+		log.Printf("Failed to complete batch %s: %v", batch.ID, result.Error)
+		return
+	}
+	log.Printf("Batch %s completed", batch.ID)
+}
+
+// access loads the project of a batch and the API key that uploaded it. It
+// returns an *accessError when they may no longer run the batch.
+func access(batch *models.Batch) (*models.Project, *models.APIKey, error) {
+	var project models.Project
+	if err := database.DB.Preload("Models").Limit(1).Find(&project, batch.ProjectID).Error; err != nil {
+		return nil, nil, err
+	}
+	if project.ID == 0 {
+		return nil, nil, &accessError{"project was deleted"}
+	}
+	if !project.IsActive {
+		return nil, nil, &accessError{"project is inactive"}
+	}
+	if batch.APIKeyID == nil {
+		return &project, nil, nil
+	}
+
+	var key models.APIKey
+	if err := database.DB.Limit(1).Find(&key, *batch.APIKeyID).Error; err != nil {
+		return nil, nil, err
+	}
+	if key.ID == 0 || key.RevokedAt != nil {
+		return nil, nil, &accessError{"API key was revoked"}
+	}
+	if key.ExpiresAt != nil && !key.ExpiresAt.After(time.Now()) {
+		return nil, nil, &accessError{"API key expired"}
+	}
+	return &project, &key, nil
+}
+
+// runItem runs a batch request and stores its result. It reports whether the
+// item left the pending state.
+func runItem(ctx context.Context, batch *models.Batch, project *models.Project, key *models.APIKey, item *models.BatchItem) bool {
+	result := runner(ctx, project, key, item)
+	if errors.Is(context.Cause(ctx), ErrCancelled) {
+		// Cancel marked the item as cancelled
+		return true
+	}
+	var denied *accessError
+	if errors.As(context.Cause(ctx), &denied) {
+		// The item fails with the batch
+		return true
+	}
+
+	body, err := json.Marshal(result.Body)
+	if err != nil {
+		result = Result{StatusCode: 500, Error: "failed to encode response: " + err.Error()}
+		body = nil
+	}
+
+	status, counter := ItemSucceeded, "completed"
+	if result.StatusCode != 200 {
+		status, counter = ItemFailed, "failed"
+	}
+
+	now := time.Now()
+	updated := database.DB.Model(&models.BatchItem{}).
+		Where("id = ? AND status = ?", item.ID, ItemPending).
+		Updates(map[string]interface{}{
+			"status":       status,
+			"status_code":  result.StatusCode,
+			"result":       string(body),
+			"error":        result.Error,
+			"completed_at": now,
+		})
+	if updated.Error != nil {
+		log.Printf("Failed to store result of batch %s line %d: %v", batch.ID, item.Line, updated.Error)
+		return false
+	}
+	if updated.RowsAffected == 0 {
+		return true
+	}
+
+	if err := database.DB.Model(&models.Batch{}).Where("id = ?", batch.ID).
+		Update(counter, gorm.Expr(counter+" + 1")).Error; err != nil {
+		log.Printf("Failed to update progress of batch %s: %v", batch.ID, err)
+	}
+	return true
+}
+
+// fail ends a batch that cannot run and fails its pending items with the
+// same message
+func fail(batch *models.Batch, message string) {
+	log.Printf("Batch %s failed: %s", batch.ID, message)
+	now := time.Now()
+	if err := database.DB.Model(&models.Batch{}).
+		Where("id = ? AND status = ?", batch.ID, StatusInProgress).
+		Updates(map[string]interface{}{"status": StatusFailed, "error": message, "completed_at": now}).Error; err != nil {
+		log.Printf("Failed to store failure of batch %s: %v", batch.ID, err)
+	}
+
+	result := database.DB.Model(&models.BatchItem{}).
+		Where("batch_id = ? AND status = ?", batch.ID, ItemPending).
+		Updates(map[string]interface{}{"status": ItemFailed, "error": message, "completed_at": now})
+	if result.Error != nil {
+		log.Printf("Failed to fail pending items of batch %s: %v", batch.ID, result.Error)
+		return
+	}
+	if err := database.DB.Model(&models.Batch{}).Where("id = ?", batch.ID).
+		Update("failed", gorm.Expr("failed + ?", result.RowsAffected)).Error; err != nil {
+		log.Printf("Failed to update progress of batch %s: %v", batch.ID, err)
+	}
+}
+
+// cancelPending marks the items of a batch that have not run as cancelled
+func cancelPending(id string, now time.Time) {
+	if err := database.DB.Model(&models.BatchItem{}).
+		Where("batch_id = ? AND status = ?", id, ItemPending).
+		Updates(map[string]interface{}{"status": ItemCancelled, "completed_at": now}).Error; err != nil {
+		log.Printf("Failed to cancel pending items of batch %s: %v", id, err)
+	}
+}
+
+// heartbeat marks the batch as alive until ctx is done and cancels it once
+// its status changes, which happens when it is cancelled on another replica,
+// or once its project or API key may no longer run it
+func heartbeat(ctx context.Context, batch *models.Batch, cancel context.CancelCauseFunc) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			result := database.DB.Model(&models.Batch{}).
+				Where("id = ? AND status = ?", batch.ID, StatusInProgress).
+				Update("heartbeat_at", time.Now())
+			if result.Error == nil && result.RowsAffected == 0 {
+				cancel(ErrCancelled)
+				return
+			}
+
+			var denied *accessError
+			if _, _, err := access(batch); errors.As(err, &denied) {
+				cancel(denied)
+				return
+			}
+		}
+	}
+}
+
+// Cancel stops a queued or running batch. Requests in flight are aborted and
+// pending ones never run. It reports false when the batch had already
+// finished.
+func Cancel(batch *models.Batch) (bool, error) {
+	now := time.Now()
+	result := database.DB.Model(&models.Batch{}).
+		Where("id = ? AND status IN ?", batch.ID, []string{StatusQueued, StatusInProgress}).
+		Updates(map[string]interface{}{"status": StatusCancelled, "completed_at": now})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	cancelPending(batch.ID, now)
+
+	runningMu.Lock()
+	if cancel, ok := running[batch.ID]; ok {
+		cancel(ErrCancelled)
+	}
+	runningMu.Unlock()
+
+	log.Printf("Batch %s cancelled", batch.ID)
+	return true, nil
+}
+
+// Create stores a new batch with its items and wakes the worker
+func Create(batch *models.Batch, items []models.BatchItem) error {
+	batch.Status = StatusQueued
+	batch.Total = len(items)
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(batch).Error; err != nil {
+			return err
+		}
+		for i := range items {
+			items[i].BatchID = batch.ID
+		}
+		return tx.CreateInBatches(items, 500).Error
+	})
+	if err != nil {
+		return err
+	}
+	Notify()
+	return nil
+}
+
+// Load returns a batch of the project
+func Load(id string, projectID uint) (*models.Batch, error) {
+	var batch models.Batch
+	if err := database.DB.Where("id = ? AND project_id = ?", id, projectID).First(&batch).Error; err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
@@ -0,0 +1,143 @@
+package batches
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/ollama-web-api/internal/database"
+	"github.com/ollama-web-api/internal/models"
+)
+
+// Endpoints a batch request may target
+const (
+	URLGenerate = "/api/ollama/generate"
+	URLChat     = "/api/ollama/chat"
+)
+
+// maxLineSize bounds a single line of an uploaded batch file
+const maxLineSize = 8 << 20
+
+// LineError reports an invalid line of a batch file
+type LineError struct {
+	Line int
+	Err  string
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Err)
+}
+
+// Parse reads a batch file and returns its requests as pending items. Every
+// line must be a request object with a unique custom_id; blank lines are
+// skipped. The request bodies themselves are validated when they run.
+func Parse(r io.Reader) ([]models.BatchItem, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64<<10), maxLineSize)
+
+	var items []models.BatchItem
+	seen := make(map[string]int)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+
+		var req models.BatchRequestLine
+		if err := json.Unmarshal(text, &req); err != nil {
+			return nil, &LineError{line, "invalid JSON: " + err.Error()}
+		}
+		if req.CustomID == "" {
+			return nil, &LineError{line, "custom_id is required"}
+		}
+		if first, ok := seen[req.CustomID]; ok {
+			return nil, &LineError{line, fmt.Sprintf("custom_id %q is already used on line %d", req.CustomID, first)}
+		}
+		seen[req.CustomID] = line
+		if req.Method != "" && req.Method != http.MethodPost {
+			return nil, &LineError{line, "method must be POST"}
+		}
+		if req.URL != URLGenerate && req.URL != URLChat {
+			return nil, &LineError{line, fmt.Sprintf("url must be %s or %s", URLGenerate, URLChat)}
+		}
+		body := bytes.TrimSpace(req.Body)
+		if len(body) == 0 || body[0] != '{' {
+			return nil, &LineError{line, "body must be a JSON object"}
+		}
+
+		if len(items) == maxRequests {
+			return nil, &LineError{line, fmt.Sprintf("a batch holds at most %d requests", maxRequests)}
+		}
+		items = append(items, models.BatchItem{
+			Line:     line,
+			CustomID: req.CustomID,
+			URL:      req.URL,
+			Request:  string(body),
+			Status:   ItemPending,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		if err == bufio.ErrTooLong {
+			return nil, &LineError{line + 1, fmt.Sprintf("line is longer than %d bytes", maxLineSize)}
+		}
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("the file contains no requests")
+	}
+	return items, nil
+}
+
+// WriteResults writes a line for every finished request of the batch to w,
+// in the order of the uploaded file
+func WriteResults(w io.Writer, batch *models.Batch) error {
+	encoder := json.NewEncoder(w)
+	lastID := uint(0)
+	for {
+		var items []models.BatchItem
+		if err := database.DB.Where("batch_id = ? AND status <> ? AND id > ?", batch.ID, ItemPending, lastID).
+			Order("id").Limit(pageSize).Find(&items).Error; err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			return nil
+		}
+
+		for _, item := range items {
+			if err := encoder.Encode(resultLine(batch, &item)); err != nil {
+				return err
+			}
+		}
+		lastID = items[len(items)-1].ID
+	}
+}
+
+// resultLine converts a finished item to its line in the results file
+func resultLine(batch *models.Batch, item *models.BatchItem) models.BatchResultLine {
+	line := models.BatchResultLine{
+		ID:       fmt.Sprintf("%s-%d", batch.ID, item.Line),
+		CustomID: item.CustomID,
+	}
+	switch item.Status {
+	case ItemCancelled:
+		line.Error = &models.BatchResultError{
+			Code:    "cancelled",
+			Message: "The batch ended before the request ran",
+		}
+		return line
+	case ItemFailed:
+		line.Error = &models.BatchResultError{Code: "request_failed", Message: item.Error}
+	}
+	if item.Result != "" {
+		line.Response = &models.BatchResultResponse{
+			StatusCode: item.StatusCode,
+			Body:       json.RawMessage(item.Result),
+		}
+	}
+	return line
+}
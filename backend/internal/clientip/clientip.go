@@ -0,0 +1,157 @@
+package clientip
+
+import (
+	"fmt"
+	"log"
+	"net/netip"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// defaultTrustedProxies trusts only proxies on the same host, such as
+// cloudflared forwarding to localhost
+const defaultTrustedProxies = "127.0.0.0/8,::1/128"
+
+var (
+	mu      sync.RWMutex
+	trusted []netip.Prefix
+)
+
+func init() {
+	trusted, _ = ParsePrefixes(strings.Split(defaultTrustedProxies, ","))
+}
+
+// Setup reads TRUSTED_PROXIES, a comma-separated list of the addresses or
+// CIDRs of the proxies in front of the server. Only their X-Forwarded-For
+// headers are believed. An empty value trusts no proxy.
+func Setup() {
+	value, ok := os.LookupEnv("TRUSTED_PROXIES")
+	if !ok {
+		value = defaultTrustedProxies
+	}
+
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	prefixes, err := ParsePrefixes(values)
+	if err != nil {
+		log.Printf("Invalid TRUSTED_PROXIES %q, using %s: %v", value, defaultTrustedProxies, err)
+		prefixes, _ = ParsePrefixes(strings.Split(defaultTrustedProxies, ","))
+	}
+
+	mu.Lock()
+	trusted = prefixes
+	mu.Unlock()
+	log.Printf("Trusting X-Forwarded-For from %d proxy ranges", len(prefixes))
+}
+
+// ParsePrefixes parses addresses and CIDRs. A bare address matches only
+// itself.
+func ParsePrefixes(values []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(values))
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if strings.Contains(v, "/") {
+			prefix, err := netip.ParsePrefix(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid CIDR %q", v)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IP address %q", v)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
+// Normalize parses addresses and CIDRs and returns them in canonical CIDR
+// form, for storing
+func Normalize(values []string) ([]string, error) {
+	prefixes, err := ParsePrefixes(values)
+	if err != nil {
+		return nil, err
+	}
+	normalized := make([]string, len(prefixes))
+	for i, prefix := range prefixes {
+		normalized[i] = prefix.String()
+	}
+	return normalized, nil
+}
+
+func isTrusted(addr netip.Addr) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return contains(trusted, addr)
+}
+
+func contains(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve returns the address of the client behind remote. When remote is a
+// trusted proxy, forwardedFor is walked from the right, skipping trusted
+// proxies, so a client cannot pick its address by sending X-Forwarded-For
+// itself.
+func Resolve(remote netip.Addr, forwardedFor []string) netip.Addr {
+	client := remote.Unmap()
+	if !isTrusted(client) {
+		return client
+	}
+
+	var hops []string
+	for _, header := range forwardedFor {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		client = addr.Unmap()
+		if !isTrusted(client) {
+			break
+		}
+	}
+	return client
+}
+
+// Get returns the address of the client of a request
+func Get(c *fiber.Ctx) netip.Addr {
+	remote, _ := netip.AddrFromSlice(c.Context().RemoteIP())
+	var forwardedFor []string
+	for _, header := range c.Request().Header.PeekAll(fiber.HeaderXForwardedFor) {
+		forwardedFor = append(forwardedFor, string(header))
+	}
+	return Resolve(remote, forwardedFor)
+}
+
+// Allowed reports whether addr lies in one of cidrs. An empty list allows
+// every address.
+func Allowed(cidrs []string, addr netip.Addr) bool {
+	if len(cidrs) == 0 {
+		return true
+	}
+	prefixes, err := ParsePrefixes(cidrs)
+	if err != nil {
+		// Lists are validated when they are saved
+		log.Printf("Ignoring invalid allowlist %v: %v", cidrs, err)
+		return false
+	}
+	return contains(prefixes, addr.Unmap())
+}
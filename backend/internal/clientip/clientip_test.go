@@ -0,0 +1,177 @@
+package clientip
+
+import (
+	"net/netip"
+	"testing"
+)
+
+// setTrusted trusts the given proxies for the duration of a test
+func setTrusted(t *testing.T, values ...string) {
+	t.Helper()
+	prefixes, err := ParsePrefixes(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	previous := trusted
+	trusted = prefixes
+	mu.Unlock()
+	t.Cleanup(func() {
+		mu.Lock()
+		trusted = previous
+		mu.Unlock()
+	})
+}
+
+func TestResolve(t *testing.T) {
+	setTrusted(t, "127.0.0.0/8", "10.0.0.0/8", "fd00::/8")
+
+	tests := []struct {
+		name         string
+		remote       string
+		forwardedFor []string
+		want         string
+	}{
+		{
+			name:   "direct client",
+			remote: "203.0.113.7",
+			want:   "203.0.113.7",
+		},
+		{
+			name:         "spoofed header from an untrusted peer",
+			remote:       "203.0.113.7",
+			forwardedFor: []string{"198.51.100.1"},
+			want:         "203.0.113.7",
+		},
+		{
+			name:         "trusted proxy",
+			remote:       "127.0.0.1",
+			forwardedFor: []string{"198.51.100.1"},
+			want:         "198.51.100.1",
+		},
+		{
+			name:         "spoofed entry left of the real client",
+			remote:       "127.0.0.1",
+			forwardedFor: []string{"1.1.1.1, 198.51.100.1"},
+			want:         "198.51.100.1",
+		},
+		{
+			name:         "chain of trusted proxies read from the right",
+			remote:       "127.0.0.1",
+			forwardedFor: []string{"1.1.1.1, 198.51.100.1, 10.0.0.5, 10.0.0.6"},
+			want:         "198.51.100.1",
+		},
+		{
+			name:         "chain over several headers",
+			remote:       "127.0.0.1",
+			forwardedFor: []string{"1.1.1.1, 198.51.100.1", "10.0.0.5"},
+			want:         "198.51.100.1",
+		},
+		{
+			name:         "only trusted proxies",
+			remote:       "127.0.0.1",
+			forwardedFor: []string{"10.0.0.5, 10.0.0.6"},
+			want:         "10.0.0.5",
+		},
+		{
+			name:   "trusted proxy without header",
+			remote: "127.0.0.1",
+			want:   "127.0.0.1",
+		},
+		{
+			name:         "malformed entry stops the walk",
+			remote:       "127.0.0.1",
+			forwardedFor: []string{"198.51.100.1, not-an-ip"},
+			want:         "127.0.0.1",
+		},
+		{
+			name:         "malformed entry left of the client",
+			remote:       "127.0.0.1",
+			forwardedFor: []string{"garbage, 198.51.100.1"},
+			want:         "198.51.100.1",
+		},
+		{
+			name:         "entry with a port",
+			remote:       "127.0.0.1",
+			forwardedFor: []string{"198.51.100.1:4000"},
+			want:         "127.0.0.1",
+		},
+		{
+			name:         "IPv6 client behind an IPv6 proxy",
+			remote:       "fd00::1",
+			forwardedFor: []string{"2001:db8::1"},
+			want:         "2001:db8::1",
+		},
+		{
+			name:         "untrusted IPv6 peer",
+			remote:       "2001:db8::2",
+			forwardedFor: []string{"2001:db8::1"},
+			want:         "2001:db8::2",
+		},
+		{
+			name:         "IPv4-mapped addresses are unmapped",
+			remote:       "::ffff:127.0.0.1",
+			forwardedFor: []string{"::ffff:198.51.100.1"},
+			want:         "198.51.100.1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Resolve(netip.MustParseAddr(tt.remote), tt.forwardedFor)
+			if got != netip.MustParseAddr(tt.want) {
+				t.Errorf("Resolve(%s, %q) = %s, want %s", tt.remote, tt.forwardedFor, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveTrustsNoProxy(t *testing.T) {
+	setTrusted(t)
+	got := Resolve(netip.MustParseAddr("127.0.0.1"), []string{"198.51.100.1"})
+	if got != netip.MustParseAddr("127.0.0.1") {
+		t.Errorf("Resolve = %s with no trusted proxies, want the peer", got)
+	}
+}
+
+func TestAllowed(t *testing.T) {
+	tests := []struct {
+		name  string
+		cidrs []string
+		addr  string
+		want  bool
+	}{
+		{"empty list", nil, "203.0.113.7", true},
+		{"in range", []string{"203.0.113.0/24"}, "203.0.113.7", true},
+		{"out of range", []string{"203.0.113.0/24"}, "198.51.100.1", false},
+		{"bare address", []string{"203.0.113.7"}, "203.0.113.7", true},
+		{"bare address, other client", []string{"203.0.113.7"}, "203.0.113.8", false},
+		{"IPv6 range", []string{"2001:db8::/32"}, "2001:db8::1", true},
+		{"IPv4 client, IPv6 list", []string{"2001:db8::/32"}, "203.0.113.7", false},
+		{"mapped address", []string{"203.0.113.0/24"}, "::ffff:203.0.113.7", true},
+		{"invalid list", []string{"nope"}, "203.0.113.7", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Allowed(tt.cidrs, netip.MustParseAddr(tt.addr)); got != tt.want {
+				t.Errorf("Allowed(%v, %s) = %v, want %v", tt.cidrs, tt.addr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	got, err := Normalize([]string{"10.1.2.3/8", " 203.0.113.7 ", "2001:db8::1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"10.0.0.0/8", "203.0.113.7/32", "2001:db8::1/128"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Normalize = %v, want %v", got, want)
+			break
+		}
+	}
+	if _, err := Normalize([]string{"10.0.0.0/33"}); err == nil {
+		t.Error("Normalize accepted an invalid CIDR")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"time"
 
+	"github.com/ollama-web-api/internal/apikeys"
 	"github.com/ollama-web-api/internal/models"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -61,16 +62,119 @@ func RunMigrations() error {
 	err := DB.AutoMigrate(
 		&models.Project{},
 		&models.ProjectModel{},
+		&models.APIKey{},
+		&models.UsageRecord{},
+		&models.QuotaTopUp{},
+		&models.OllamaBackend{},
+		&models.GenerationJob{},
+		&models.Batch{},
+		&models.BatchItem{},
+		&models.RateLimitCounter{},
+		&models.RateLimitLease{},
+		&models.AuditEvent{},
+		&models.User{},
+		&models.OIDCLogin{},
+		&models.Session{},
+		&models.RevokedToken{},
+		&models.LoginThrottle{},
+		&models.RecoveryCode{},
+		&models.LoginChallenge{},
+		&models.SecurityPolicy{},
 	)
 
 	if err != nil {
 		return err
 	}
 
+	if err := migrateProjectAPIKeys(); err != nil {
+		return fmt.Errorf("failed to migrate API keys: %w", err)
+	}
+
 	log.Println("Migrations completed successfully")
 	return nil
 }
 
+// migrateProjectAPIKeys moves the single API key that earlier versions kept
+// on the project, in plaintext or as a prefix and salted hash, to the API key
+// table and drops the old columns. Existing keys keep working.
+func migrateProjectAPIKeys() error {
+	migrator := DB.Migrator()
+	plaintext := migrator.HasColumn(&models.Project{}, "api_key")
+	hashed := migrator.HasColumn(&models.Project{}, "api_key_hash")
+	if !plaintext && !hashed {
+		return nil
+	}
+
+	var rows []struct {
+		ID           uint
+		APIKey       string
+		APIKeyPrefix string
+		APIKeySalt   string
+		APIKeyHash   string
+		CreatedAt    time.Time
+	}
+	columns := []string{"id", "created_at"}
+	if plaintext {
+		columns = append(columns, "api_key")
+	}
+	if hashed {
+		columns = append(columns, "api_key_prefix", "api_key_salt", "api_key_hash")
+	}
+	if err := DB.Table("projects").Select(columns).Scan(&rows).Error; err != nil {
+		return err
+	}
+
+	migrated := 0
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		for _, row := range rows {
+			key := models.APIKey{
+				ProjectID: row.ID,
+				Name:      "default",
+				Prefix:    row.APIKeyPrefix,
+				Salt:      row.APIKeySalt,
+				Hash:      row.APIKeyHash,
+				CreatedAt: row.CreatedAt,
+			}
+			if key.Hash == "" && row.APIKey != "" {
+				salt, hash, err := apikeys.Hash(row.APIKey)
+				if err != nil {
+					return err
+				}
+				key.Prefix, key.Salt, key.Hash = apikeys.Prefix(row.APIKey), salt, hash
+			}
+			if key.Hash == "" {
+				continue
+			}
+			if err := tx.Create(&key).Error; err != nil {
+				return err
+			}
+			migrated++
+		}
+
+		for _, index := range []string{"idx_projects_api_key", "idx_projects_api_key_prefix"} {
+			if tx.Migrator().HasIndex(&models.Project{}, index) {
+				if err := tx.Migrator().DropIndex(&models.Project{}, index); err != nil {
+					return err
+				}
+			}
+		}
+		for _, column := range []string{"api_key", "api_key_prefix", "api_key_salt", "api_key_hash"} {
+			if tx.Migrator().HasColumn(&models.Project{}, column) {
+				if err := tx.Migrator().DropColumn(&models.Project{}, column); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Moved %d project API keys to the API key table", migrated)
+	return nil
+}
+
 // GetDB returns the database instance
 func GetDB() *gorm.DB {
 	return DB
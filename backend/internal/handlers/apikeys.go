@@ -0,0 +1,314 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ollama-web-api/internal/apikeys"
+	"github.com/ollama-web-api/internal/audit"
+	"github.com/ollama-web-api/internal/clientip"
+	"github.com/ollama-web-api/internal/database"
+	"github.com/ollama-web-api/internal/models"
+	"gorm.io/gorm"
+)
+
+// defaultGracePeriod is how long a rotated key keeps working by default
+const defaultGracePeriod = 24 * time.Hour
+
+// newAPIKey generates an API key created by the admin of the request. Only
+// its prefix and salted hash are stored; the key itself is left in Key to be
+// shown once.
+func newAPIKey(c *fiber.Ctx, name string, expiresAt *time.Time) (*models.APIKey, error) {
+	key, err := generateAPIKey()
+	if err != nil {
+		return nil, err
+	}
+	salt, hash, err := apikeys.Hash(key)
+	if err != nil {
+		return nil, err
+	}
+	createdBy, _ := c.Locals("username").(string)
+	return &models.APIKey{
+		Name:      name,
+		Key:       key,
+		Prefix:    apikeys.Prefix(key),
+		Salt:      salt,
+		Hash:      hash,
+		CreatedBy: createdBy,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// validateExpiry checks that an expiry, when given, lies in the future
+func validateExpiry(expiresAt *time.Time) *requestError {
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return &requestError{fiber.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: "expires_at must be in the future",
+		}}
+	}
+	return nil
+}
+
+// validateKeyLimits checks that scopes are known and that models are
+// assigned to the project
+func validateKeyLimits(project *models.Project, scopes, modelNames []string) *requestError {
+	for _, scope := range scopes {
+		if !apikeys.ValidScope(scope) {
+			return &requestError{fiber.StatusBadRequest, models.ErrorResponse{
+				Error:   "Invalid request",
+				Message: fmt.Sprintf("Unknown scope '%s'; valid scopes are %s", scope, strings.Join(apikeys.AllScopes, ", ")),
+			}}
+		}
+	}
+	for _, name := range modelNames {
+		if rerr := checkProjectModel(project, name); rerr != nil {
+			return &requestError{fiber.StatusBadRequest, models.ErrorResponse{
+				Error:   "Invalid request",
+				Message: rerr.Message,
+			}}
+		}
+	}
+	return nil
+}
+
+// projectAPIKey loads a key of the project in the route that is not revoked
+func projectAPIKey(c *fiber.Ctx) (*models.APIKey, *requestError) {
+	var key models.APIKey
+	err := database.DB.Where("id = ? AND project_id = ? AND revoked_at IS NULL", c.Params("keyId"), c.Params("id")).First(&key).Error
+	if err != nil {
+		return nil, &requestError{fiber.StatusNotFound, models.ErrorResponse{
+			Error:   "API key not found",
+			Message: err.Error(),
+		}}
+	}
+	return &key, nil
+}
+
+// ListAPIKeys godoc
+// @Summary List the API keys of a project
+// @Description Get the keys of a project, including revoked and expired ones. Only their prefixes are shown.
+// @Tags api-keys
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Project ID"
+// @Success 200 {array} models.APIKey
+// @Failure 404 {object} models.ErrorResponse
+// @Router /api/projects/{id}/keys [get]
+func ListAPIKeys(c *fiber.Ctx) error {
+	var project models.Project
+	if err := database.DB.First(&project, c.Params("id")).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+			Error:   "Project not found",
+			Message: err.Error(),
+		})
+	}
+
+	var keys []models.APIKey
+	if err := database.DB.Where("project_id = ?", project.ID).Order("created_at").Find(&keys).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to fetch API keys",
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(keys)
+}
+
+// CreateAPIKey godoc
+// @Summary Create an API key
+// @Description Create a named API key for a project, optionally expiring and limited to scopes, a subset of the project's models and the networks it may be used from. The key is only returned in this response.
+// @Tags api-keys
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Project ID"
+// @Param request body models.CreateAPIKeyRequest true "Key details"
+// @Success 201 {object} models.APIKey
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /api/projects/{id}/keys [post]
+func CreateAPIKey(c *fiber.Ctx) error {
+	var project models.Project
+	if err := database.DB.Preload("Models").First(&project, c.Params("id")).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+			Error:   "Project not found",
+			Message: err.Error(),
+		})
+	}
+
+	var req models.CreateAPIKeyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: err.Error(),
+		})
+	}
+	if req.Name == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: "name is required",
+		})
+	}
+	if rerr := validateExpiry(req.ExpiresAt); rerr != nil {
+		return rerr.send(c)
+	}
+	if rerr := validateKeyLimits(&project, req.Scopes, req.Models); rerr != nil {
+		return rerr.send(c)
+	}
+	allowedCIDRs, err := clientip.Normalize(req.AllowedCIDRs)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "Invalid allowed CIDRs",
+			Message: err.Error(),
+		})
+	}
+
+	key, err := newAPIKey(c, req.Name, req.ExpiresAt)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to generate API key",
+			Message: err.Error(),
+		})
+	}
+	key.ProjectID = project.ID
+	key.Scopes = req.Scopes
+	key.Models = req.Models
+	if len(allowedCIDRs) > 0 {
+		key.AllowedCIDRs = allowedCIDRs
+	}
+	if err := database.DB.Create(key).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to create API key",
+			Message: err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(key)
+}
+
+// RevokeAPIKey godoc
+// @Summary Revoke an API key
+// @Description Revoke an API key right away
+// @Tags api-keys
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Project ID"
+// @Param keyId path int true "API key ID"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /api/projects/{id}/keys/{keyId} [delete]
+func RevokeAPIKey(c *fiber.Ctx) error {
+	key, rerr := projectAPIKey(c)
+	if rerr != nil {
+		return rerr.send(c)
+	}
+
+	if err := database.DB.Model(key).Update("revoked_at", time.Now()).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to revoke API key",
+			Message: err.Error(),
+		})
+	}
+	recordKeyAction(c, audit.ActionAPIKeyRevoked, key, fmt.Sprintf("key %s (%d) of project %d", key.Prefix, key.ID, key.ProjectID))
+
+	return c.JSON(models.SuccessResponse{
+		Message: "API key revoked successfully",
+	})
+}
+
+// RotateAPIKey godoc
+// @Summary Rotate an API key
+// @Description Replace an API key with a new one of the same name, scopes, models and allowed CIDRs. The old key keeps working for the grace period so consumers can switch over. The new key is only returned in this response.
+// @Tags api-keys
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Project ID"
+// @Param keyId path int true "API key ID"
+// @Param request body models.RotateAPIKeyRequest false "Rotation details"
+// @Success 201 {object} models.APIKey
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /api/projects/{id}/keys/{keyId}/rotate [post]
+func RotateAPIKey(c *fiber.Ctx) error {
+	old, rerr := projectAPIKey(c)
+	if rerr != nil {
+		return rerr.send(c)
+	}
+	// An expired key is not in use anymore, so there is nothing to hand over
+	if old.ExpiresAt != nil && !old.ExpiresAt.After(time.Now()) {
+		return c.Status(fiber.StatusConflict).JSON(models.ErrorResponse{
+			Error:   "API key expired",
+			Message: "Expired keys cannot be rotated; create a new key instead",
+		})
+	}
+
+	var req models.RotateAPIKeyRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+				Error:   "Invalid request",
+				Message: err.Error(),
+			})
+		}
+	}
+	grace := defaultGracePeriod
+	if req.GracePeriod != "" {
+		d, err := time.ParseDuration(req.GracePeriod)
+		if err != nil || d < 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+				Error:   "Invalid request",
+				Message: "grace_period must be a duration such as 24h",
+			})
+		}
+		grace = d
+	}
+	if rerr := validateExpiry(req.ExpiresAt); rerr != nil {
+		return rerr.send(c)
+	}
+
+	key, err := newAPIKey(c, old.Name, req.ExpiresAt)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to generate API key",
+			Message: err.Error(),
+		})
+	}
+	key.ProjectID = old.ProjectID
+	key.Scopes = old.Scopes
+	key.Models = old.Models
+	key.AllowedCIDRs = old.AllowedCIDRs
+
+	// The old key ends with the grace period, or earlier if it already expires
+	now := time.Now()
+	oldUpdates := map[string]interface{}{}
+	if grace == 0 {
+		oldUpdates["revoked_at"] = now
+	} else if end := now.Add(grace); old.ExpiresAt == nil || end.Before(*old.ExpiresAt) {
+		oldUpdates["expires_at"] = end
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(key).Error; err != nil {
+			return err
+		}
+		if len(oldUpdates) == 0 {
+			return nil
+		}
+		return tx.Model(old).Updates(oldUpdates).Error
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to rotate API key",
+			Message: err.Error(),
+		})
+	}
+	recordKeyAction(c, audit.ActionAPIKeyRotated, old, fmt.Sprintf("key %s (%d) of project %d replaced by %s (%d) with a grace period of %s",
+		old.Prefix, old.ID, old.ProjectID, key.Prefix, key.ID, grace))
+
+	return c.Status(fiber.StatusCreated).JSON(key)
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ollama-web-api/internal/audit"
+	"github.com/ollama-web-api/internal/clientip"
+	"github.com/ollama-web-api/internal/database"
+	"github.com/ollama-web-api/internal/models"
+)
+
+// Page sizes of the audit log
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+// recordAdminAction writes an action of the logged-in user to the audit log
+func recordAdminAction(c *fiber.Ctx, action, detail string) {
+	actor, _ := c.Locals("username").(string)
+	audit.Record(&models.AuditEvent{
+		Action: action,
+		Actor:  actor,
+		IP:     clientip.Get(c).String(),
+		Detail: detail,
+	})
+}
+
+// recordKeyAction writes an action of the logged-in user on an API key to the
+// audit log, under the project and key
+func recordKeyAction(c *fiber.Ctx, action string, key *models.APIKey, detail string) {
+	actor, _ := c.Locals("username").(string)
+	audit.Record(&models.AuditEvent{
+		Action:    action,
+		Actor:     actor,
+		ProjectID: &key.ProjectID,
+		APIKeyID:  &key.ID,
+		IP:        clientip.Get(c).String(),
+		Detail:    detail,
+	})
+}
+
+// ListAuditEvents godoc
+// @Summary List audit events
+// @Description List security-relevant events, newest first, such as API key calls refused by an IP allowlist and changes to users
+// @Tags audit
+// @Security BearerAuth
+// @Produce json
+// @Param action query string false "Filter by action, e.g. ip_denied"
+// @Param project_id query int false "Filter by project ID"
+// @Param from query string false "Start (RFC3339 or YYYY-MM-DD)"
+// @Param to query string false "End (RFC3339 or YYYY-MM-DD)"
+// @Param limit query int false "Maximum number of events" default(100)
+// @Success 200 {array} models.AuditEvent
+// @Failure 400 {object} models.ErrorResponse
+// @Router /api/audit [get]
+func ListAuditEvents(c *fiber.Ctx) error {
+	query := database.DB.Model(&models.AuditEvent{})
+
+	if v := c.Query("action"); v != "" {
+		query = query.Where("action = ?", v)
+	}
+	if v := c.Query("project_id"); v != "" {
+		projectID, err := strconv.Atoi(v)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+				Error:   "Invalid request",
+				Message: "project_id must be a number",
+			})
+		}
+		query = query.Where("project_id = ?", projectID)
+	}
+	if v := c.Query("from"); v != "" {
+		t, err := parseUsageTime(v)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+				Error:   "Invalid request",
+				Message: "from must be an RFC3339 timestamp or a YYYY-MM-DD date",
+			})
+		}
+		query = query.Where("created_at >= ?", t)
+	}
+	if v := c.Query("to"); v != "" {
+		t, err := parseUsageTime(v)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+				Error:   "Invalid request",
+				Message: "to must be an RFC3339 timestamp or a YYYY-MM-DD date",
+			})
+		}
+		query = query.Where("created_at < ?", t)
+	}
+
+	limit := c.QueryInt("limit", defaultAuditLimit)
+	if limit < 1 || limit > maxAuditLimit {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: "limit must be between 1 and " + strconv.Itoa(maxAuditLimit),
+		})
+	}
+
+	events := []models.AuditEvent{}
+	if err := query.Order("created_at DESC, id DESC").Limit(limit).Find(&events).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to fetch audit events",
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(events)
+}
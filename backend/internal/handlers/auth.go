@@ -1,23 +1,36 @@
 package handlers
 
 import (
-	"os"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/ollama-web-api/internal/database"
-	"github.com/ollama-web-api/internal/middleware"
+	"github.com/ollama-web-api/internal/audit"
+	"github.com/ollama-web-api/internal/clientip"
+	"github.com/ollama-web-api/internal/loginguard"
 	"github.com/ollama-web-api/internal/models"
+	"github.com/ollama-web-api/internal/sessions"
+	"github.com/ollama-web-api/internal/users"
 )
 
+// maxUserAgentLength bounds the user agent stored with a session
+const maxUserAgentLength = 256
+
 // Login godoc
 // @Summary Admin login
-// @Description Login with admin credentials to get JWT token
+// @Description Login with the credentials of an admin user to get a JWT token. Users with two-factor authentication get a challenge instead, to be completed with a code at /api/auth/2fa/verify. After a failed login the client IP and the username have to wait before trying again, twice as long after every further failure, and are locked out for a while after too many failures.
 // @Tags auth
 // @Accept json
 // @Produce json
 // @Param credentials body models.LoginRequest true "Login credentials"
 // @Success 200 {object} models.LoginResponse
 // @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
 // @Router /api/auth/login [post]
 func Login(c *fiber.Ctx) error {
 	var req models.LoginRequest
@@ -28,17 +41,65 @@ func Login(c *fiber.Ctx) error {
 		})
 	}
 
-	adminUser := os.Getenv("ADMIN_USER")
-	adminPassword := os.Getenv("ADMIN_PASSWORD")
+	ip := clientip.Get(c).String()
+	if rerr := reserveLoginAttempt(c, ip, req.Username); rerr != nil {
+		return rerr.send(c)
+	}
+	// The attempt counts as failed until it is known how it went
+	settled := false
+	defer func() {
+		if !settled {
+			if err := loginguard.Release(ip, req.Username); err != nil {
+				log.Printf("Failed to release login attempt of %s: %v", ip, err)
+			}
+		}
+	}()
 
-	if req.Username != adminUser || req.Password != adminPassword {
+	user, err := users.Authenticate(req.Username, req.Password)
+	if errors.Is(err, users.ErrInvalidCredentials) {
+		settled = true
+		locked, err := loginguard.Confirm(ip, req.Username)
+		auditLoginFailure(ip, req.Username, "invalid credentials", locked, err)
 		return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
 			Error:   "Invalid credentials",
 			Message: "Username or password is incorrect",
 		})
 	}
+	if errors.Is(err, users.ErrDisabled) {
+		return c.Status(fiber.StatusForbidden).JSON(models.ErrorResponse{
+			Error:   "User disabled",
+			Message: "This user has been disabled",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to log in",
+			Message: err.Error(),
+		})
+	}
+
+	// Failures are only forgotten once the second factor is right too
+	if user.TOTPEnabled {
+		challenge, err := users.NewChallenge(user)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+				Error:   "Failed to log in",
+				Message: err.Error(),
+			})
+		}
+		return c.JSON(models.LoginResponse{
+			TwoFactorRequired: true,
+			Challenge:         challenge,
+		})
+	}
+
+	// The reserved attempt is released, so the IP's failures stay as they
+	// were
+	if err := loginguard.Succeeded(req.Username); err != nil {
+		log.Printf("Failed to reset failed logins of %s: %v", req.Username, err)
+	}
 
-	token, err := middleware.GenerateToken(req.Username)
+	tokens, err := startSession(c, user)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
 			Error:   "Failed to generate token",
@@ -46,11 +107,248 @@ func Login(c *fiber.Ctx) error {
 		})
 	}
 
-	return c.JSON(models.LoginResponse{
-		Token: token,
+	return c.JSON(loginResponse(tokens, user))
+}
+
+// checkLoginThrottle refuses a login attempt while the client IP or the
+// username have to wait after failed logins
+func checkLoginThrottle(c *fiber.Ctx, ip, username string) *requestError {
+	wait, err := loginguard.Check(ip, username)
+	return throttleError(c, wait, err)
+}
+
+// reserveLoginAttempt is checkLoginThrottle for password logins, which may
+// be tried concurrently: the attempt is counted before the password is
+// checked, so no more attempts get through than the limit allows
+func reserveLoginAttempt(c *fiber.Ctx, ip, username string) *requestError {
+	wait, err := loginguard.Reserve(ip, username)
+	return throttleError(c, wait, err)
+}
+
+// throttleError returns the error of a login attempt that has to wait, or
+// could not be checked
+func throttleError(c *fiber.Ctx, wait time.Duration, err error) *requestError {
+	if err != nil {
+		return &requestError{fiber.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to log in",
+			Message: err.Error(),
+		}}
+	}
+	if wait > 0 {
+		seconds := int(math.Ceil(wait.Seconds()))
+		c.Set("Retry-After", strconv.Itoa(seconds))
+		return &requestError{fiber.StatusTooManyRequests, models.ErrorResponse{
+			Error:   "Too many failed logins",
+			Message: fmt.Sprintf("Try again in %d seconds", seconds),
+		}}
+	}
+	return nil
+}
+
+// recordLoginFailure counts a failed login and writes it, and any lockout it
+// starts, to the audit log
+func recordLoginFailure(ip, username, reason string) {
+	locked, err := loginguard.Failed(ip, username)
+	auditLoginFailure(ip, username, reason, locked, err)
+}
+
+// auditLoginFailure writes a failed login, and the lockouts it started, to
+// the audit log
+func auditLoginFailure(ip, username, reason string, locked []models.LoginThrottle, err error) {
+	audit.Record(&models.AuditEvent{
+		Action: audit.ActionLoginFailed,
+		Actor:  username,
+		IP:     ip,
+		Detail: reason,
+	})
+	if err != nil {
+		log.Printf("Failed to count failed login of %s: %v", ip, err)
+	}
+	for _, throttle := range locked {
+		audit.Record(&models.AuditEvent{
+			Action: audit.ActionLoginLocked,
+			Actor:  username,
+			IP:     ip,
+			Detail: fmt.Sprintf("%s %s locked out after %d failures until %s", throttle.Kind, throttle.Key, throttle.Failures, throttle.BlockedUntil.Format(time.RFC3339)),
+		})
+	}
+}
+
+// startSession starts a session for a user who logged in from the client of
+// the request
+func startSession(c *fiber.Ctx, user *models.User) (*sessions.Tokens, error) {
+	userAgent := c.Get(fiber.HeaderUserAgent)
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+	return sessions.Start(user, clientip.Get(c).String(), userAgent)
+}
+
+func loginResponse(tokens *sessions.Tokens, user *models.User) models.LoginResponse {
+	return models.LoginResponse{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+		Role:         user.Role,
+	}
+}
+
+// RefreshToken godoc
+// @Summary Refresh tokens
+// @Description Exchange a refresh token for a new access token and a new refresh token. Each refresh token works once; using a replaced one again ends the session.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body models.RefreshRequest true "Refresh token"
+// @Success 200 {object} models.LoginResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Router /api/auth/refresh [post]
+func RefreshToken(c *fiber.Ctx) error {
+	var req models.RefreshRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: err.Error(),
+		})
+	}
+
+	tokens, user, err := sessions.Refresh(req.RefreshToken, clientip.Get(c).String())
+	if errors.Is(err, sessions.ErrInvalidToken) || errors.Is(err, sessions.ErrTokenReused) {
+		return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
+			Error:   "Invalid refresh token",
+			Message: "The refresh token is unknown, expired or revoked; log in again",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to refresh token",
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(loginResponse(tokens, user))
+}
+
+// Logout godoc
+// @Summary Log out
+// @Description End the session of the token. Its refresh token stops working and the token itself is revoked.
+// @Tags auth
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} models.SuccessResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Router /api/auth/logout [post]
+func Logout(c *fiber.Ctx) error {
+	sessionID, _ := c.Locals("session_id").(uint)
+	session, err := sessions.Find(sessionID)
+	if err == nil && session != nil {
+		err = sessions.Revoke(session)
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to log out",
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(models.SuccessResponse{
+		Message: "Logged out successfully",
 	})
 }
 
+// invalidChallenge is the response to an unknown, expired or used up login
+// challenge
+var invalidChallenge = &requestError{fiber.StatusUnauthorized, models.ErrorResponse{
+	Error:   "Invalid challenge",
+	Message: "The login expired or had too many wrong codes; log in again",
+}}
+
+// VerifyTwoFactor godoc
+// @Summary Complete a login with a two-factor code
+// @Description Complete the challenge of a login with a code from the authenticator app or a recovery code. Wrong codes count as failed logins; after five the challenge ends and the login has to start over.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body models.TwoFactorVerifyRequest true "Challenge and code"
+// @Success 200 {object} models.LoginResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
+// @Router /api/auth/2fa/verify [post]
+func VerifyTwoFactor(c *fiber.Ctx) error {
+	var req models.TwoFactorVerifyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: err.Error(),
+		})
+	}
+
+	challenge, user, err := users.Challenge(req.Challenge)
+	if errors.Is(err, users.ErrInvalidChallenge) {
+		return invalidChallenge.send(c)
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to log in",
+			Message: err.Error(),
+		})
+	}
+
+	ip := clientip.Get(c).String()
+	if rerr := checkLoginThrottle(c, ip, user.Username); rerr != nil {
+		return rerr.send(c)
+	}
+
+	if err := users.AttemptChallenge(challenge); err != nil {
+		if errors.Is(err, users.ErrInvalidChallenge) {
+			return invalidChallenge.send(c)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to log in",
+			Message: err.Error(),
+		})
+	}
+
+	usedRecovery, err := users.CheckSecondFactor(user, req.Code)
+	if errors.Is(err, users.ErrInvalidCode) {
+		recordLoginFailure(ip, user.Username, "invalid two-factor code")
+		return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
+			Error:   "Invalid code",
+			Message: "The two-factor code is wrong, expired or already used",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to log in",
+			Message: err.Error(),
+		})
+	}
+
+	if err := users.CompleteChallenge(challenge); err != nil {
+		log.Printf("Failed to delete login challenge of user %d: %v", user.ID, err)
+	}
+	if err := loginguard.Succeeded(user.Username); err != nil {
+		log.Printf("Failed to reset failed logins of %s: %v", user.Username, err)
+	}
+	if usedRecovery {
+		audit.Record(&models.AuditEvent{
+			Action: audit.ActionRecoveryCodeUsed,
+			Actor:  user.Username,
+			IP:     ip,
+		})
+	}
+
+	tokens, err := startSession(c, user)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to generate token",
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(loginResponse(tokens, user))
+}
+
 // ValidateProjectKey godoc
 // @Summary Validate project API key
 // @Description Check whether the provided X-API-Key belongs to an active project
@@ -63,23 +361,14 @@ func Login(c *fiber.Ctx) error {
 // @Failure 403 {object} models.ErrorResponse
 // @Router /api/validate_key [get]
 func ValidateProjectKey(c *fiber.Ctx) error {
-	apiKey, ok := c.Locals("api_key").(string)
-	if !ok || apiKey == "" {
+	project, ok := c.Locals("project").(*models.Project)
+	if !ok {
 		return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
 			Error:   "Invalid API key",
 			Message: "API key not provided",
 		})
 	}
 
-	var project models.Project
-	result := database.DB.Where("api_key = ?", apiKey).First(&project)
-	if result.Error != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
-			Error:   "Invalid API key",
-			Message: "Project not found with the provided API key",
-		})
-	}
-
 	if !project.IsActive {
 		return c.Status(fiber.StatusForbidden).JSON(models.ErrorResponse{
 			Error:   "Project inactive",
@@ -87,8 +376,12 @@ func ValidateProjectKey(c *fiber.Ctx) error {
 		})
 	}
 
-	return c.JSON(fiber.Map{
+	response := fiber.Map{
 		"valid":   true,
 		"project": fiber.Map{"id": project.ID, "name": project.Name},
-	})
+	}
+	if key, ok := c.Locals("api_key").(*models.APIKey); ok {
+		response["key"] = fiber.Map{"name": key.Name, "scopes": key.Scopes, "models": key.Models}
+	}
+	return c.JSON(response)
 }
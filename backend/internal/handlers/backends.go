@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ollama-web-api/internal/backends"
+	"github.com/ollama-web-api/internal/database"
+	"github.com/ollama-web-api/internal/models"
+)
+
+// backendStatuses returns the status of every backend in the pool
+func backendStatuses() []models.BackendStatus {
+	pool := backends.All()
+	statuses := make([]models.BackendStatus, 0, len(pool))
+	for _, b := range pool {
+		statuses = append(statuses, b.Status())
+	}
+	return statuses
+}
+
+// ListBackends godoc
+// @Summary List Ollama backends
+// @Description Get the health, installed models and loaded models of every Ollama backend in the pool
+// @Tags backends
+// @Security BearerAuth
+// @Produce json
+// @Param refresh query bool false "Run a health check before responding"
+// @Success 200 {array} models.BackendStatus
+// @Router /api/backends [get]
+func ListBackends(c *fiber.Ctx) error {
+	if c.QueryBool("refresh") {
+		backends.CheckAll()
+	}
+	return c.JSON(backendStatuses())
+}
+
+// CreateBackend godoc
+// @Summary Register an Ollama backend
+// @Description Add an Ollama server to the backend pool
+// @Tags backends
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param backend body models.CreateBackendRequest true "Backend details"
+// @Success 201 {object} models.OllamaBackend
+// @Failure 400 {object} models.ErrorResponse
+// @Router /api/backends [post]
+func CreateBackend(c *fiber.Ctx) error {
+	var req models.CreateBackendRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: err.Error(),
+		})
+	}
+
+	if req.Name == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: "Backend name is required",
+		})
+	}
+	if u, err := url.Parse(req.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: "Backend URL must be an absolute http or https URL",
+		})
+	}
+
+	backend := models.OllamaBackend{
+		Name:    req.Name,
+		URL:     strings.TrimRight(req.URL, "/"),
+		Enabled: true,
+	}
+	if err := database.DB.Create(&backend).Error; err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "Failed to create backend",
+			Message: err.Error(),
+		})
+	}
+
+	if err := backends.Reload(); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to reload backends",
+			Message: err.Error(),
+		})
+	}
+	for _, b := range backends.All() {
+		if b.ID == backend.ID {
+			b.Check()
+		}
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(backend)
+}
+
+// DeleteBackend godoc
+// @Summary Remove an Ollama backend
+// @Description Remove a registered Ollama server from the backend pool. Backends from OLLAMA_BACKENDS cannot be removed here.
+// @Tags backends
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Backend ID"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /api/backends/{id} [delete]
+func DeleteBackend(c *fiber.Ctx) error {
+	id := c.Params("id")
+	var backend models.OllamaBackend
+
+	if err := database.DB.First(&backend, id).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+			Error:   "Backend not found",
+			Message: err.Error(),
+		})
+	}
+
+	if err := database.DB.Delete(&backend).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to delete backend",
+			Message: err.Error(),
+		})
+	}
+
+	if err := backends.Reload(); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to reload backends",
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(models.SuccessResponse{
+		Message: "Backend deleted successfully",
+	})
+}
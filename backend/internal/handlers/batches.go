@@ -0,0 +1,329 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ollama-web-api/internal/apikeys"
+	"github.com/ollama-web-api/internal/batches"
+	"github.com/ollama-web-api/internal/models"
+	"gorm.io/gorm"
+)
+
+// RunBatchItem runs a single request of a batch under the model assignments,
+// settings and limits of its project and the models of the API key that
+// uploaded it
+func RunBatchItem(ctx context.Context, project *models.Project, key *models.APIKey, item *models.BatchItem) batches.Result {
+	var resp interface{}
+	var rerr *requestError
+	switch item.URL {
+	case batches.URLChat:
+		resp, rerr = runBatchChat(ctx, project, key, item.Request)
+	default:
+		resp, rerr = runBatchGenerate(ctx, project, key, item.Request)
+	}
+	if rerr != nil {
+		return batches.Result{
+			StatusCode: rerr.Status,
+			Body:       rerr.ErrorResponse,
+			Error:      fmt.Sprintf("%s: %s", rerr.Error, rerr.Message),
+		}
+	}
+	return batches.Result{StatusCode: fiber.StatusOK, Body: resp}
+}
+
+// invalidBatchBody reports a request body that does not decode
+func invalidBatchBody(err error) *requestError {
+	return &requestError{fiber.StatusBadRequest, models.ErrorResponse{
+		Error:   "Invalid request",
+		Message: err.Error(),
+	}}
+}
+
+func runBatchGenerate(ctx context.Context, project *models.Project, key *models.APIKey, body string) (interface{}, *requestError) {
+	var req models.OllamaRequest
+	if err := json.Unmarshal([]byte(body), &req); err != nil {
+		return nil, invalidBatchBody(err)
+	}
+	req.KeepAlive = normalizeKeepAlive(req.KeepAlive)
+	req.Stream = false
+
+	if rerr := checkProjectModel(project, req.Model); rerr != nil {
+		return nil, rerr
+	}
+	if rerr := keyModelError(key, req.Model); rerr != nil {
+		return nil, rerr
+	}
+	if rerr := applyGenerateSettings(&req, project); rerr != nil {
+		return nil, rerr
+	}
+
+	inf, rerr := admitBackground(ctx, project, req.Model)
+	if rerr != nil {
+		return nil, rerr
+	}
+	resp, rerr := inf.generate(&req)
+	if rerr != nil {
+		return nil, rerr
+	}
+	return resp, nil
+}
+
+func runBatchChat(ctx context.Context, project *models.Project, key *models.APIKey, body string) (interface{}, *requestError) {
+	var req models.OllamaChatRequest
+	if err := json.Unmarshal([]byte(body), &req); err != nil {
+		return nil, invalidBatchBody(err)
+	}
+	if rerr := validateChatMessages(req.Messages); rerr != nil {
+		return nil, rerr
+	}
+	req.KeepAlive = normalizeKeepAlive(req.KeepAlive)
+	req.Stream = false
+
+	if rerr := checkProjectModel(project, req.Model); rerr != nil {
+		return nil, rerr
+	}
+	if rerr := keyModelError(key, req.Model); rerr != nil {
+		return nil, rerr
+	}
+	if rerr := applyChatSettings(&req, project); rerr != nil {
+		return nil, rerr
+	}
+
+	inf, rerr := admitBackground(ctx, project, req.Model)
+	if rerr != nil {
+		return nil, rerr
+	}
+	resp, rerr := inf.chat(&req)
+	if rerr != nil {
+		return nil, rerr
+	}
+	return resp, nil
+}
+
+// projectBatch loads a batch of the project authenticated by the API key
+func projectBatch(c *fiber.Ctx) (*models.Batch, *requestError) {
+	project, rerr := authorizeProject(c, apikeys.ScopeBatches)
+	if rerr != nil {
+		return nil, rerr
+	}
+
+	batch, err := batches.Load(c.Params("id"), project.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, &requestError{fiber.StatusNotFound, models.ErrorResponse{
+			Error:   "Batch not found",
+			Message: "No batch with this ID exists for the project",
+		}}
+	}
+	if err != nil {
+		return nil, &requestError{fiber.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to fetch batch",
+			Message: err.Error(),
+		}}
+	}
+	return batch, nil
+}
+
+// authorizeBatchItems checks that the API key may make every request of a
+// batch, so a restricted key cannot reach more through a batch than directly
+func authorizeBatchItems(c *fiber.Ctx, items []models.BatchItem) *requestError {
+	for _, item := range items {
+		scope := apikeys.ScopeGenerate
+		if item.URL == batches.URLChat {
+			scope = apikeys.ScopeChat
+		}
+		var body struct {
+			Model string `json:"model"`
+		}
+		if err := json.Unmarshal([]byte(item.Request), &body); err != nil {
+			return &requestError{fiber.StatusBadRequest, models.ErrorResponse{
+				Error:   "Invalid batch file",
+				Message: fmt.Sprintf("line %d: invalid body: %v", item.Line, err),
+			}}
+		}
+
+		rerr := checkScope(c, scope)
+		if rerr == nil {
+			rerr = checkKeyModel(c, body.Model)
+		}
+		if rerr != nil {
+			rerr.Message = fmt.Sprintf("line %d: %s", item.Line, rerr.Message)
+			return rerr
+		}
+	}
+	return nil
+}
+
+// CreateBatch godoc
+// @Summary Upload a batch
+// @Description Upload a JSONL file of generate and chat requests to run in the background. Every line is a models.BatchRequestLine with a unique custom_id and a url of /api/ollama/generate or /api/ollama/chat. Requests run under the model assignments, settings, quotas and rate limits of the project; download the results once the batch finished.
+// @Tags batches
+// @Accept mpfd
+// @Produce json
+// @Param X-API-Key header string true "Project API Key"
+// @Param file formData file true "JSONL file of requests"
+// @Success 202 {object} models.Batch
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
+// @Router /api/ollama/batches [post]
+func CreateBatch(c *fiber.Ctx) error {
+	project, rerr := authorizeProject(c, apikeys.ScopeBatches)
+	if rerr != nil {
+		return rerr.send(c)
+	}
+
+	fh, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: "A JSONL file is required in the 'file' field",
+		})
+	}
+
+	// Refuse right away when the quota is used up; every request would fail
+	if rerr := checkQuotas(c, project); rerr != nil {
+		return rerr.send(c)
+	}
+
+	f, err := fh.Open()
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: err.Error(),
+		})
+	}
+	defer f.Close()
+
+	items, err := batches.Parse(f)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "Invalid batch file",
+			Message: err.Error(),
+		})
+	}
+	if rerr := authorizeBatchItems(c, items); rerr != nil {
+		return rerr.send(c)
+	}
+
+	id, err := batches.NewID()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to create batch",
+			Message: err.Error(),
+		})
+	}
+	batch := models.Batch{
+		ID:        id,
+		ProjectID: project.ID,
+		Filename:  fh.Filename,
+	}
+	if key, ok := c.Locals("api_key").(*models.APIKey); ok {
+		batch.APIKeyID = &key.ID
+	}
+	if err := batches.Create(&batch, items); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to create batch",
+			Message: err.Error(),
+		})
+	}
+
+	log.Printf("Queued batch %s with %d requests for project %d", batch.ID, batch.Total, project.ID)
+	c.Location("/api/ollama/batches/" + batch.ID)
+	return c.Status(fiber.StatusAccepted).JSON(batch)
+}
+
+// GetBatch godoc
+// @Summary Get a batch
+// @Description Get the status and progress of a batch
+// @Tags batches
+// @Produce json
+// @Param X-API-Key header string true "Project API Key"
+// @Param id path string true "Batch ID"
+// @Success 200 {object} models.Batch
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /api/ollama/batches/{id} [get]
+func GetBatch(c *fiber.Ctx) error {
+	batch, rerr := projectBatch(c)
+	if rerr != nil {
+		return rerr.send(c)
+	}
+	return c.JSON(batch)
+}
+
+// CancelBatch godoc
+// @Summary Cancel a batch
+// @Description Cancel a queued or running batch. Requests in flight are stopped and the remaining ones never run; results of finished requests stay available.
+// @Tags batches
+// @Produce json
+// @Param X-API-Key header string true "Project API Key"
+// @Param id path string true "Batch ID"
+// @Success 200 {object} models.Batch
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /api/ollama/batches/{id}/cancel [post]
+func CancelBatch(c *fiber.Ctx) error {
+	batch, rerr := projectBatch(c)
+	if rerr != nil {
+		return rerr.send(c)
+	}
+
+	cancelled, err := batches.Cancel(batch)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to cancel batch",
+			Message: err.Error(),
+		})
+	}
+	if !cancelled {
+		return c.Status(fiber.StatusConflict).JSON(models.ErrorResponse{
+			Error:   "Batch already finished",
+			Message: fmt.Sprintf("The batch has already %s", batch.Status),
+		})
+	}
+
+	batch, rerr = projectBatch(c)
+	if rerr != nil {
+		return rerr.send(c)
+	}
+	return c.JSON(batch)
+}
+
+// DownloadBatchResults godoc
+// @Summary Download batch results
+// @Description Download a JSONL file with a models.BatchResultLine for every finished request of the batch, in the order of the uploaded file. Failed and cancelled requests carry an error. The file is partial while the batch runs.
+// @Tags batches
+// @Produce application/x-ndjson
+// @Param X-API-Key header string true "Project API Key"
+// @Param id path string true "Batch ID"
+// @Success 200 {string} string "JSONL results"
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /api/ollama/batches/{id}/results [get]
+func DownloadBatchResults(c *fiber.Ctx) error {
+	batch, rerr := projectBatch(c)
+	if rerr != nil {
+		return rerr.send(c)
+	}
+
+	c.Attachment(batch.ID + "-results.jsonl")
+	c.Set("Content-Type", "application/x-ndjson")
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		if err := batches.WriteResults(w, batch); err != nil {
+			log.Printf("Failed to write results of batch %s: %v", batch.ID, err)
+		}
+		w.Flush()
+	})
+	return nil
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ollama-web-api/internal/backends"
+	"github.com/ollama-web-api/internal/models"
+	"github.com/ollama-web-api/internal/ollama"
+)
+
+// requestTimeout bounds a proxied inference request or model pull
+const requestTimeout = 300 * time.Second
+
+// adminTimeout bounds the other administrative calls to Ollama
+const adminTimeout = 30 * time.Second
+
+// OllamaClient is the Ollama API the handlers depend on. *ollama.Client
+// implements it; tests can serve requests from a fake by replacing
+// ollamaClientFor.
+type OllamaClient interface {
+	Generate(ctx context.Context, req *models.OllamaRequest) (*models.OllamaResponse, error)
+	GenerateStream(ctx context.Context, req *models.OllamaRequest) (*ollama.Stream[models.OllamaResponse], error)
+	Chat(ctx context.Context, req *models.OllamaChatRequest) (*models.OllamaChatResponse, error)
+	ChatStream(ctx context.Context, req *models.OllamaChatRequest) (*ollama.Stream[models.OllamaChatResponse], error)
+	Embed(ctx context.Context, req *models.OllamaEmbedRequest) (*models.OllamaEmbedResponse, error)
+	Tags(ctx context.Context) (*ollama.ListResponse, error)
+	Ps(ctx context.Context) (*ollama.ProcessResponse, error)
+	Pull(ctx context.Context, req *ollama.PullRequest) (*ollama.Stream[ollama.ProgressResponse], error)
+	Delete(ctx context.Context, req *ollama.DeleteRequest) error
+	Show(ctx context.Context, req *ollama.ShowRequest) (*ollama.ShowResponse, error)
+	Copy(ctx context.Context, req *ollama.CopyRequest) error
+	Create(ctx context.Context, req *ollama.CreateRequest) (*ollama.Stream[ollama.ProgressResponse], error)
+}
+
+var _ OllamaClient = (*ollama.Client)(nil)
+
+// ollamaClientFor returns the client used to talk to a backend
+var ollamaClientFor = func(b *backends.Backend) OllamaClient {
+	return b.Client
+}
+
+// adminTargets returns the backends an administrative call applies to: the
+// one named by the "backend" query parameter, or else every healthy backend
+func adminTargets(c *fiber.Ctx) ([]*backends.Backend, *requestError) {
+	if name := c.Query("backend"); name != "" {
+		backend := backends.Find(name)
+		if backend == nil {
+			return nil, &requestError{fiber.StatusNotFound, models.ErrorResponse{
+				Error:   "Backend not found",
+				Message: fmt.Sprintf("No Ollama backend is named %q", name),
+			}}
+		}
+		return []*backends.Backend{backend}, nil
+	}
+
+	targets := backends.Available()
+	if len(targets) == 0 {
+		return nil, &requestError{fiber.StatusBadGateway, models.ErrorResponse{
+			Error:   "Failed to connect to Ollama",
+			Message: "No Ollama backend is healthy",
+		}}
+	}
+	return targets, nil
+}
+
+// fanOut runs fn concurrently with the client of each backend, by its index
+// in targets, and returns the error of each. Backends that cannot be reached
+// are marked down.
+func fanOut(targets []*backends.Backend, fn func(i int, client OllamaClient) error) []error {
+	errs := make([]error, len(targets))
+	var wg sync.WaitGroup
+	for i, b := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if errs[i] = fn(i, ollamaClientFor(b)); errs[i] != nil {
+				log.Printf("Ollama backend %s: %v", b.Name, errs[i])
+				if ollama.IsConnectError(errs[i]) {
+					b.MarkDown(errs[i])
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return errs
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ollama-web-api/internal/batches"
+	"github.com/ollama-web-api/internal/jobs"
+)
+
+// disconnectPollInterval is how often the connection of a request waiting on
+// Ollama is checked for a hang-up
+const disconnectPollInterval = 250 * time.Millisecond
+
+// statusClientClosedRequest is recorded for requests the client abandoned
+const statusClientClosedRequest = 499
+
+// errClientGone is the cancellation cause of a request context whose client
+// closed the connection
+var errClientGone = errors.New("client disconnected")
+
+// requestContext returns the context for upstream calls made on behalf of
+// the request. It expires after timeout and is cancelled with errClientGone
+// as cause as soon as the client closes its connection, so Ollama stops
+// generating for nobody. It must be cancelled once the request is done.
+func requestContext(c *fiber.Ctx, timeout time.Duration) (context.Context, context.CancelCauseFunc) {
+	parent, cancelCause := context.WithCancelCause(context.Background())
+	ctx, cancelTimeout := context.WithTimeout(parent, timeout)
+	cancel := func(cause error) {
+		cancelCause(cause)
+		cancelTimeout()
+	}
+
+	// The fiber context is recycled when the handler returns, while streamed
+	// responses are written afterwards; hold on to the connection itself
+	conn := c.Context().Conn()
+	if !canDetectClose(conn) {
+		return ctx, cancel
+	}
+
+	go func() {
+		ticker := time.NewTicker(disconnectPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if connClosed(conn) {
+					cancel(errClientGone)
+					return
+				}
+			}
+		}
+	}()
+	return ctx, cancel
+}
+
+// clientGone reports whether ctx was cancelled on behalf of the client,
+// because it hung up or cancelled its job or batch
+func clientGone(ctx context.Context) bool {
+	cause := context.Cause(ctx)
+	return errors.Is(cause, errClientGone) || errors.Is(cause, jobs.ErrCancelled) ||
+		errors.Is(cause, batches.ErrCancelled)
+}
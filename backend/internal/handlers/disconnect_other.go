@@ -0,0 +1,16 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd)
+
+package handlers
+
+import "net"
+
+// canDetectClose reports whether connClosed works for conn. Hang-ups are
+// only noticed on supported platforms; elsewhere requests run to the end.
+func canDetectClose(conn net.Conn) bool {
+	return false
+}
+
+// connClosed reports whether the peer has closed conn
+func connClosed(conn net.Conn) bool {
+	return false
+}
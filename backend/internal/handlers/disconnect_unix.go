@@ -0,0 +1,45 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+
+package handlers
+
+import (
+	"errors"
+	"net"
+	"syscall"
+)
+
+// canDetectClose reports whether connClosed works for conn
+func canDetectClose(conn net.Conn) bool {
+	_, ok := conn.(syscall.Conn)
+	return ok
+}
+
+// connClosed reports whether the peer has closed conn. It peeks at the
+// socket without blocking, so pipelined request data is left in place.
+func connClosed(conn net.Conn) bool {
+	sc, ok := conn.(syscall.Conn)
+	if !ok {
+		return false
+	}
+	raw, err := sc.SyscallConn()
+	if err != nil {
+		return true
+	}
+
+	closed := false
+	var buf [1]byte
+	err = raw.Read(func(fd uintptr) bool {
+		n, _, err := syscall.Recvfrom(int(fd), buf[:], syscall.MSG_PEEK|syscall.MSG_DONTWAIT)
+		switch {
+		case err == nil:
+			closed = n == 0
+		case errors.Is(err, syscall.EAGAIN), errors.Is(err, syscall.EWOULDBLOCK), errors.Is(err, syscall.EINTR):
+			// Nothing to read yet, the connection is still open
+		default:
+			closed = true
+		}
+		// Never wait for the socket to become readable
+		return true
+	})
+	return closed || err != nil
+}
@@ -0,0 +1,440 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ollama-web-api/internal/backends"
+	"github.com/ollama-web-api/internal/database"
+	"github.com/ollama-web-api/internal/models"
+	"github.com/ollama-web-api/internal/ollama"
+	"github.com/ollama-web-api/internal/queue"
+	"github.com/ollama-web-api/internal/ratelimit"
+)
+
+// generationStats holds the counters Ollama reports in its final response
+type generationStats struct {
+	PromptEvalCount    int
+	EvalCount          int
+	TotalDuration      int64
+	LoadDuration       int64
+	PromptEvalDuration int64
+	EvalDuration       int64
+}
+
+// statsOf extracts the counters from a response or stream chunk. It returns
+// nil for chunks other than the final one.
+func statsOf(v interface{}) *generationStats {
+	switch r := v.(type) {
+	case *models.OllamaResponse:
+		if r.Done {
+			return &generationStats{r.PromptEvalCount, r.EvalCount, r.TotalDuration, r.LoadDuration, r.PromptEvalDuration, r.EvalDuration}
+		}
+	case *models.OllamaChatResponse:
+		if r.Done {
+			return &generationStats{r.PromptEvalCount, r.EvalCount, r.TotalDuration, r.LoadDuration, r.PromptEvalDuration, r.EvalDuration}
+		}
+	case *models.OllamaEmbedResponse:
+		return &generationStats{PromptEvalCount: r.PromptEvalCount, TotalDuration: r.TotalDuration, LoadDuration: r.LoadDuration}
+	}
+	return nil
+}
+
+// inference tracks a single proxied Ollama request from admission until its
+// response body has been fully consumed, at which point its usage is recorded
+type inference struct {
+	project    *models.Project
+	model      string
+	endpoint   string
+	statusCode int
+	limit      *ratelimit.Result
+	once       sync.Once
+
+	// ctx bounds the upstream request; it is cancelled once finished or
+	// when the client disconnects
+	ctx         context.Context
+	cancel      context.CancelCauseFunc
+	ticket      *queue.Ticket
+	backendDone func()
+}
+
+// rateLimitKey returns the rate limit bucket of a project
+func rateLimitKey(project *models.Project) string {
+	return fmt.Sprintf("project:%d", project.ID)
+}
+
+// projectRateLimits converts the project rate limit settings
+func projectRateLimits(project *models.Project) ratelimit.Limits {
+	var limits ratelimit.Limits
+	if v := project.RateLimits.RequestsPerMinute; v != nil {
+		limits.RequestsPerMinute = *v
+	}
+	if v := project.RateLimits.TokensPerMinute; v != nil {
+		limits.TokensPerMinute = *v
+	}
+	if v := project.RateLimits.MaxConcurrent; v != nil {
+		limits.MaxConcurrent = *v
+	}
+	return limits
+}
+
+// responseHeaders receives the headers admission adds to the response:
+// the fiber context for API requests, an http.Header for background jobs
+type responseHeaders interface {
+	Set(key, val string)
+}
+
+// setRateLimitHeaders adds the X-RateLimit-* headers for the configured budgets
+func setRateLimitHeaders(c responseHeaders, result *ratelimit.Result) {
+	reset := strconv.Itoa(int(result.Reset.Unix()))
+	if result.RequestLimit > 0 {
+		c.Set("X-RateLimit-Limit-Requests", strconv.Itoa(result.RequestLimit))
+		c.Set("X-RateLimit-Remaining-Requests", strconv.Itoa(result.RequestRemaining))
+		c.Set("X-RateLimit-Reset-Requests", reset)
+	}
+	if result.TokenLimit > 0 {
+		c.Set("X-RateLimit-Limit-Tokens", strconv.Itoa(result.TokenLimit))
+		c.Set("X-RateLimit-Remaining-Tokens", strconv.Itoa(result.TokenRemaining))
+		c.Set("X-RateLimit-Reset-Tokens", reset)
+	}
+}
+
+// beginInference admits a request for the project under its quotas and its
+// rate and concurrency limits, then waits in the queue for a backend slot.
+// The returned inference must be finished, which happens automatically once
+// its response has been read or its stream closed.
+func beginInference(c *fiber.Ctx, project *models.Project, model string) (*inference, *requestError) {
+	ctx, cancel := requestContext(c, requestTimeout)
+	return admitInference(ctx, cancel, c, project, model)
+}
+
+// admitInference is beginInference for a caller-supplied context, which the
+// inference cancels once finished or refused
+func admitInference(ctx context.Context, cancel context.CancelCauseFunc, c responseHeaders, project *models.Project, model string) (*inference, *requestError) {
+	if rerr := checkQuotas(c, project); rerr != nil {
+		cancel(nil)
+		return nil, rerr
+	}
+
+	limits := projectRateLimits(project)
+	result, err := ratelimit.Allow(rateLimitKey(project), limits)
+	if err != nil {
+		// Fail open: an unavailable limiter store must not take the gateway down
+		log.Printf("Rate limiter error for project %d: %v", project.ID, err)
+		result = nil
+	} else {
+		setRateLimitHeaders(c, result)
+		if !result.Allowed {
+			retryAfter := int(result.RetryAfter.Seconds() + 0.5)
+			if retryAfter < 1 {
+				retryAfter = 1
+			}
+			c.Set("Retry-After", strconv.Itoa(retryAfter))
+
+			var message string
+			switch result.Reason {
+			case "requests":
+				message = fmt.Sprintf("Request limit of %d per minute exceeded", limits.RequestsPerMinute)
+			case "tokens":
+				message = fmt.Sprintf("Token limit of %d per minute exceeded", limits.TokensPerMinute)
+			default:
+				message = fmt.Sprintf("Concurrency limit of %d in-flight requests exceeded", limits.MaxConcurrent)
+			}
+			cancel(nil)
+			return nil, &requestError{fiber.StatusTooManyRequests, models.ErrorResponse{
+				Error:   "Rate limit exceeded",
+				Message: message,
+			}}
+		}
+	}
+
+	inf := &inference{project: project, model: model, limit: result, ctx: ctx, cancel: cancel}
+	if rerr := inf.enqueue(c); rerr != nil {
+		inf.release()
+		return nil, rerr
+	}
+	return inf, nil
+}
+
+// errNoBackends is returned when the pool has no backend to try
+var errNoBackends = errors.New("no Ollama backend is configured")
+
+// ollamaError converts an error from the Ollama client into a gateway error
+func ollamaError(err error) *requestError {
+	status := ollama.GatewayStatus(err)
+
+	var statusErr *ollama.StatusError
+	switch {
+	case errors.As(err, &statusErr):
+		return &requestError{status, models.ErrorResponse{
+			Error:   "Ollama API error",
+			Message: statusErr.Message,
+		}}
+	case errors.Is(err, context.DeadlineExceeded):
+		return &requestError{status, models.ErrorResponse{
+			Error:   "Ollama request timed out",
+			Message: err.Error(),
+		}}
+	default:
+		return &requestError{status, models.ErrorResponse{
+			Error:   "Failed to connect to Ollama",
+			Message: err.Error(),
+		}}
+	}
+}
+
+// call runs fn against the backends that serve the model, moving on to the
+// next backend when one cannot be reached. On success the backend stays
+// reserved until the inference is finished; on failure the inference is
+// finished right away.
+func (inf *inference) call(endpoint string, fn func(client OllamaClient) error) *requestError {
+	inf.endpoint = endpoint
+
+	lastErr := errNoBackends
+	for _, backend := range inf.candidates() {
+		log.Printf("Sending %s request for %s to Ollama backend %s", endpoint, inf.model, backend.Name)
+
+		release := backend.Begin()
+		err := fn(ollamaClientFor(backend))
+		if err == nil {
+			inf.backendDone = release
+			inf.statusCode = fiber.StatusOK
+			return nil
+		}
+		release()
+		lastErr = err
+
+		if !ollama.IsConnectError(err) || inf.ctx.Err() != nil {
+			break
+		}
+		// Nothing was sent, so another backend can take the request
+		log.Printf("Connection error to Ollama backend %s: %v", backend.Name, err)
+		backend.MarkDown(err)
+	}
+
+	rerr := ollamaError(lastErr)
+	inf.statusCode = rerr.Status
+	inf.finish(nil)
+	return rerr
+}
+
+// candidates returns the backends to try: the one the queue granted a slot
+// on, then the other candidates for failover. Failover skips the queue, as
+// the request has already waited its turn.
+func (inf *inference) candidates() []*backends.Backend {
+	candidates := backends.Candidates(inf.model)
+	if inf.ticket == nil || inf.ticket.Backend == nil {
+		return candidates
+	}
+	ordered := []*backends.Backend{inf.ticket.Backend}
+	for _, b := range candidates {
+		if b != inf.ticket.Backend {
+			ordered = append(ordered, b)
+		}
+	}
+	return ordered
+}
+
+// generate runs a non-streaming completion
+func (inf *inference) generate(req *models.OllamaRequest) (*models.OllamaResponse, *requestError) {
+	var resp *models.OllamaResponse
+	rerr := inf.call("generate", func(client OllamaClient) (err error) {
+		resp, err = client.Generate(inf.ctx, req)
+		return err
+	})
+	if rerr != nil {
+		return nil, rerr
+	}
+	inf.finish(statsOf(resp))
+	return resp, nil
+}
+
+// generateStream runs a streaming completion
+func (inf *inference) generateStream(req *models.OllamaRequest) (*inferenceStream[models.OllamaResponse], *requestError) {
+	var stream *ollama.Stream[models.OllamaResponse]
+	rerr := inf.call("generate", func(client OllamaClient) (err error) {
+		stream, err = client.GenerateStream(inf.ctx, req)
+		return err
+	})
+	if rerr != nil {
+		return nil, rerr
+	}
+	return &inferenceStream[models.OllamaResponse]{Stream: stream, inf: inf}, nil
+}
+
+// chat runs a non-streaming chat completion
+func (inf *inference) chat(req *models.OllamaChatRequest) (*models.OllamaChatResponse, *requestError) {
+	var resp *models.OllamaChatResponse
+	rerr := inf.call("chat", func(client OllamaClient) (err error) {
+		resp, err = client.Chat(inf.ctx, req)
+		return err
+	})
+	if rerr != nil {
+		return nil, rerr
+	}
+	inf.finish(statsOf(resp))
+	return resp, nil
+}
+
+// chatStream runs a streaming chat completion
+func (inf *inference) chatStream(req *models.OllamaChatRequest) (*inferenceStream[models.OllamaChatResponse], *requestError) {
+	var stream *ollama.Stream[models.OllamaChatResponse]
+	rerr := inf.call("chat", func(client OllamaClient) (err error) {
+		stream, err = client.ChatStream(inf.ctx, req)
+		return err
+	})
+	if rerr != nil {
+		return nil, rerr
+	}
+	return &inferenceStream[models.OllamaChatResponse]{Stream: stream, inf: inf}, nil
+}
+
+// embed generates embeddings
+func (inf *inference) embed(req *models.OllamaEmbedRequest) (*models.OllamaEmbedResponse, *requestError) {
+	var resp *models.OllamaEmbedResponse
+	rerr := inf.call("embed", func(client OllamaClient) (err error) {
+		resp, err = client.Embed(inf.ctx, req)
+		return err
+	})
+	if rerr != nil {
+		return nil, rerr
+	}
+	inf.finish(statsOf(resp))
+	return resp, nil
+}
+
+// release cancels the upstream context and frees the backend, the queue
+// slot and the concurrency slot
+func (inf *inference) release() {
+	inf.cancel(nil)
+	if inf.backendDone != nil {
+		inf.backendDone()
+	}
+	if inf.ticket != nil {
+		inf.ticket.Release()
+	}
+	if inf.limit != nil {
+		inf.limit.Release()
+	}
+}
+
+// finish releases everything the inference holds, records consumed tokens
+// and persists the usage record. Only the first call has an effect.
+func (inf *inference) finish(stats *generationStats) {
+	inf.once.Do(func() {
+		cancelled := clientGone(inf.ctx)
+		inf.release()
+
+		record := models.UsageRecord{
+			ProjectID:  inf.project.ID,
+			Model:      inf.model,
+			Endpoint:   inf.endpoint,
+			StatusCode: inf.statusCode,
+		}
+		switch {
+		case stats != nil && inf.statusCode == fiber.StatusOK:
+			record.Status = "success"
+		case cancelled:
+			log.Printf("%s request for %s cancelled by the client", inf.endpoint, inf.model)
+			record.Status = "cancelled"
+			record.StatusCode = statusClientClosedRequest
+		case inf.statusCode != fiber.StatusOK:
+			record.Status = "error"
+		default:
+			// The response ended before Ollama reported its final counters
+			record.Status = "incomplete"
+		}
+
+		if stats != nil {
+			record.PromptTokens = stats.PromptEvalCount
+			record.CompletionTokens = stats.EvalCount
+			record.TotalTokens = stats.PromptEvalCount + stats.EvalCount
+			record.TotalDuration = stats.TotalDuration
+			record.LoadDuration = stats.LoadDuration
+			record.PromptEvalDuration = stats.PromptEvalDuration
+			record.EvalDuration = stats.EvalDuration
+
+			if err := ratelimit.AddTokens(rateLimitKey(inf.project), record.TotalTokens); err != nil {
+				log.Printf("Failed to record tokens for project %d: %v", inf.project.ID, err)
+			}
+		}
+
+		if err := database.DB.Create(&record).Error; err != nil {
+			log.Printf("Failed to record usage for project %d: %v", inf.project.ID, err)
+		}
+	})
+}
+
+// inferenceStream is an Ollama stream that picks up the counters of the
+// final chunk and finishes its inference when closed
+type inferenceStream[T any] struct {
+	*ollama.Stream[T]
+	inf   *inference
+	stats *generationStats
+}
+
+// Next advances to the next chunk
+func (s *inferenceStream[T]) Next() bool {
+	if !s.Stream.Next() {
+		if err := s.Err(); err != nil {
+			s.inf.statusCode = ollama.GatewayStatus(err)
+		}
+		return false
+	}
+	if stats := statsOf(s.Current()); stats != nil {
+		s.stats = stats
+	}
+	return true
+}
+
+// abort stops the upstream generation after the client went away
+func (s *inferenceStream[T]) abort() {
+	s.inf.cancel(errClientGone)
+}
+
+// Close releases the connection and finishes the inference
+func (s *inferenceStream[T]) Close() error {
+	err := s.Stream.Close()
+	s.inf.finish(s.stats)
+	return err
+}
+
+// proxyStream writes the chunks of stream to the client unchanged, as
+// newline-delimited JSON
+func proxyStream[T any](c *fiber.Ctx, stream *inferenceStream[T]) error {
+	c.Set("Content-Type", "application/x-ndjson")
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer stream.Close()
+
+		for stream.Next() {
+			w.Write(stream.Raw())
+			w.WriteByte('\n')
+			if err := w.Flush(); err != nil {
+				// Client went away
+				stream.abort()
+				return
+			}
+		}
+
+		if err := stream.Err(); err != nil {
+			// Report the failure in-band, the way Ollama does
+			message := err.Error()
+			var statusErr *ollama.StatusError
+			if errors.As(err, &statusErr) {
+				message = statusErr.Message
+			}
+			data, _ := json.Marshal(fiber.Map{"error": message})
+			w.Write(data)
+			w.WriteByte('\n')
+			w.Flush()
+		}
+	})
+	return nil
+}
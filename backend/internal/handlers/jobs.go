@@ -0,0 +1,308 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ollama-web-api/internal/apikeys"
+	"github.com/ollama-web-api/internal/database"
+	"github.com/ollama-web-api/internal/jobs"
+	"github.com/ollama-web-api/internal/models"
+	"gorm.io/gorm"
+)
+
+// validateWebhookURL checks that a project webhook is an absolute HTTP(S) URL
+func validateWebhookURL(raw string) error {
+	if raw == "" {
+		return nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("webhook_url must be an absolute http or https URL")
+	}
+	return nil
+}
+
+// setWebhookURL sets the webhook of a project and creates its signing secret
+// the first time one is configured
+func setWebhookURL(project *models.Project, raw string) error {
+	project.WebhookURL = raw
+	if raw != "" && project.WebhookSecret == "" {
+		return newWebhookSecret(project)
+	}
+	return nil
+}
+
+// newWebhookSecret replaces the signing secret of a project's webhook. The
+// secret is also set in NewWebhookSecret, so the response shows it once.
+func newWebhookSecret(project *models.Project) error {
+	secret, err := generateAPIKey()
+	if err != nil {
+		return err
+	}
+	project.WebhookSecret = secret
+	project.NewWebhookSecret = secret
+	return nil
+}
+
+// retryableAdmission reports whether a background request refused admission
+// should wait and try again: rate limits and a full queue clear up, quotas
+// and bad requests do not
+func retryableAdmission(rerr *requestError) bool {
+	switch {
+	case rerr.Status == fiber.StatusTooManyRequests:
+		return rerr.Code != "quota_exceeded"
+	case rerr.Status == fiber.StatusServiceUnavailable:
+		return rerr.Code == "queue_timeout"
+	}
+	return false
+}
+
+// admitBackground admits a request made on behalf of a background job or
+// batch through the same quota, rate limit and queue admission as an API
+// request. Refusals that clear up with time are waited out instead of
+// returned.
+func admitBackground(ctx context.Context, project *models.Project, model string) (*inference, *requestError) {
+	for {
+		attemptCtx, cancel := context.WithCancelCause(ctx)
+		headers := http.Header{}
+		inf, rerr := admitInference(attemptCtx, cancel, headers, project, model)
+		if rerr == nil || !retryableAdmission(rerr) {
+			return inf, rerr
+		}
+
+		wait := time.Second
+		if seconds, err := strconv.Atoi(headers.Get("Retry-After")); err == nil && seconds > 0 {
+			wait = time.Duration(seconds) * time.Second
+		}
+		select {
+		case <-ctx.Done():
+			return nil, &requestError{statusClientClosedRequest, models.ErrorResponse{
+				Error:   "Request cancelled",
+				Message: context.Cause(ctx).Error(),
+			}}
+		case <-time.After(wait):
+		}
+	}
+}
+
+// jobAPIKey loads the API key that queued a job and checks that it is still
+// valid. Jobs of earlier versions have no key.
+func jobAPIKey(job *models.GenerationJob) (*models.APIKey, error) {
+	if job.APIKeyID == nil {
+		return nil, nil
+	}
+	var key models.APIKey
+	if err := database.DB.Limit(1).Find(&key, *job.APIKeyID).Error; err != nil {
+		return nil, fmt.Errorf("failed to load API key: %w", err)
+	}
+	if key.ID == 0 || key.RevokedAt != nil {
+		return nil, errors.New("API key was revoked")
+	}
+	if key.ExpiresAt != nil && !key.ExpiresAt.After(time.Now()) {
+		return nil, errors.New("API key expired")
+	}
+	return &key, nil
+}
+
+// RunGenerationJob runs a generation job under the limits of its project.
+// The project must still be active, the model assigned to it, and the key
+// that queued the job valid and allowed the model.
+func RunGenerationJob(ctx context.Context, job *models.GenerationJob) (*models.OllamaResponse, error) {
+	var project models.Project
+	if err := database.DB.Preload("Models").First(&project, job.ProjectID).Error; err != nil {
+		return nil, fmt.Errorf("failed to load project: %w", err)
+	}
+	if !project.IsActive {
+		return nil, errors.New("project is inactive")
+	}
+	key, err := jobAPIKey(job)
+	if err != nil {
+		return nil, err
+	}
+
+	var req models.OllamaRequest
+	if err := json.Unmarshal([]byte(job.Request), &req); err != nil {
+		return nil, fmt.Errorf("failed to decode job request: %w", err)
+	}
+	if rerr := checkProjectModel(&project, req.Model); rerr != nil {
+		return nil, fmt.Errorf("%s: %s", rerr.Error, rerr.Message)
+	}
+	if rerr := keyModelError(key, req.Model); rerr != nil {
+		return nil, fmt.Errorf("%s: %s", rerr.Error, rerr.Message)
+	}
+
+	inf, rerr := admitBackground(ctx, &project, req.Model)
+	if rerr != nil {
+		return nil, fmt.Errorf("%s: %s", rerr.Error, rerr.Message)
+	}
+	resp, rerr := inf.generate(&req)
+	if rerr != nil {
+		return nil, fmt.Errorf("%s: %s", rerr.Error, rerr.Message)
+	}
+	return resp, nil
+}
+
+// projectJob loads a job of the project authenticated by the API key
+func projectJob(c *fiber.Ctx) (*models.GenerationJob, *requestError) {
+	project, rerr := authorizeProject(c, apikeys.ScopeGenerate)
+	if rerr != nil {
+		return nil, rerr
+	}
+
+	job, err := jobs.Load(c.Params("id"), project.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, &requestError{fiber.StatusNotFound, models.ErrorResponse{
+			Error:   "Job not found",
+			Message: "No job with this ID exists for the project",
+		}}
+	}
+	if err != nil {
+		return nil, &requestError{fiber.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to fetch job",
+			Message: err.Error(),
+		}}
+	}
+	return job, nil
+}
+
+// CreateGenerationJob godoc
+// @Summary Queue a generation job
+// @Description Accept a generate request and run it in the background, without the time limit of synchronous requests. Poll the job or configure a project webhook to get the result. Requires a valid project API key and model assignment.
+// @Tags jobs
+// @Accept json
+// @Produce json
+// @Param X-API-Key header string true "Project API Key"
+// @Param request body models.OllamaRequest true "Ollama request"
+// @Success 202 {object} models.GenerationJob
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
+// @Router /api/ollama/jobs [post]
+func CreateGenerationJob(c *fiber.Ctx) error {
+	req, rerr := parseGenerateRequest(c)
+	if rerr != nil {
+		return rerr.send(c)
+	}
+
+	project, rerr := authorizeProjectModel(c, apikeys.ScopeGenerate, req.Model)
+	if rerr != nil {
+		return rerr.send(c)
+	}
+
+	if rerr := applyGenerateSettings(req, project); rerr != nil {
+		return rerr.send(c)
+	}
+	req.Stream = false
+
+	// Refuse right away when the quota is used up; the job would only fail
+	if rerr := checkQuotas(c, project); rerr != nil {
+		return rerr.send(c)
+	}
+
+	request, err := json.Marshal(req)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to create job",
+			Message: err.Error(),
+		})
+	}
+	id, err := jobs.NewID()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to create job",
+			Message: err.Error(),
+		})
+	}
+
+	job := models.GenerationJob{
+		ID:        id,
+		ProjectID: project.ID,
+		Model:     req.Model,
+		Status:    jobs.StatusQueued,
+		Request:   string(request),
+	}
+	if key, ok := c.Locals("api_key").(*models.APIKey); ok {
+		job.APIKeyID = &key.ID
+	}
+	if err := database.DB.Create(&job).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to create job",
+			Message: err.Error(),
+		})
+	}
+	jobs.Notify()
+
+	log.Printf("Queued generation job %s for project %d", job.ID, project.ID)
+	c.Location("/api/ollama/jobs/" + job.ID)
+	return c.Status(fiber.StatusAccepted).JSON(job)
+}
+
+// GetGenerationJob godoc
+// @Summary Get a generation job
+// @Description Get the status of a generation job and, once it succeeded, its response
+// @Tags jobs
+// @Produce json
+// @Param X-API-Key header string true "Project API Key"
+// @Param id path string true "Job ID"
+// @Success 200 {object} models.GenerationJob
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /api/ollama/jobs/{id} [get]
+func GetGenerationJob(c *fiber.Ctx) error {
+	job, rerr := projectJob(c)
+	if rerr != nil {
+		return rerr.send(c)
+	}
+	return c.JSON(job)
+}
+
+// CancelGenerationJob godoc
+// @Summary Cancel a generation job
+// @Description Cancel a queued or running generation job
+// @Tags jobs
+// @Produce json
+// @Param X-API-Key header string true "Project API Key"
+// @Param id path string true "Job ID"
+// @Success 200 {object} models.GenerationJob
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /api/ollama/jobs/{id}/cancel [post]
+func CancelGenerationJob(c *fiber.Ctx) error {
+	job, rerr := projectJob(c)
+	if rerr != nil {
+		return rerr.send(c)
+	}
+
+	cancelled, err := jobs.Cancel(job)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to cancel job",
+			Message: err.Error(),
+		})
+	}
+	if !cancelled {
+		return c.Status(fiber.StatusConflict).JSON(models.ErrorResponse{
+			Error:   "Job already finished",
+			Message: fmt.Sprintf("The job has already %s", job.Status),
+		})
+	}
+
+	job, rerr = projectJob(c)
+	if rerr != nil {
+		return rerr.send(c)
+	}
+	return c.JSON(job)
+}
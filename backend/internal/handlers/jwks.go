@@ -0,0 +1,19 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/ollama-web-api/internal/jwtkeys"
+)
+
+// JWKS godoc
+// @Summary Keys that verify admin tokens
+// @Description Get the public keys that verify admin JWT tokens as a JSON Web Key Set, for other services to check tokens by their kid header. Empty when tokens are signed with JWT_SECRET, which is never published.
+// @Tags auth
+// @Produce json
+// @Success 200 {object} models.JSONWebKeySet
+// @Router /.well-known/jwks.json [get]
+func JWKS(c *fiber.Ctx) error {
+	// Short enough that verifiers pick up a new key before it signs tokens
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.JSON(jwtkeys.JWKS())
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ollama-web-api/internal/audit"
+	"github.com/ollama-web-api/internal/database"
+	"github.com/ollama-web-api/internal/loginguard"
+	"github.com/ollama-web-api/internal/models"
+)
+
+// ListLockouts godoc
+// @Summary List login throttles
+// @Description List the client IPs and usernames with recent failed logins, most recent first. Those with blocked_until in the future cannot log in until then; locked ones reached the failure limit.
+// @Tags lockouts
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} models.LoginThrottle
+// @Router /api/lockouts [get]
+func ListLockouts(c *fiber.Ctx) error {
+	now := time.Now()
+	var throttles []models.LoginThrottle
+	err := database.DB.
+		Where("last_failure_at >= ? OR blocked_until > ?", now.Add(-loginguard.Window()), now).
+		Order("last_failure_at DESC").
+		Find(&throttles).Error
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to fetch lockouts",
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(throttles)
+}
+
+// ClearLockout godoc
+// @Summary Clear a login throttle
+// @Description Forget the failed logins of a client IP or username, so it can log in again right away
+// @Tags lockouts
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Throttle ID"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /api/lockouts/{id} [delete]
+func ClearLockout(c *fiber.Ctx) error {
+	var throttle models.LoginThrottle
+	if err := database.DB.First(&throttle, c.Params("id")).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+			Error:   "Lockout not found",
+			Message: err.Error(),
+		})
+	}
+
+	if err := database.DB.Delete(&throttle).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to clear lockout",
+			Message: err.Error(),
+		})
+	}
+
+	recordAdminAction(c, audit.ActionLockoutCleared, fmt.Sprintf("%s %s after %d failures", throttle.Kind, throttle.Key, throttle.Failures))
+	return c.JSON(models.SuccessResponse{
+		Message: "Lockout cleared successfully",
+	})
+}
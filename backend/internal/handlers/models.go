@@ -37,6 +37,15 @@ func AssignModel(c *fiber.Ctx) error {
 		})
 	}
 
+	if req.Settings != nil {
+		if err := validateSettings(req.Settings); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+				Error:   "Invalid settings",
+				Message: err.Error(),
+			})
+		}
+	}
+
 	// Check if model is already assigned
 	var existingModel models.ProjectModel
 	result := database.DB.Where("project_id = ? AND model_name = ?", project.ID, req.ModelName).First(&existingModel)
@@ -51,6 +60,9 @@ func AssignModel(c *fiber.Ctx) error {
 		ProjectID: project.ID,
 		ModelName: req.ModelName,
 	}
+	if req.Settings != nil {
+		projectModel.Settings = *req.Settings
+	}
 
 	if err := database.DB.Create(&projectModel).Error; err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
@@ -62,6 +74,58 @@ func AssignModel(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusCreated).JSON(projectModel)
 }
 
+// UpdateModelSettings godoc
+// @Summary Update model settings for a project
+// @Description Replace the per-model default parameters and limits, which override the project settings
+// @Tags models
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Project ID"
+// @Param modelId path int true "Model assignment ID"
+// @Param settings body models.GenerationSettings true "Model settings"
+// @Success 200 {object} models.ProjectModel
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /api/projects/{id}/models/{modelId} [put]
+func UpdateModelSettings(c *fiber.Ctx) error {
+	projectID := c.Params("id")
+	modelID := c.Params("modelId")
+
+	var projectModel models.ProjectModel
+	if err := database.DB.Where("id = ? AND project_id = ?", modelID, projectID).First(&projectModel).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+			Error:   "Model assignment not found",
+			Message: err.Error(),
+		})
+	}
+
+	var settings models.GenerationSettings
+	if err := c.BodyParser(&settings); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: err.Error(),
+		})
+	}
+
+	if err := validateSettings(&settings); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "Invalid settings",
+			Message: err.Error(),
+		})
+	}
+
+	projectModel.Settings = settings
+	if err := database.DB.Save(&projectModel).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to update model settings",
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(projectModel)
+}
+
 // UnassignModel godoc
 // @Summary Unassign a model from a project
 // @Description Remove a model assignment from a project
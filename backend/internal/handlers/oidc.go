@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ollama-web-api/internal/audit"
+	"github.com/ollama-web-api/internal/clientip"
+	"github.com/ollama-web-api/internal/database"
+	"github.com/ollama-web-api/internal/models"
+	"github.com/ollama-web-api/internal/oidc"
+	"github.com/ollama-web-api/internal/users"
+)
+
+// oidcLoginTTL is how long a user has to sign in at the provider
+const oidcLoginTTL = 10 * time.Minute
+
+// oidcStateCookie binds a login to the browser that started it
+const oidcStateCookie = "oidc_state"
+
+// OIDCConfig godoc
+// @Summary Single sign-on configuration
+// @Description Tell whether logging in through OpenID Connect is available
+// @Tags auth
+// @Produce json
+// @Success 200 {object} models.OIDCConfigResponse
+// @Router /api/auth/oidc/config [get]
+func OIDCConfig(c *fiber.Ctx) error {
+	return c.JSON(models.OIDCConfigResponse{Enabled: oidc.Enabled()})
+}
+
+// OIDCLogin godoc
+// @Summary Start a single sign-on login
+// @Description Redirect to the OpenID Connect provider to log in with the authorization code flow and PKCE. The provider sends the browser back to the callback.
+// @Tags auth
+// @Success 302
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 502 {object} models.ErrorResponse
+// @Router /api/auth/oidc/login [get]
+func OIDCLogin(c *fiber.Ctx) error {
+	cfg := oidc.Current()
+	if cfg == nil {
+		return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+			Error:   "Single sign-on disabled",
+			Message: "OpenID Connect is not configured",
+		})
+	}
+
+	login := models.OIDCLogin{ExpiresAt: time.Now().Add(oidcLoginTTL)}
+	for _, value := range []*string{&login.State, &login.Nonce, &login.Verifier} {
+		s, err := oidc.RandomString()
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+				Error:   "Failed to start login",
+				Message: err.Error(),
+			})
+		}
+		*value = s
+	}
+
+	redirect, err := oidc.AuthCodeURL(c.UserContext(), login.State, login.Nonce, login.Verifier)
+	if err != nil {
+		return c.Status(fiber.StatusBadGateway).JSON(models.ErrorResponse{
+			Error:   "Identity provider unavailable",
+			Message: err.Error(),
+		})
+	}
+
+	// Logins that were never finished are cleaned up when new ones start
+	database.DB.Where("expires_at < ?", time.Now()).Delete(&models.OIDCLogin{})
+	if err := database.DB.Create(&login).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to start login",
+			Message: err.Error(),
+		})
+	}
+
+	c.Cookie(&fiber.Cookie{
+		Name:     oidcStateCookie,
+		Value:    login.State,
+		Path:     "/api/auth/oidc",
+		Expires:  login.ExpiresAt,
+		Secure:   strings.HasPrefix(cfg.RedirectURL, "https://"),
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+	return c.Redirect(redirect, fiber.StatusFound)
+}
+
+// OIDCCallback godoc
+// @Summary Finish a single sign-on login
+// @Description Redeem the authorization code from the OpenID Connect provider and redirect to the admin UI with a JWT token and a refresh token in the URL fragment (#token=...&refresh_token=...), or with #error=... when the login failed. Users are created on their first login; their role comes from the configured group and email domain mappings.
+// @Tags auth
+// @Param code query string false "Authorization code"
+// @Param state query string true "State of the login"
+// @Success 302
+// @Router /api/auth/oidc/callback [get]
+func OIDCCallback(c *fiber.Ctx) error {
+	cfg := oidc.Current()
+	if cfg == nil {
+		return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+			Error:   "Single sign-on disabled",
+			Message: "OpenID Connect is not configured",
+		})
+	}
+
+	state := c.Query("state")
+	cookie := c.Cookies(oidcStateCookie)
+	c.ClearCookie(oidcStateCookie)
+	if state == "" || cookie != state {
+		return oidcFailed(c, cfg, "The login expired or was started in another browser. Please try again")
+	}
+
+	var login models.OIDCLogin
+	if err := database.DB.Where("state = ? AND expires_at > ?", state, time.Now()).Limit(1).Find(&login).Error; err != nil {
+		return oidcFailed(c, cfg, "Failed to look up the login")
+	}
+	if login.State == "" {
+		return oidcFailed(c, cfg, "The login expired. Please try again")
+	}
+	// A state can only be used once
+	database.DB.Delete(&login)
+
+	if providerError := c.Query("error"); providerError != "" {
+		return oidcFailed(c, cfg, fmt.Sprintf("The identity provider refused the login: %s %s", providerError, c.Query("error_description")))
+	}
+	if c.Query("code") == "" {
+		return oidcFailed(c, cfg, "The identity provider returned no authorization code")
+	}
+
+	identity, err := oidc.Exchange(c.UserContext(), c.Query("code"), login.Verifier, login.Nonce)
+	if err != nil {
+		log.Printf("Single sign-on failed: %v", err)
+		return oidcFailed(c, cfg, "The identity provider could not confirm the login")
+	}
+
+	username := identity.Email
+	if username == "" {
+		username = identity.Username
+	}
+	if username == "" {
+		username = identity.Subject
+	}
+
+	role, ok := cfg.Role(identity)
+	if !ok {
+		audit.Record(&models.AuditEvent{
+			Action: audit.ActionSSODenied,
+			Actor:  username,
+			IP:     clientip.Get(c).String(),
+			Detail: fmt.Sprintf("no role mapping matches groups %v", identity.Groups),
+		})
+		return oidcFailed(c, cfg, "Your account is not allowed to use this admin UI")
+	}
+
+	user, created, err := users.SignInExternal(users.ProviderOIDC, identity.Issuer+"|"+identity.Subject, username, role)
+	if errors.Is(err, users.ErrDisabled) {
+		return oidcFailed(c, cfg, "This user has been disabled")
+	}
+	if errors.Is(err, users.ErrUsernameTaken) {
+		return oidcFailed(c, cfg, fmt.Sprintf("A local user named '%s' already exists", username))
+	}
+	if err != nil {
+		log.Printf("Single sign-on failed: %v", err)
+		return oidcFailed(c, cfg, "Failed to log in")
+	}
+	if created {
+		audit.Record(&models.AuditEvent{
+			Action: audit.ActionUserCreated,
+			Actor:  users.ProviderOIDC,
+			IP:     clientip.Get(c).String(),
+			Detail: fmt.Sprintf("user %s (%d) as %s", user.Username, user.ID, user.Role),
+		})
+	}
+
+	tokens, err := startSession(c, user)
+	if err != nil {
+		return oidcFailed(c, cfg, "Failed to generate token")
+	}
+
+	fragment := url.Values{
+		"token":         {tokens.AccessToken},
+		"refresh_token": {tokens.RefreshToken},
+		"expires_in":    {strconv.Itoa(tokens.ExpiresIn)},
+		"role":          {user.Role},
+	}
+	return c.Redirect(cfg.PostLoginURL+"#"+fragment.Encode(), fiber.StatusFound)
+}
+
+// oidcFailed sends the browser back to the admin UI with an error. The
+// fragment is not sent to servers, so neither it nor a token end up in logs.
+func oidcFailed(c *fiber.Ctx, cfg *oidc.Config, message string) error {
+	fragment := url.Values{"error": {message}}
+	return c.Redirect(cfg.PostLoginURL+"#"+fragment.Encode(), fiber.StatusFound)
+}
@@ -1,65 +1,210 @@
 package handlers
 
 import (
-	"bytes"
+	"bufio"
+	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
-	"net/http"
-	"os"
 	"strconv"
 	"strings"
-	"time"
+	"sync"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/ollama-web-api/internal/database"
+	"github.com/ollama-web-api/internal/apikeys"
 	"github.com/ollama-web-api/internal/models"
-	"bufio"
+	"github.com/ollama-web-api/internal/ollama"
 )
 
-// OllamaGenerate godoc
-// @Summary Generate text using Ollama
-// @Description Send a prompt to Ollama for text generation. Requires a valid project API key and model assignment.
-// @Tags ollama
-// @Accept json
-// @Produce json
-// @Param X-API-Key header string true "Project API Key"
-// @Param request body models.OllamaRequest true "Ollama request"
-// @Success 200 {object} models.OllamaResponse
-// @Failure 400 {object} models.ErrorResponse
-// @Failure 401 {object} models.ErrorResponse
-// @Failure 403 {object} models.ErrorResponse
-// @Router /api/ollama/generate [post]
-func OllamaGenerate(c *fiber.Ctx) error {
-	// Get API key from context (set by middleware)
-	apiKey, ok := c.Locals("api_key").(string)
+// requestError is an error response that has not been written to the client yet
+type requestError struct {
+	Status int
+	models.ErrorResponse
+}
+
+// send writes the error response to the client
+func (e *requestError) send(c *fiber.Ctx) error {
+	return c.Status(e.Status).JSON(e.ErrorResponse)
+}
+
+// projectFromAPIKey returns the project authenticated by ValidateAPIKey,
+// with its assigned models preloaded, if it is active
+func projectFromAPIKey(c *fiber.Ctx) (*models.Project, *requestError) {
+	project, ok := c.Locals("project").(*models.Project)
 	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
+		return nil, &requestError{fiber.StatusUnauthorized, models.ErrorResponse{
 			Error:   "Invalid API key",
 			Message: "API key not found in request",
-		})
-	}
-
-	// Find project by API key
-	var project models.Project
-	result := database.DB.Where("api_key = ?", apiKey).Preload("Models").First(&project)
-	if result.Error != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
-			Error:   "Invalid API key",
-			Message: "Project not found with the provided API key",
-		})
+		}}
 	}
 
 	// Check if project is active
 	if !project.IsActive {
-		return c.Status(fiber.StatusForbidden).JSON(models.ErrorResponse{
+		return nil, &requestError{fiber.StatusForbidden, models.ErrorResponse{
 			Error:   "Project inactive",
 			Message: "This project is currently inactive and cannot use the API",
-		})
+		}}
 	}
 
+	return project, nil
+}
+
+// checkScope checks that the API key of the request may use scope
+func checkScope(c *fiber.Ctx, scope string) *requestError {
+	key, ok := c.Locals("api_key").(*models.APIKey)
+	if ok && !apikeys.Allows(key.Scopes, scope) {
+		return &requestError{fiber.StatusForbidden, models.ErrorResponse{
+			Error:   "Insufficient scope",
+			Message: fmt.Sprintf("This API key lacks the '%s' scope", scope),
+			Code:    "missing_scope",
+		}}
+	}
+	return nil
+}
+
+// checkKeyModel checks that the API key of the request may use the model
+func checkKeyModel(c *fiber.Ctx, modelName string) *requestError {
+	key, _ := c.Locals("api_key").(*models.APIKey)
+	return keyModelError(key, modelName)
+}
+
+// keyModelError checks that an API key, if any, may use the model
+func keyModelError(key *models.APIKey, modelName string) *requestError {
+	if key != nil && !apikeys.AllowsModel(key.Models, modelName) {
+		return &requestError{fiber.StatusForbidden, models.ErrorResponse{
+			Error:   "Model not available",
+			Message: fmt.Sprintf("Model '%s' is not available to this API key", modelName),
+		}}
+	}
+	return nil
+}
+
+// authorizeProject resolves the active project for the API key set by
+// ValidateAPIKey and checks that the key has the scope
+func authorizeProject(c *fiber.Ctx, scope string) (*models.Project, *requestError) {
+	project, rerr := projectFromAPIKey(c)
+	if rerr != nil {
+		return nil, rerr
+	}
+	if rerr := checkScope(c, scope); rerr != nil {
+		return nil, rerr
+	}
+	return project, nil
+}
+
+// authorizeProjectModel resolves the active project for the API key set by
+// ValidateAPIKey and checks that the key has the scope and that the
+// requested model is assigned to the project and allowed for the key
+func authorizeProjectModel(c *fiber.Ctx, scope, modelName string) (*models.Project, *requestError) {
+	project, rerr := authorizeProject(c, scope)
+	if rerr != nil {
+		return nil, rerr
+	}
+
+	if rerr := checkProjectModel(project, modelName); rerr != nil {
+		return nil, rerr
+	}
+	if rerr := checkKeyModel(c, modelName); rerr != nil {
+		return nil, rerr
+	}
+	return project, nil
+}
+
+// checkProjectModel checks that the model is assigned to the project
+func checkProjectModel(project *models.Project, modelName string) *requestError {
+	for _, pm := range project.Models {
+		if pm.ModelName == modelName {
+			return nil
+		}
+	}
+
+	return &requestError{fiber.StatusForbidden, models.ErrorResponse{
+		Error:   "Model not available",
+		Message: fmt.Sprintf("Model '%s' is not assigned to this project", modelName),
+	}}
+}
+
+// normalizeKeepAlive converts a numeric keep_alive string (e.g. "300" from a
+// form field) into seconds, since Ollama only accepts numbers or duration
+// strings with a unit
+func normalizeKeepAlive(keepAlive interface{}) interface{} {
+	if s, ok := keepAlive.(string); ok {
+		if s == "" {
+			return nil
+		}
+		if n, err := strconv.Atoi(s); err == nil {
+			return n
+		}
+	}
+	return keepAlive
+}
+
+// parseGenerateFormFields reads the optional generation fields of a
+// multipart generate request. Structured fields (format, options, context)
+// are expected as JSON strings.
+func parseGenerateFormFields(values map[string][]string, req *models.OllamaRequest) error {
+	value := func(key string) (string, bool) {
+		if vals, ok := values[key]; ok && len(vals) > 0 && vals[0] != "" {
+			return vals[0], true
+		}
+		return "", false
+	}
+
+	if v, ok := value("suffix"); ok {
+		req.Suffix = v
+	}
+	if v, ok := value("system"); ok {
+		req.System = v
+	}
+	if v, ok := value("template"); ok {
+		req.Template = v
+	}
+	if v, ok := value("raw"); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("raw must be a boolean")
+		}
+		req.Raw = b
+	}
+	if v, ok := value("keep_alive"); ok {
+		req.KeepAlive = v
+	}
+	if v, ok := value("format"); ok {
+		// "json" or a JSON schema object
+		if strings.HasPrefix(strings.TrimSpace(v), "{") {
+			var schema map[string]interface{}
+			if err := json.Unmarshal([]byte(v), &schema); err != nil {
+				return fmt.Errorf("format must be \"json\" or a JSON schema: %w", err)
+			}
+			req.Format = schema
+		} else {
+			req.Format = v
+		}
+	}
+	if v, ok := value("options"); ok {
+		var options models.GenerateOptions
+		if err := json.Unmarshal([]byte(v), &options); err != nil {
+			return fmt.Errorf("options must be a JSON object: %w", err)
+		}
+		req.Options = &options
+	}
+	if v, ok := value("context"); ok {
+		var context []int
+		if err := json.Unmarshal([]byte(v), &context); err != nil {
+			return fmt.Errorf("context must be a JSON array of integers: %w", err)
+		}
+		req.Context = context
+	}
+
+	return nil
+}
+
+// parseGenerateRequest reads a generate request from a JSON or
+// multipart/form-data body; files sent as "attachments" become images
+func parseGenerateRequest(c *fiber.Ctx) (*models.OllamaRequest, *requestError) {
 	// Parse request - support both JSON and multipart/form-data (for attachments)
 	var req models.OllamaRequest
 	contentType := c.Get("Content-Type")
@@ -67,10 +212,10 @@ func OllamaGenerate(c *fiber.Ctx) error {
 		// parse multipart form
 		form, err := c.MultipartForm()
 		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			return nil, &requestError{fiber.StatusBadRequest, models.ErrorResponse{
 				Error:   "Invalid multipart request",
 				Message: err.Error(),
-			})
+			}}
 		}
 
 		// required fields
@@ -85,6 +230,14 @@ func OllamaGenerate(c *fiber.Ctx) error {
 			req.Stream = b
 		}
 
+		// optional generation fields
+		if err := parseGenerateFormFields(form.Value, &req); err != nil {
+			return nil, &requestError{fiber.StatusBadRequest, models.ErrorResponse{
+				Error:   "Invalid multipart request",
+				Message: err.Error(),
+			}}
+		}
+
 		// handle attachments (read and base64-encode)
 		if files, ok := form.File["attachments"]; ok {
 			for _, fh := range files {
@@ -104,181 +257,325 @@ func OllamaGenerate(c *fiber.Ctx) error {
 	} else {
 		// JSON body
 		if err := c.BodyParser(&req); err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			return nil, &requestError{fiber.StatusBadRequest, models.ErrorResponse{
 				Error:   "Invalid request",
 				Message: err.Error(),
-			})
+			}}
 		}
 	}
 
-	// Validate that the requested model is assigned to this project
-	modelAssigned := false
-	for _, pm := range project.Models {
-		if pm.ModelName == req.Model {
-			modelAssigned = true
-			break
-		}
+	req.KeepAlive = normalizeKeepAlive(req.KeepAlive)
+	return &req, nil
+}
+
+// OllamaGenerate godoc
+// @Summary Generate text using Ollama
+// @Description Send a prompt to Ollama for text generation. Requires a valid project API key and model assignment.
+// @Tags ollama
+// @Accept json
+// @Produce json
+// @Param X-API-Key header string true "Project API Key"
+// @Param request body models.OllamaRequest true "Ollama request"
+// @Success 200 {object} models.OllamaResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /api/ollama/generate [post]
+func OllamaGenerate(c *fiber.Ctx) error {
+	req, rerr := parseGenerateRequest(c)
+	if rerr != nil {
+		return rerr.send(c)
 	}
 
-	if !modelAssigned {
-		return c.Status(fiber.StatusForbidden).JSON(models.ErrorResponse{
-			Error:   "Model not available",
-			Message: fmt.Sprintf("Model '%s' is not assigned to this project", req.Model),
-		})
+	project, rerr := authorizeProjectModel(c, apikeys.ScopeGenerate, req.Model)
+	if rerr != nil {
+		return rerr.send(c)
 	}
 
-	// Forward request to Ollama
-	ollamaURL := os.Getenv("OLLAMA_BASE_URL")
-	if ollamaURL == "" {
-		ollamaURL = "http://localhost:11434"
+	if rerr := applyGenerateSettings(req, project); rerr != nil {
+		return rerr.send(c)
 	}
 
-	requestBody, err := json.Marshal(req)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
-			Error:   "Failed to marshal request",
-			Message: err.Error(),
-		})
+	inf, rerr := beginInference(c, project, req.Model)
+	if rerr != nil {
+		return rerr.send(c)
 	}
 
-	log.Printf("Sending request to Ollama: %s", string(requestBody))
+	if req.Stream {
+		stream, rerr := inf.generateStream(req)
+		if rerr != nil {
+			return rerr.send(c)
+		}
+		return proxyStream(c, stream)
+	}
 
-	client := &http.Client{
-		Timeout: 300 * time.Second, // 5 minutes timeout for long-running requests
+	resp, rerr := inf.generate(req)
+	if rerr != nil {
+		return rerr.send(c)
 	}
+	return c.JSON(resp)
+}
 
-	log.Printf("Attempting to connect to Ollama at: %s", fmt.Sprintf("%s/api/generate", ollamaURL))
+// validateChatMessages checks that a conversation is not empty and only
+// uses the roles Ollama knows
+func validateChatMessages(messages []models.ChatMessage) *requestError {
+	if len(messages) == 0 {
+		return &requestError{fiber.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: "At least one message is required",
+		}}
+	}
 
-	// Use a raw request so we can stream the response back to the client if requested
-	reqHttp, err := http.NewRequest("POST", fmt.Sprintf("%s/api/generate", ollamaURL), bytes.NewBuffer(requestBody))
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
-			Error:   "Failed to create request",
-			Message: err.Error(),
-		})
+	for i, msg := range messages {
+		switch msg.Role {
+		case "system", "user", "assistant", "tool":
+		default:
+			return &requestError{fiber.StatusBadRequest, models.ErrorResponse{
+				Error:   "Invalid request",
+				Message: fmt.Sprintf("Message %d has unsupported role '%s'", i, msg.Role),
+			}}
+		}
 	}
-	reqHttp.Header.Set("Content-Type", "application/json")
+	return nil
+}
 
-	resp, err := client.Do(reqHttp)
-	if err != nil {
-		log.Printf("Connection error to Ollama: %v", err)
-		return c.Status(fiber.StatusBadGateway).JSON(models.ErrorResponse{
-			Error:   "Failed to connect to Ollama",
+// OllamaChat godoc
+// @Summary Chat using Ollama
+// @Description Send a multi-turn conversation to Ollama. Requires a valid project API key and model assignment.
+// @Tags ollama
+// @Accept json
+// @Produce json
+// @Param X-API-Key header string true "Project API Key"
+// @Param request body models.OllamaChatRequest true "Ollama chat request"
+// @Success 200 {object} models.OllamaChatResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /api/ollama/chat [post]
+func OllamaChat(c *fiber.Ctx) error {
+	var req models.OllamaChatRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "Invalid request",
 			Message: err.Error(),
 		})
 	}
 
-	// If streaming requested, proxy response body as a stream back to the client
+	if rerr := validateChatMessages(req.Messages); rerr != nil {
+		return rerr.send(c)
+	}
+
+	req.KeepAlive = normalizeKeepAlive(req.KeepAlive)
+
+	project, rerr := authorizeProjectModel(c, apikeys.ScopeChat, req.Model)
+	if rerr != nil {
+		return rerr.send(c)
+	}
+
+	if rerr := applyChatSettings(&req, project); rerr != nil {
+		return rerr.send(c)
+	}
+
+	inf, rerr := beginInference(c, project, req.Model)
+	if rerr != nil {
+		return rerr.send(c)
+	}
+
 	if req.Stream {
-		// Pass through content-type from Ollama (e.g., text/event-stream or application/octet-stream)
-		ct := resp.Header.Get("Content-Type")
-		if ct == "" {
-			ct = "text/event-stream"
+		stream, rerr := inf.chatStream(&req)
+		if rerr != nil {
+			return rerr.send(c)
 		}
-		c.Set("Content-Type", ct)
+		return proxyStream(c, stream)
+	}
 
-		// Do not close resp.Body here; SendStream will read from it
-		return c.SendStream(resp.Body)
+	resp, rerr := inf.chat(&req)
+	if rerr != nil {
+		return rerr.send(c)
 	}
+	return c.JSON(resp)
+}
+
+// embedInputs normalizes an embedding input (string or array of strings)
+func embedInputs(input interface{}) ([]string, error) {
+	switch v := input.(type) {
+	case string:
+		if v == "" {
+			return nil, fmt.Errorf("input must not be empty")
+		}
+		return []string{v}, nil
+	case []interface{}:
+		if len(v) == 0 {
+			return nil, fmt.Errorf("input must not be empty")
+		}
+		inputs := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("input must be a string or an array of strings")
+			}
+			inputs = append(inputs, s)
+		}
+		return inputs, nil
+	default:
+		return nil, fmt.Errorf("input must be a string or an array of strings")
+	}
+}
 
-	// Non-streaming: read full body and return JSON or raw response
-	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
+// OllamaEmbed godoc
+// @Summary Generate embeddings using Ollama
+// @Description Generate embeddings for one or more inputs. Requires a valid project API key and model assignment.
+// @Tags ollama
+// @Accept json
+// @Produce json
+// @Param X-API-Key header string true "Project API Key"
+// @Param request body models.OllamaEmbedRequest true "Ollama embed request"
+// @Success 200 {object} models.OllamaEmbedResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /api/ollama/embed [post]
+func OllamaEmbed(c *fiber.Ctx) error {
+	var req models.OllamaEmbedRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: err.Error(),
+		})
+	}
+
+	inputs, err := embedInputs(req.Input)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
-			Error:   "Failed to read response",
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "Invalid request",
 			Message: err.Error(),
 		})
 	}
+	req.Input = inputs
+	req.KeepAlive = normalizeKeepAlive(req.KeepAlive)
 
-	log.Printf("Received response from Ollama: %s", string(body))
+	project, rerr := authorizeProjectModel(c, apikeys.ScopeEmbed, req.Model)
+	if rerr != nil {
+		return rerr.send(c)
+	}
 
-	// If Ollama returned an error
-	if resp.StatusCode != http.StatusOK {
-		return c.Status(resp.StatusCode).JSON(models.ErrorResponse{
-			Error:   "Ollama API error",
-			Message: string(body),
-		})
+	if rerr := applyEmbedSettings(&req, inputs, project); rerr != nil {
+		return rerr.send(c)
 	}
 
-	// Parse and return response
-	var ollamaResp models.OllamaResponse
-	if err := json.Unmarshal(body, &ollamaResp); err != nil {
-		// If we can't parse it, just return the raw response
-		c.Set("Content-Type", "application/json")
-		return c.Send(body)
+	inf, rerr := beginInference(c, project, req.Model)
+	if rerr != nil {
+		return rerr.send(c)
 	}
 
-	return c.JSON(ollamaResp)
+	resp, rerr := inf.embed(&req)
+	if rerr != nil {
+		return rerr.send(c)
+	}
+	return c.JSON(resp)
+}
+
+// modelActionError returns the outcome of a model action run on several
+// backends. Backends that do not have the model are only an error when none
+// of them has it; any other failure is returned as is.
+func modelActionError(errs []error) error {
+	var notFound error
+	done := false
+	for _, err := range errs {
+		var statusErr *ollama.StatusError
+		switch {
+		case err == nil:
+			done = true
+		case errors.As(err, &statusErr) && statusErr.StatusCode == fiber.StatusNotFound:
+			notFound = err
+		default:
+			return err
+		}
+	}
+	if done {
+		return nil
+	}
+	return notFound
+}
+
+// firstError returns the first error when every backend failed, so results
+// of the others can still be returned
+func firstError(errs []error) error {
+	for _, err := range errs {
+		if err == nil {
+			return nil
+		}
+	}
+	return errs[0]
 }
 
 // ListOllamaModels godoc
 // @Summary List available Ollama models
-// @Description Get a list of all models available on the Ollama server
+// @Description Get the models installed on the healthy Ollama backends, or on the one named by backend. A model installed on several backends is listed once.
 // @Tags ollama
 // @Security BearerAuth
 // @Produce json
-// @Success 200 {object} map[string]interface{}
+// @Param backend query string false "Name of the backend to list"
+// @Success 200 {object} ollama.ListResponse
+// @Failure 404 {object} models.ErrorResponse
 // @Failure 502 {object} models.ErrorResponse
 // @Router /api/ollama/models [get]
 func ListOllamaModels(c *fiber.Ctx) error {
-	ollamaURL := os.Getenv("OLLAMA_BASE_URL")
-	if ollamaURL == "" {
-		ollamaURL = "http://localhost:11434"
-	}
-
-	client := &http.Client{
-		Timeout: 30 * time.Second,
+	targets, rerr := adminTargets(c)
+	if rerr != nil {
+		return rerr.send(c)
 	}
 
-	log.Printf("Attempting to connect to Ollama at: %s", fmt.Sprintf("%s/api/tags", ollamaURL))
+	ctx, cancel := context.WithTimeout(context.Background(), adminTimeout)
+	defer cancel()
 
-	resp, err := client.Get(fmt.Sprintf("%s/api/tags", ollamaURL))
-	if err != nil {
-		log.Printf("Connection error to Ollama: %v", err)
-		return c.Status(fiber.StatusBadGateway).JSON(models.ErrorResponse{
-			Error:   "Failed to connect to Ollama",
-			Message: err.Error(),
-		})
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
-			Error:   "Failed to read response",
-			Message: err.Error(),
-		})
+	lists := make([]*ollama.ListResponse, len(targets))
+	errs := fanOut(targets, func(i int, client OllamaClient) (err error) {
+		lists[i], err = client.Tags(ctx)
+		return err
+	})
+	if err := firstError(errs); err != nil {
+		log.Printf("Failed to list Ollama models: %v", err)
+		return ollamaError(err).send(c)
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return c.Status(resp.StatusCode).JSON(models.ErrorResponse{
-			Error:   "Ollama API error",
-			Message: string(body),
-		})
+	merged := &ollama.ListResponse{Models: []ollama.ListModel{}}
+	seen := map[string]bool{}
+	for _, list := range lists {
+		if list == nil {
+			continue
+		}
+		for _, model := range list.Models {
+			if !seen[model.Name] {
+				seen[model.Name] = true
+				merged.Models = append(merged.Models, model)
+			}
+		}
 	}
+	return c.JSON(merged)
+}
 
-	c.Set("Content-Type", "application/json")
-	return c.Send(body)
+// pullProgress is a progress update of a pull on one backend
+type pullProgress struct {
+	Backend string `json:"backend"`
+	ollama.ProgressResponse
+	Error string `json:"error,omitempty"`
 }
 
 // PullOllamaModel godoc
 // @Summary Pull an Ollama model
-// @Description Download and install a model from the Ollama library
+// @Description Download and install a model from the Ollama library on the healthy backends, or on the one named by backend. Progress is streamed as newline-delimited JSON, each update naming its backend.
 // @Tags ollama
 // @Accept json
 // @Produce json
 // @Param request body map[string]string true "Model pull request"
-// @Success 200 {object} map[string]interface{}
+// @Param backend query string false "Name of the backend to pull on"
+// @Success 200 {object} ollama.ProgressResponse
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
 // @Failure 502 {object} models.ErrorResponse
 // @Router /api/ollama/models/pull [post]
 func PullOllamaModel(c *fiber.Ctx) error {
-	ollamaURL := os.Getenv("OLLAMA_BASE_URL")
-	if ollamaURL == "" {
-		ollamaURL = "http://localhost:11434"
-	}
-
 	var req map[string]string
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
@@ -294,42 +591,74 @@ func PullOllamaModel(c *fiber.Ctx) error {
 			Message: "Model name is required",
 		})
 	}
-
-	requestBody := map[string]string{"name": modelName}
-	jsonBody, err := json.Marshal(requestBody)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
-			Error:   "Failed to marshal request",
-			Message: err.Error(),
-		})
-	}
-
-	client := &http.Client{
-		Timeout: 300 * time.Second, // Long timeout for model downloads
+	targets, rerr := adminTargets(c)
+	if rerr != nil {
+		return rerr.send(c)
 	}
 
-	log.Printf("Pulling Ollama model: %s from %s", modelName, fmt.Sprintf("%s/api/pull", ollamaURL))
+	log.Printf("Pulling Ollama model %s on %d backends", modelName, len(targets))
 
-	reqURL := fmt.Sprintf("%s/api/pull", ollamaURL)
-	resp, err := client.Post(reqURL, "application/json", bytes.NewBuffer(jsonBody))
-	if err != nil {
-		log.Printf("Connection error pulling model: %v", err)
-		return c.Status(fiber.StatusBadGateway).JSON(models.ErrorResponse{
-			Error:   "Failed to connect to Ollama",
-			Message: err.Error(),
-		})
+	ctx, cancel := requestContext(c, requestTimeout)
+	streams := make([]*ollama.Stream[ollama.ProgressResponse], len(targets))
+	errs := fanOut(targets, func(i int, client OllamaClient) (err error) {
+		streams[i], err = client.Pull(ctx, &ollama.PullRequest{Model: modelName})
+		return err
+	})
+	if err := firstError(errs); err != nil {
+		cancel(nil)
+		log.Printf("Failed to pull model: %v", err)
+		return ollamaError(err).send(c)
+	}
+
+	// Each backend sends its updates to the writer, which streams them as
+	// newline-delimited JSON
+	updates := make(chan pullProgress)
+	var wg sync.WaitGroup
+	for i, backend := range targets {
+		if errs[i] != nil {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				updates <- pullProgress{Backend: backend.Name, Error: errs[i].Error()}
+			}()
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stream := streams[i]
+			defer stream.Close()
+			for stream.Next() {
+				updates <- pullProgress{Backend: backend.Name, ProgressResponse: *stream.Current()}
+			}
+			if err := stream.Err(); err != nil && !clientGone(ctx) {
+				updates <- pullProgress{Backend: backend.Name, Error: err.Error()}
+			}
+		}()
 	}
-	defer resp.Body.Close()
+	go func() {
+		wg.Wait()
+		close(updates)
+	}()
 
-	// Stream progress as newline-delimited JSON
 	c.Set("Content-Type", "text/event-stream")
 	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
-		scanner := bufio.NewScanner(resp.Body)
-		for scanner.Scan() {
-			line := scanner.Bytes()
-			w.Write(line)
+		defer cancel(nil)
+
+		// Updates are drained even after the client left, so the pulls end
+		for update := range updates {
+			if clientGone(ctx) {
+				continue
+			}
+			data, _ := json.Marshal(update)
+			w.Write(data)
 			w.Write([]byte("\n"))
-			w.Flush()
+			if err := w.Flush(); err != nil {
+				cancel(errClientGone)
+			}
+		}
+		if clientGone(ctx) {
+			log.Printf("Pull of %s cancelled by the client", modelName)
 		}
 	})
 	return nil
@@ -337,21 +666,18 @@ func PullOllamaModel(c *fiber.Ctx) error {
 
 // DeleteOllamaModel godoc
 // @Summary Delete an Ollama model
-// @Description Remove a model from the local Ollama instance
+// @Description Remove a model from the healthy Ollama backends, or from the one named by backend. Backends that do not have the model are skipped.
 // @Tags ollama
 // @Accept json
 // @Produce json
 // @Param request body map[string]string true "Model delete request"
-// @Success 200 {object} map[string]interface{}
+// @Param backend query string false "Name of the backend to delete from"
+// @Success 200 {object} models.SuccessResponse
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
 // @Failure 502 {object} models.ErrorResponse
 // @Router /api/ollama/models/delete [delete]
 func DeleteOllamaModel(c *fiber.Ctx) error {
-	ollamaURL := os.Getenv("OLLAMA_BASE_URL")
-	if ollamaURL == "" {
-		ollamaURL = "http://localhost:11434"
-	}
-
 	var req map[string]string
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
@@ -367,105 +693,118 @@ func DeleteOllamaModel(c *fiber.Ctx) error {
 			Message: "Model name is required",
 		})
 	}
-
-	requestBody := map[string]string{"name": modelName}
-	jsonBody, err := json.Marshal(requestBody)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
-			Error:   "Failed to marshal request",
-			Message: err.Error(),
-		})
+	targets, rerr := adminTargets(c)
+	if rerr != nil {
+		return rerr.send(c)
 	}
 
-	client := &http.Client{
-		Timeout: 30 * time.Second,
-	}
+	log.Printf("Deleting Ollama model %s from %d backends", modelName, len(targets))
 
-	log.Printf("Deleting Ollama model: %s from %s", modelName, fmt.Sprintf("%s/api/delete", ollamaURL))
+	ctx, cancel := context.WithTimeout(context.Background(), adminTimeout)
+	defer cancel()
 
-	reqHttp, err := http.NewRequest("DELETE", fmt.Sprintf("%s/api/delete", ollamaURL), bytes.NewBuffer(jsonBody))
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
-			Error:   "Failed to create request",
-			Message: err.Error(),
-		})
+	errs := fanOut(targets, func(_ int, client OllamaClient) error {
+		return client.Delete(ctx, &ollama.DeleteRequest{Model: modelName})
+	})
+	if err := modelActionError(errs); err != nil {
+		log.Printf("Failed to delete model: %v", err)
+		return ollamaError(err).send(c)
 	}
-	reqHttp.Header.Set("Content-Type", "application/json")
 
-	resp, err := client.Do(reqHttp)
-	if err != nil {
-		log.Printf("Connection error deleting model: %v", err)
-		return c.Status(fiber.StatusBadGateway).JSON(models.ErrorResponse{
-			Error:   "Failed to connect to Ollama",
+	return c.JSON(models.SuccessResponse{
+		Message: "Model deleted successfully",
+	})
+}
+
+// UnloadOllamaModel godoc
+// @Summary Unload an Ollama model
+// @Description Unload a model from the memory of the healthy Ollama backends, or of the one named by backend, right away, keeping it installed
+// @Tags ollama
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body map[string]string true "Model unload request"
+// @Param backend query string false "Name of the backend to unload from"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 502 {object} models.ErrorResponse
+// @Router /api/ollama/models/unload [post]
+func UnloadOllamaModel(c *fiber.Ctx) error {
+	var req map[string]string
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "Invalid request",
 			Message: err.Error(),
 		})
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
-			Error:   "Failed to read response",
-			Message: err.Error(),
+	modelName, exists := req["name"]
+	if !exists || modelName == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: "Model name is required",
 		})
 	}
+	targets, rerr := adminTargets(c)
+	if rerr != nil {
+		return rerr.send(c)
+	}
 
-	if resp.StatusCode != http.StatusOK {
-		return c.Status(resp.StatusCode).JSON(models.ErrorResponse{
-			Error:   "Ollama API error",
-			Message: string(body),
-		})
+	log.Printf("Unloading Ollama model %s on %d backends", modelName, len(targets))
+
+	ctx, cancel := context.WithTimeout(context.Background(), adminTimeout)
+	defer cancel()
+
+	// A request without a prompt and a keep_alive of 0 makes Ollama unload the model
+	errs := fanOut(targets, func(_ int, client OllamaClient) error {
+		_, err := client.Generate(ctx, &models.OllamaRequest{Model: modelName, KeepAlive: 0})
+		return err
+	})
+	if err := modelActionError(errs); err != nil {
+		log.Printf("Failed to unload model: %v", err)
+		return ollamaError(err).send(c)
 	}
 
-	c.Set("Content-Type", "application/json")
-	return c.Send(body)
+	return c.JSON(models.SuccessResponse{
+		Message: "Model unloaded successfully",
+	})
 }
 
 // ListRunningOllamaModels godoc
 // @Summary List running Ollama models
-// @Description Get a list of currently loaded/running models
+// @Description Get the models loaded on the healthy Ollama backends, or on the one named by backend. A model loaded on several backends is listed for each.
 // @Tags ollama
 // @Produce json
-// @Success 200 {object} map[string]interface{}
+// @Param backend query string false "Name of the backend to list"
+// @Success 200 {object} ollama.ProcessResponse
+// @Failure 404 {object} models.ErrorResponse
 // @Failure 502 {object} models.ErrorResponse
 // @Router /api/ollama/models/running [get]
 func ListRunningOllamaModels(c *fiber.Ctx) error {
-	ollamaURL := os.Getenv("OLLAMA_BASE_URL")
-	if ollamaURL == "" {
-		ollamaURL = "http://localhost:11434"
-	}
-
-	client := &http.Client{
-		Timeout: 30 * time.Second,
+	targets, rerr := adminTargets(c)
+	if rerr != nil {
+		return rerr.send(c)
 	}
 
-	log.Printf("Getting running models from: %s", fmt.Sprintf("%s/api/ps", ollamaURL))
-
-	resp, err := client.Get(fmt.Sprintf("%s/api/ps", ollamaURL))
-	if err != nil {
-		log.Printf("Connection error getting running models: %v", err)
-		return c.Status(fiber.StatusBadGateway).JSON(models.ErrorResponse{
-			Error:   "Failed to connect to Ollama",
-			Message: err.Error(),
-		})
-	}
-	defer resp.Body.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), adminTimeout)
+	defer cancel()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
-			Error:   "Failed to read response",
-			Message: err.Error(),
-		})
+	running := make([]*ollama.ProcessResponse, len(targets))
+	errs := fanOut(targets, func(i int, client OllamaClient) (err error) {
+		running[i], err = client.Ps(ctx)
+		return err
+	})
+	if err := firstError(errs); err != nil {
+		log.Printf("Failed to get running models: %v", err)
+		return ollamaError(err).send(c)
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return c.Status(resp.StatusCode).JSON(models.ErrorResponse{
-			Error:   "Ollama API error",
-			Message: string(body),
-		})
+	merged := &ollama.ProcessResponse{Models: []ollama.ProcessModel{}}
+	for _, r := range running {
+		if r != nil {
+			merged.Models = append(merged.Models, r.Models...)
+		}
 	}
-
-	c.Set("Content-Type", "application/json")
-	return c.Send(body)
+	return c.JSON(merged)
 }
@@ -0,0 +1,713 @@
+package handlers
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ollama-web-api/internal/apikeys"
+	"github.com/ollama-web-api/internal/models"
+	"github.com/ollama-web-api/internal/ollama"
+)
+
+// sendOpenAIError writes an error in the OpenAI error response format
+func sendOpenAIError(c *fiber.Ctx, status int, message string) error {
+	errType := "invalid_request_error"
+	switch {
+	case status == fiber.StatusUnauthorized:
+		errType = "authentication_error"
+	case status == fiber.StatusForbidden:
+		errType = "permission_error"
+	case status == fiber.StatusTooManyRequests:
+		errType = "rate_limit_error"
+	case status >= fiber.StatusInternalServerError:
+		errType = "api_error"
+	}
+
+	return c.Status(status).JSON(models.OpenAIErrorResponse{
+		Error: models.OpenAIErrorDetail{
+			Message: message,
+			Type:    errType,
+		},
+	})
+}
+
+// sendOpenAI writes the request error in the OpenAI error response format
+func (e *requestError) sendOpenAI(c *fiber.Ctx) error {
+	message := e.Message
+	if message == "" {
+		message = e.Error
+	}
+	if e.Code == "quota_exceeded" {
+		// Matches the error OpenAI returns when a billing quota is used up
+		return c.Status(e.Status).JSON(models.OpenAIErrorResponse{
+			Error: models.OpenAIErrorDetail{
+				Message: message,
+				Type:    "insufficient_quota",
+				Code:    "insufficient_quota",
+			},
+		})
+	}
+	return sendOpenAIError(c, e.Status, message)
+}
+
+// newCompletionID generates a random OpenAI-style completion ID
+func newCompletionID(prefix string) string {
+	bytes := make([]byte, 12)
+	if _, err := rand.Read(bytes); err != nil {
+		return fmt.Sprintf("%s-%d", prefix, time.Now().UnixNano())
+	}
+	return prefix + "-" + hex.EncodeToString(bytes)
+}
+
+// openAIStop normalizes the OpenAI stop field (string or array of strings)
+func openAIStop(stop interface{}) ([]string, error) {
+	switch v := stop.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{v}, nil
+	case []interface{}:
+		stops := make([]string, 0, len(v))
+		for _, s := range v {
+			str, ok := s.(string)
+			if !ok {
+				return nil, fmt.Errorf("stop must be a string or an array of strings")
+			}
+			stops = append(stops, str)
+		}
+		return stops, nil
+	default:
+		return nil, fmt.Errorf("stop must be a string or an array of strings")
+	}
+}
+
+// openAIOptions maps OpenAI sampling parameters to Ollama options
+func openAIOptions(temperature, topP *float64, maxTokens *int, stop interface{}, seed *int, presencePenalty, frequencyPenalty *float64) (*models.GenerateOptions, error) {
+	stops, err := openAIStop(stop)
+	if err != nil {
+		return nil, err
+	}
+
+	if temperature == nil && topP == nil && maxTokens == nil && seed == nil &&
+		presencePenalty == nil && frequencyPenalty == nil && len(stops) == 0 {
+		return nil, nil
+	}
+
+	return &models.GenerateOptions{
+		Temperature:      temperature,
+		TopP:             topP,
+		NumPredict:       maxTokens,
+		Seed:             seed,
+		PresencePenalty:  presencePenalty,
+		FrequencyPenalty: frequencyPenalty,
+		Stop:             stops,
+	}, nil
+}
+
+// openAIFormat maps the OpenAI response_format to the Ollama format field
+func openAIFormat(rf *models.OpenAIResponseFormat) interface{} {
+	if rf == nil {
+		return nil
+	}
+	switch rf.Type {
+	case "json_object":
+		return "json"
+	case "json_schema":
+		if schema, ok := rf.JSONSchema.(map[string]interface{}); ok {
+			if inner, ok := schema["schema"]; ok {
+				return inner
+			}
+		}
+		return "json"
+	default:
+		return nil
+	}
+}
+
+// openAIContent flattens OpenAI message content into text and base64 images
+func openAIContent(content interface{}) (string, []string, error) {
+	switch v := content.(type) {
+	case nil:
+		return "", nil, nil
+	case string:
+		return v, nil, nil
+	case []interface{}:
+		var text []string
+		var images []string
+		for _, p := range v {
+			part, ok := p.(map[string]interface{})
+			if !ok {
+				return "", nil, fmt.Errorf("invalid content part")
+			}
+			switch part["type"] {
+			case "text":
+				if t, ok := part["text"].(string); ok {
+					text = append(text, t)
+				}
+			case "image_url":
+				var url string
+				switch iu := part["image_url"].(type) {
+				case string:
+					url = iu
+				case map[string]interface{}:
+					url, _ = iu["url"].(string)
+				}
+				idx := strings.Index(url, ";base64,")
+				if !strings.HasPrefix(url, "data:") || idx < 0 {
+					return "", nil, fmt.Errorf("only base64 data URLs are supported for image_url")
+				}
+				images = append(images, url[idx+len(";base64,"):])
+			default:
+				return "", nil, fmt.Errorf("unsupported content part type '%v'", part["type"])
+			}
+		}
+		return strings.Join(text, "\n"), images, nil
+	default:
+		return "", nil, fmt.Errorf("content must be a string or an array of content parts")
+	}
+}
+
+// toOllamaToolCalls converts OpenAI tool calls (JSON string arguments) to
+// Ollama tool calls (object arguments)
+func toOllamaToolCalls(calls []interface{}) []interface{} {
+	converted := make([]interface{}, 0, len(calls))
+	for _, call := range calls {
+		m, ok := call.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		fn, _ := m["function"].(map[string]interface{})
+		if fn == nil {
+			continue
+		}
+		args := fn["arguments"]
+		if s, ok := args.(string); ok {
+			var parsed interface{}
+			if err := json.Unmarshal([]byte(s), &parsed); err == nil {
+				args = parsed
+			}
+		}
+		converted = append(converted, map[string]interface{}{
+			"function": map[string]interface{}{
+				"name":      fn["name"],
+				"arguments": args,
+			},
+		})
+	}
+	return converted
+}
+
+// toOpenAIToolCalls converts Ollama tool calls to the OpenAI format. Their
+// indexes start at first, the number of calls sent before in a stream.
+func toOpenAIToolCalls(calls []interface{}, first int) []interface{} {
+	converted := make([]interface{}, 0, len(calls))
+	for _, call := range calls {
+		m, ok := call.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		fn, _ := m["function"].(map[string]interface{})
+		if fn == nil {
+			continue
+		}
+		args, err := json.Marshal(fn["arguments"])
+		if err != nil {
+			args = []byte("{}")
+		}
+		converted = append(converted, map[string]interface{}{
+			"index": first + len(converted),
+			"id":    newCompletionID("call"),
+			"type":  "function",
+			"function": map[string]interface{}{
+				"name":      fn["name"],
+				"arguments": string(args),
+			},
+		})
+	}
+	return converted
+}
+
+// toolCallNames maps the IDs of the tool calls in a message to the names of
+// their functions
+func toolCallNames(calls []interface{}, names map[string]string) {
+	for _, call := range calls {
+		m, _ := call.(map[string]interface{})
+		fn, _ := m["function"].(map[string]interface{})
+		id, _ := m["id"].(string)
+		if name, ok := fn["name"].(string); ok && id != "" {
+			names[id] = name
+		}
+	}
+}
+
+// toOllamaChatRequest translates an OpenAI chat completion request. Tool
+// messages keep the ID of the call they answer and get the name of its
+// function, which Ollama matches them by.
+func toOllamaChatRequest(req *models.OpenAIChatCompletionRequest) (*models.OllamaChatRequest, error) {
+	messages := make([]models.ChatMessage, 0, len(req.Messages))
+	callNames := map[string]string{}
+	for i, msg := range req.Messages {
+		role := msg.Role
+		if role == "developer" {
+			role = "system"
+		}
+		switch role {
+		case "system", "user", "assistant", "tool":
+		default:
+			return nil, fmt.Errorf("message %d has unsupported role '%s'", i, msg.Role)
+		}
+
+		text, images, err := openAIContent(msg.Content)
+		if err != nil {
+			return nil, fmt.Errorf("message %d: %w", i, err)
+		}
+
+		toolCallNames(msg.ToolCalls, callNames)
+		message := models.ChatMessage{
+			Role:      role,
+			Content:   text,
+			Images:    images,
+			ToolCalls: toOllamaToolCalls(msg.ToolCalls),
+		}
+		if role == "tool" {
+			message.ToolCallID = msg.ToolCallID
+			message.ToolName = callNames[msg.ToolCallID]
+			if message.ToolName == "" {
+				message.ToolName = msg.Name
+			}
+		}
+		messages = append(messages, message)
+	}
+
+	options, err := openAIOptions(req.Temperature, req.TopP, req.MaxTokens, req.Stop, req.Seed, req.PresencePenalty, req.FrequencyPenalty)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.OllamaChatRequest{
+		Model:    req.Model,
+		Messages: messages,
+		Stream:   req.Stream,
+		Tools:    req.Tools,
+		Format:   openAIFormat(req.ResponseFormat),
+		Options:  options,
+	}, nil
+}
+
+// openAIFinishReason maps the Ollama done_reason to an OpenAI finish_reason
+func openAIFinishReason(doneReason string, toolCalls bool) *string {
+	reason := "stop"
+	if doneReason == "length" {
+		reason = "length"
+	}
+	if toolCalls {
+		reason = "tool_calls"
+	}
+	return &reason
+}
+
+// openAIUsage builds an OpenAI usage object from Ollama token counts
+func openAIUsage(promptEvalCount, evalCount int) *models.OpenAIUsage {
+	return &models.OpenAIUsage{
+		PromptTokens:     promptEvalCount,
+		CompletionTokens: evalCount,
+		TotalTokens:      promptEvalCount + evalCount,
+	}
+}
+
+// streamSSE converts an Ollama stream into server-sent events. convert is
+// called for every chunk and returns the events to emit; the stream is
+// terminated with "data: [DONE]".
+func streamSSE[T any](c *fiber.Ctx, stream *inferenceStream[T], convert func(chunk *T) []interface{}) error {
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer stream.Close()
+
+		writeEvent := func(v interface{}) error {
+			data, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+				return err
+			}
+			return w.Flush()
+		}
+
+		for stream.Next() {
+			for _, ev := range convert(stream.Current()) {
+				if err := writeEvent(ev); err != nil {
+					// Client went away
+					stream.abort()
+					return
+				}
+			}
+		}
+
+		if err := stream.Err(); err != nil {
+			message := err.Error()
+			var statusErr *ollama.StatusError
+			if errors.As(err, &statusErr) {
+				message = statusErr.Message
+			}
+			writeEvent(models.OpenAIErrorResponse{
+				Error: models.OpenAIErrorDetail{Message: message, Type: "api_error"},
+			})
+		}
+
+		fmt.Fprint(w, "data: [DONE]\n\n")
+		w.Flush()
+	})
+	return nil
+}
+
+// OpenAIChatCompletions godoc
+// @Summary OpenAI-compatible chat completions
+// @Description Create a chat completion using the OpenAI request and response format. Accepts the project API key as a Bearer token or X-API-Key header.
+// @Tags openai
+// @Accept json
+// @Produce json
+// @Param Authorization header string false "Bearer <project API key>"
+// @Param request body models.OpenAIChatCompletionRequest true "Chat completion request"
+// @Success 200 {object} models.OpenAIChatCompletionResponse
+// @Failure 400 {object} models.OpenAIErrorResponse
+// @Failure 401 {object} models.OpenAIErrorResponse
+// @Failure 403 {object} models.OpenAIErrorResponse
+// @Router /v1/chat/completions [post]
+func OpenAIChatCompletions(c *fiber.Ctx) error {
+	var req models.OpenAIChatCompletionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return sendOpenAIError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	if len(req.Messages) == 0 {
+		return sendOpenAIError(c, fiber.StatusBadRequest, "At least one message is required")
+	}
+
+	ollamaReq, err := toOllamaChatRequest(&req)
+	if err != nil {
+		return sendOpenAIError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	project, rerr := authorizeProjectModel(c, apikeys.ScopeChat, req.Model)
+	if rerr != nil {
+		return rerr.sendOpenAI(c)
+	}
+
+	if rerr := applyChatSettings(ollamaReq, project); rerr != nil {
+		return rerr.sendOpenAI(c)
+	}
+
+	inf, rerr := beginInference(c, project, req.Model)
+	if rerr != nil {
+		return rerr.sendOpenAI(c)
+	}
+
+	id := newCompletionID("chatcmpl")
+	created := time.Now().Unix()
+
+	if req.Stream {
+		includeUsage := req.StreamOptions != nil && req.StreamOptions.IncludeUsage
+		stream, rerr := inf.chatStream(ollamaReq)
+		if rerr != nil {
+			return rerr.sendOpenAI(c)
+		}
+
+		first := true
+		toolCalls := 0
+		return streamSSE(c, stream, func(chunk *models.OllamaChatResponse) []interface{} {
+			delta := &models.OpenAIChatMessage{Content: chunk.Message.Content}
+			if first {
+				delta.Role = "assistant"
+				first = false
+			}
+			if len(chunk.Message.ToolCalls) > 0 {
+				delta.ToolCalls = toOpenAIToolCalls(chunk.Message.ToolCalls, toolCalls)
+				toolCalls += len(delta.ToolCalls)
+			}
+
+			choice := models.OpenAIChatChoice{Index: 0, Delta: delta}
+			if chunk.Done {
+				choice.FinishReason = openAIFinishReason(chunk.DoneReason, toolCalls > 0)
+			}
+
+			events := []interface{}{models.OpenAIChatCompletionResponse{
+				ID:      id,
+				Object:  "chat.completion.chunk",
+				Created: created,
+				Model:   req.Model,
+				Choices: []models.OpenAIChatChoice{choice},
+			}}
+			if chunk.Done && includeUsage {
+				events = append(events, models.OpenAIChatCompletionResponse{
+					ID:      id,
+					Object:  "chat.completion.chunk",
+					Created: created,
+					Model:   req.Model,
+					Choices: []models.OpenAIChatChoice{},
+					Usage:   openAIUsage(chunk.PromptEvalCount, chunk.EvalCount),
+				})
+			}
+			return events
+		})
+	}
+
+	ollamaResp, rerr := inf.chat(ollamaReq)
+	if rerr != nil {
+		return rerr.sendOpenAI(c)
+	}
+
+	message := &models.OpenAIChatMessage{
+		Role:    "assistant",
+		Content: ollamaResp.Message.Content,
+	}
+	if len(ollamaResp.Message.ToolCalls) > 0 {
+		message.ToolCalls = toOpenAIToolCalls(ollamaResp.Message.ToolCalls, 0)
+	}
+
+	return c.JSON(models.OpenAIChatCompletionResponse{
+		ID:      id,
+		Object:  "chat.completion",
+		Created: created,
+		Model:   req.Model,
+		Choices: []models.OpenAIChatChoice{{
+			Index:        0,
+			Message:      message,
+			FinishReason: openAIFinishReason(ollamaResp.DoneReason, len(message.ToolCalls) > 0),
+		}},
+		Usage: openAIUsage(ollamaResp.PromptEvalCount, ollamaResp.EvalCount),
+	})
+}
+
+// OpenAICompletions godoc
+// @Summary OpenAI-compatible completions
+// @Description Create a text completion using the OpenAI (legacy) completions format. Accepts the project API key as a Bearer token or X-API-Key header.
+// @Tags openai
+// @Accept json
+// @Produce json
+// @Param Authorization header string false "Bearer <project API key>"
+// @Param request body models.OpenAICompletionRequest true "Completion request"
+// @Success 200 {object} models.OpenAICompletionResponse
+// @Failure 400 {object} models.OpenAIErrorResponse
+// @Failure 401 {object} models.OpenAIErrorResponse
+// @Failure 403 {object} models.OpenAIErrorResponse
+// @Router /v1/completions [post]
+func OpenAICompletions(c *fiber.Ctx) error {
+	var req models.OpenAICompletionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return sendOpenAIError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	var prompt string
+	switch p := req.Prompt.(type) {
+	case string:
+		prompt = p
+	case []interface{}:
+		if len(p) != 1 {
+			return sendOpenAIError(c, fiber.StatusBadRequest, "Only a single prompt is supported per request")
+		}
+		s, ok := p[0].(string)
+		if !ok {
+			return sendOpenAIError(c, fiber.StatusBadRequest, "prompt must be a string")
+		}
+		prompt = s
+	default:
+		return sendOpenAIError(c, fiber.StatusBadRequest, "prompt must be a string")
+	}
+
+	options, err := openAIOptions(req.Temperature, req.TopP, req.MaxTokens, req.Stop, req.Seed, req.PresencePenalty, req.FrequencyPenalty)
+	if err != nil {
+		return sendOpenAIError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	project, rerr := authorizeProjectModel(c, apikeys.ScopeGenerate, req.Model)
+	if rerr != nil {
+		return rerr.sendOpenAI(c)
+	}
+
+	ollamaReq := models.OllamaRequest{
+		Model:   req.Model,
+		Prompt:  prompt,
+		Suffix:  req.Suffix,
+		Stream:  req.Stream,
+		Options: options,
+	}
+	if rerr := applyGenerateSettings(&ollamaReq, project); rerr != nil {
+		return rerr.sendOpenAI(c)
+	}
+
+	inf, rerr := beginInference(c, project, req.Model)
+	if rerr != nil {
+		return rerr.sendOpenAI(c)
+	}
+
+	id := newCompletionID("cmpl")
+	created := time.Now().Unix()
+
+	if req.Stream {
+		includeUsage := req.StreamOptions != nil && req.StreamOptions.IncludeUsage
+		stream, rerr := inf.generateStream(&ollamaReq)
+		if rerr != nil {
+			return rerr.sendOpenAI(c)
+		}
+
+		return streamSSE(c, stream, func(chunk *models.OllamaResponse) []interface{} {
+			choice := models.OpenAICompletionChoice{Index: 0, Text: chunk.Response}
+			if chunk.Done {
+				choice.FinishReason = openAIFinishReason(chunk.DoneReason, false)
+			}
+
+			events := []interface{}{models.OpenAICompletionResponse{
+				ID:      id,
+				Object:  "text_completion",
+				Created: created,
+				Model:   req.Model,
+				Choices: []models.OpenAICompletionChoice{choice},
+			}}
+			if chunk.Done && includeUsage {
+				events = append(events, models.OpenAICompletionResponse{
+					ID:      id,
+					Object:  "text_completion",
+					Created: created,
+					Model:   req.Model,
+					Choices: []models.OpenAICompletionChoice{},
+					Usage:   openAIUsage(chunk.PromptEvalCount, chunk.EvalCount),
+				})
+			}
+			return events
+		})
+	}
+
+	ollamaResp, rerr := inf.generate(&ollamaReq)
+	if rerr != nil {
+		return rerr.sendOpenAI(c)
+	}
+
+	return c.JSON(models.OpenAICompletionResponse{
+		ID:      id,
+		Object:  "text_completion",
+		Created: created,
+		Model:   req.Model,
+		Choices: []models.OpenAICompletionChoice{{
+			Index:        0,
+			Text:         ollamaResp.Response,
+			FinishReason: openAIFinishReason(ollamaResp.DoneReason, false),
+		}},
+		Usage: openAIUsage(ollamaResp.PromptEvalCount, ollamaResp.EvalCount),
+	})
+}
+
+// OpenAIListModels godoc
+// @Summary OpenAI-compatible model list
+// @Description List the models assigned to the calling project in the OpenAI format
+// @Tags openai
+// @Produce json
+// @Param Authorization header string false "Bearer <project API key>"
+// @Success 200 {object} models.OpenAIModelList
+// @Failure 401 {object} models.OpenAIErrorResponse
+// @Failure 403 {object} models.OpenAIErrorResponse
+// @Router /v1/models [get]
+func OpenAIListModels(c *fiber.Ctx) error {
+	project, rerr := authorizeProject(c, apikeys.ScopeModelsRead)
+	if rerr != nil {
+		return rerr.sendOpenAI(c)
+	}
+
+	list := models.OpenAIModelList{
+		Object: "list",
+		Data:   make([]models.OpenAIModel, 0, len(project.Models)),
+	}
+	for _, pm := range project.Models {
+		if checkKeyModel(c, pm.ModelName) != nil {
+			continue
+		}
+		list.Data = append(list.Data, models.OpenAIModel{
+			ID:      pm.ModelName,
+			Object:  "model",
+			Created: pm.CreatedAt.Unix(),
+			OwnedBy: "ollama",
+		})
+	}
+
+	return c.JSON(list)
+}
+
+// OpenAIEmbeddings godoc
+// @Summary OpenAI-compatible embeddings
+// @Description Create embeddings using the OpenAI request and response format. Accepts the project API key as a Bearer token or X-API-Key header.
+// @Tags openai
+// @Accept json
+// @Produce json
+// @Param Authorization header string false "Bearer <project API key>"
+// @Param request body models.OpenAIEmbeddingRequest true "Embedding request"
+// @Success 200 {object} models.OpenAIEmbeddingResponse
+// @Failure 400 {object} models.OpenAIErrorResponse
+// @Failure 401 {object} models.OpenAIErrorResponse
+// @Failure 403 {object} models.OpenAIErrorResponse
+// @Router /v1/embeddings [post]
+func OpenAIEmbeddings(c *fiber.Ctx) error {
+	var req models.OpenAIEmbeddingRequest
+	if err := c.BodyParser(&req); err != nil {
+		return sendOpenAIError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	if req.EncodingFormat != "" && req.EncodingFormat != "float" {
+		return sendOpenAIError(c, fiber.StatusBadRequest, "Only the 'float' encoding_format is supported")
+	}
+
+	inputs, err := embedInputs(req.Input)
+	if err != nil {
+		return sendOpenAIError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	project, rerr := authorizeProjectModel(c, apikeys.ScopeEmbed, req.Model)
+	if rerr != nil {
+		return rerr.sendOpenAI(c)
+	}
+
+	ollamaReq := models.OllamaEmbedRequest{
+		Model:      req.Model,
+		Input:      inputs,
+		Dimensions: req.Dimensions,
+	}
+	if rerr := applyEmbedSettings(&ollamaReq, inputs, project); rerr != nil {
+		return rerr.sendOpenAI(c)
+	}
+
+	inf, rerr := beginInference(c, project, req.Model)
+	if rerr != nil {
+		return rerr.sendOpenAI(c)
+	}
+
+	ollamaResp, rerr := inf.embed(&ollamaReq)
+	if rerr != nil {
+		return rerr.sendOpenAI(c)
+	}
+
+	data := make([]models.OpenAIEmbedding, 0, len(ollamaResp.Embeddings))
+	for i, embedding := range ollamaResp.Embeddings {
+		data = append(data, models.OpenAIEmbedding{
+			Object:    "embedding",
+			Index:     i,
+			Embedding: embedding,
+		})
+	}
+
+	return c.JSON(models.OpenAIEmbeddingResponse{
+		Object: "list",
+		Data:   data,
+		Model:  req.Model,
+		Usage: models.OpenAIUsage{
+			PromptTokens: ollamaResp.PromptEvalCount,
+			TotalTokens:  ollamaResp.PromptEvalCount,
+		},
+	})
+}
@@ -3,10 +3,14 @@ package handlers
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"fmt"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/ollama-web-api/internal/audit"
+	"github.com/ollama-web-api/internal/clientip"
 	"github.com/ollama-web-api/internal/database"
 	"github.com/ollama-web-api/internal/models"
+	"gorm.io/gorm"
 )
 
 // generateAPIKey generates a random API key
@@ -29,7 +33,7 @@ func generateAPIKey() (string, error) {
 // @Router /api/projects [get]
 func ListProjects(c *fiber.Ctx) error {
 	var projects []models.Project
-	result := database.DB.Preload("Models").Find(&projects)
+	result := database.DB.Preload("Models").Preload("APIKeys", "revoked_at IS NULL").Find(&projects)
 	if result.Error != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
 			Error:   "Failed to fetch projects",
@@ -54,7 +58,7 @@ func GetProject(c *fiber.Ctx) error {
 	id := c.Params("id")
 	var project models.Project
 
-	result := database.DB.Preload("Models").First(&project, id)
+	result := database.DB.Preload("Models").Preload("APIKeys", "revoked_at IS NULL").First(&project, id)
 	if result.Error != nil {
 		return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
 			Error:   "Project not found",
@@ -67,7 +71,7 @@ func GetProject(c *fiber.Ctx) error {
 
 // CreateProject godoc
 // @Summary Create a new project
-// @Description Create a new project with a unique API key
+// @Description Create a new project with a first API key named "default". The key is only returned in this response; afterwards only its prefix is shown. So is the webhook secret when a webhook_url is set.
 // @Tags projects
 // @Security BearerAuth
 // @Accept json
@@ -85,35 +89,114 @@ func CreateProject(c *fiber.Ctx) error {
 		})
 	}
 
-	apiKey, err := generateAPIKey()
+	if req.Settings != nil {
+		if err := validateSettings(req.Settings); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+				Error:   "Invalid settings",
+				Message: err.Error(),
+			})
+		}
+	}
+	if req.RateLimits != nil {
+		if err := validateRateLimits(req.RateLimits); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+				Error:   "Invalid rate limits",
+				Message: err.Error(),
+			})
+		}
+	}
+	if req.Quotas != nil {
+		if err := validateQuotas(req.Quotas); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+				Error:   "Invalid quotas",
+				Message: err.Error(),
+			})
+		}
+	}
+	if req.Scheduling != nil {
+		if err := validateScheduling(req.Scheduling); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+				Error:   "Invalid scheduling",
+				Message: err.Error(),
+			})
+		}
+	}
+	if req.WebhookURL != nil {
+		if err := validateWebhookURL(*req.WebhookURL); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+				Error:   "Invalid webhook",
+				Message: err.Error(),
+			})
+		}
+	}
+	var allowedCIDRs []string
+	if req.AllowedCIDRs != nil {
+		cidrs, err := clientip.Normalize(*req.AllowedCIDRs)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+				Error:   "Invalid allowed CIDRs",
+				Message: err.Error(),
+			})
+		}
+		allowedCIDRs = cidrs
+	}
+
+	project := models.Project{
+		Name:         req.Name,
+		Description:  req.Description,
+		IsActive:     true,
+		AllowedCIDRs: allowedCIDRs,
+	}
+	key, err := newAPIKey(c, "default", nil)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
 			Error:   "Failed to generate API key",
 			Message: err.Error(),
 		})
 	}
-
-	project := models.Project{
-		Name:        req.Name,
-		Description: req.Description,
-		APIKey:      apiKey,
-		IsActive:    true,
+	if req.Settings != nil {
+		project.Settings = *req.Settings
+	}
+	if req.RateLimits != nil {
+		project.RateLimits = *req.RateLimits
+	}
+	if req.Quotas != nil {
+		project.Quotas = *req.Quotas
+	}
+	if req.Scheduling != nil {
+		project.Scheduling = *req.Scheduling
+	}
+	if req.WebhookURL != nil {
+		if err := setWebhookURL(&project, *req.WebhookURL); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+				Error:   "Failed to generate webhook secret",
+				Message: err.Error(),
+			})
+		}
 	}
 
-	result := database.DB.Create(&project)
-	if result.Error != nil {
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&project).Error; err != nil {
+			return err
+		}
+		key.ProjectID = project.ID
+		return tx.Create(key).Error
+	})
+	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
 			Error:   "Failed to create project",
-			Message: result.Error.Error(),
+			Message: err.Error(),
 		})
 	}
 
+	project.APIKey = key.Key
+	project.APIKeys = []models.APIKey{*key}
 	return c.Status(fiber.StatusCreated).JSON(project)
 }
 
 // UpdateProject godoc
 // @Summary Update a project
-// @Description Update project details
+// @Description Update project details. Settings, rate limits, quotas, scheduling, the webhook URL and the allowed CIDRs are only replaced when provided. When the first webhook URL is set, its secret is only returned in this response.
 // @Tags projects
 // @Security BearerAuth
 // @Accept json
@@ -144,6 +227,66 @@ func UpdateProject(c *fiber.Ctx) error {
 
 	project.Name = req.Name
 	project.Description = req.Description
+	if req.Settings != nil {
+		if err := validateSettings(req.Settings); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+				Error:   "Invalid settings",
+				Message: err.Error(),
+			})
+		}
+		project.Settings = *req.Settings
+	}
+	if req.RateLimits != nil {
+		if err := validateRateLimits(req.RateLimits); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+				Error:   "Invalid rate limits",
+				Message: err.Error(),
+			})
+		}
+		project.RateLimits = *req.RateLimits
+	}
+	if req.Quotas != nil {
+		if err := validateQuotas(req.Quotas); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+				Error:   "Invalid quotas",
+				Message: err.Error(),
+			})
+		}
+		project.Quotas = *req.Quotas
+	}
+	if req.Scheduling != nil {
+		if err := validateScheduling(req.Scheduling); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+				Error:   "Invalid scheduling",
+				Message: err.Error(),
+			})
+		}
+		project.Scheduling = *req.Scheduling
+	}
+	if req.WebhookURL != nil {
+		if err := validateWebhookURL(*req.WebhookURL); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+				Error:   "Invalid webhook",
+				Message: err.Error(),
+			})
+		}
+		if err := setWebhookURL(&project, *req.WebhookURL); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+				Error:   "Failed to generate webhook secret",
+				Message: err.Error(),
+			})
+		}
+	}
+	if req.AllowedCIDRs != nil {
+		cidrs, err := clientip.Normalize(*req.AllowedCIDRs)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+				Error:   "Invalid allowed CIDRs",
+				Message: err.Error(),
+			})
+		}
+		project.AllowedCIDRs = cidrs
+	}
 
 	if err := database.DB.Save(&project).Error; err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
@@ -188,6 +331,51 @@ func ToggleProjectStatus(c *fiber.Ctx) error {
 	return c.JSON(project)
 }
 
+// RotateWebhookSecret godoc
+// @Summary Rotate the webhook secret of a project
+// @Description Replace the secret that signs the project's webhook deliveries. Deliveries are signed with the new secret right away. The secret is only returned in this response.
+// @Tags projects
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Project ID"
+// @Success 200 {object} models.Project
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /api/projects/{id}/webhook/rotate [post]
+func RotateWebhookSecret(c *fiber.Ctx) error {
+	id := c.Params("id")
+	var project models.Project
+
+	if err := database.DB.First(&project, id).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+			Error:   "Project not found",
+			Message: err.Error(),
+		})
+	}
+	if project.WebhookURL == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "No webhook",
+			Message: "The project has no webhook_url",
+		})
+	}
+
+	if err := newWebhookSecret(&project); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to generate webhook secret",
+			Message: err.Error(),
+		})
+	}
+	if err := database.DB.Model(&project).Update("webhook_secret", project.WebhookSecret).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to rotate webhook secret",
+			Message: err.Error(),
+		})
+	}
+
+	recordAdminAction(c, audit.ActionWebhookRotated, fmt.Sprintf("project %s (%d)", project.Name, project.ID))
+	return c.JSON(project)
+}
+
 // DeleteProject godoc
 // @Summary Delete a project
 // @Description Soft delete a project
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ollama-web-api/internal/models"
+	"github.com/ollama-web-api/internal/queue"
+)
+
+// validateScheduling checks the priority tier and weight of a project
+func validateScheduling(s *models.SchedulingSettings) error {
+	if !queue.ValidPriority(s.Priority) {
+		return fmt.Errorf("priority must be high, normal or low")
+	}
+	if s.Weight != nil && *s.Weight < 1 {
+		return fmt.Errorf("weight must be at least 1")
+	}
+	return nil
+}
+
+// enqueue waits in the request queue of the model until a backend has a
+// free slot, and reports the queue position in the X-Queue-Position header
+func (inf *inference) enqueue(c responseHeaders) *requestError {
+	scheduler := queue.Default()
+	req := queue.Request{
+		Model:     inf.model,
+		ProjectID: inf.project.ID,
+		Priority:  inf.project.Scheduling.Priority,
+	}
+	if w := inf.project.Scheduling.Weight; w != nil {
+		req.Weight = *w
+	}
+
+	ticket, err := scheduler.Acquire(inf.ctx, req)
+	if err != nil {
+		if errors.Is(err, queue.ErrTimeout) {
+			c.Set("Retry-After", strconv.Itoa(int(scheduler.MaxWait().Seconds()+0.5)))
+			return &requestError{fiber.StatusServiceUnavailable, models.ErrorResponse{
+				Error:   "Server busy",
+				Message: fmt.Sprintf("No capacity for %s became available within %s", inf.model, scheduler.MaxWait()),
+				Code:    "queue_timeout",
+			}}
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			return &requestError{fiber.StatusGatewayTimeout, models.ErrorResponse{
+				Error:   "Request timed out",
+				Message: "The request timed out while waiting in the queue",
+			}}
+		}
+		return &requestError{statusClientClosedRequest, models.ErrorResponse{
+			Error:   "Request cancelled",
+			Message: "The client disconnected while waiting in the queue",
+		}}
+	}
+
+	c.Set("X-Queue-Position", strconv.Itoa(ticket.Position))
+	inf.ticket = ticket
+	return nil
+}
+
+// GetQueueStatus godoc
+// @Summary Get request queue depth
+// @Description Get the queued and running requests per model and project
+// @Tags queue
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} models.QueueStatus
+// @Router /api/queue [get]
+func GetQueueStatus(c *fiber.Ctx) error {
+	return c.JSON(queue.Default().Status())
+}
@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ollama-web-api/internal/database"
+	"github.com/ollama-web-api/internal/models"
+)
+
+// quotaPeriod describes one of the supported quota periods
+type quotaPeriod struct {
+	name  string
+	soft  func(*models.QuotaSettings) *int
+	hard  func(*models.QuotaSettings) *int
+	start func(time.Time) time.Time
+	next  func(time.Time) time.Time
+}
+
+// quotaPeriods are evaluated in UTC so that every replica agrees on resets
+var quotaPeriods = []quotaPeriod{
+	{
+		name:  "daily",
+		soft:  func(q *models.QuotaSettings) *int { return q.DailySoftLimit },
+		hard:  func(q *models.QuotaSettings) *int { return q.DailyHardLimit },
+		start: func(t time.Time) time.Time { return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC) },
+		next:  func(start time.Time) time.Time { return start.AddDate(0, 0, 1) },
+	},
+	{
+		name:  "monthly",
+		soft:  func(q *models.QuotaSettings) *int { return q.MonthlySoftLimit },
+		hard:  func(q *models.QuotaSettings) *int { return q.MonthlyHardLimit },
+		start: func(t time.Time) time.Time { return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC) },
+		next:  func(start time.Time) time.Time { return start.AddDate(0, 1, 0) },
+	},
+}
+
+// findQuotaPeriod returns the quota period with the given name
+func findQuotaPeriod(name string) (quotaPeriod, bool) {
+	for _, p := range quotaPeriods {
+		if p.name == name {
+			return p, true
+		}
+	}
+	return quotaPeriod{}, false
+}
+
+// validateQuotas checks that quota limits are not negative and that soft
+// limits do not exceed hard limits
+func validateQuotas(q *models.QuotaSettings) error {
+	for _, p := range quotaPeriods {
+		soft, hard := p.soft(q), p.hard(q)
+		if soft != nil && *soft < 0 {
+			return fmt.Errorf("%s_soft_limit must not be negative", p.name)
+		}
+		if hard != nil && *hard < 0 {
+			return fmt.Errorf("%s_hard_limit must not be negative", p.name)
+		}
+		if soft != nil && hard != nil && *soft > *hard {
+			return fmt.Errorf("%s_soft_limit must not exceed %s_hard_limit", p.name, p.name)
+		}
+	}
+	return nil
+}
+
+// quotaStatus computes the consumption of a project in the current period.
+// Tokens are summed from the recorded usage, and top-ups granted for the
+// period raise both thresholds.
+func quotaStatus(project *models.Project, p quotaPeriod, now time.Time) (*models.QuotaStatus, error) {
+	start := p.start(now.UTC())
+	status := &models.QuotaStatus{
+		Period:      p.name,
+		PeriodStart: start,
+		ResetsAt:    p.next(start),
+	}
+
+	var used int64
+	if err := database.DB.Model(&models.UsageRecord{}).
+		Where("project_id = ? AND created_at >= ?", project.ID, start).
+		Select("COALESCE(SUM(total_tokens), 0)").
+		Scan(&used).Error; err != nil {
+		return nil, err
+	}
+	var topUp int64
+	if err := database.DB.Model(&models.QuotaTopUp{}).
+		Where("project_id = ? AND period = ? AND period_start = ?", project.ID, p.name, start).
+		Select("COALESCE(SUM(tokens), 0)").
+		Scan(&topUp).Error; err != nil {
+		return nil, err
+	}
+	status.Used = int(used)
+	status.TopUp = int(topUp)
+
+	if soft := p.soft(&project.Quotas); soft != nil {
+		limit := *soft + status.TopUp
+		status.SoftLimit = &limit
+		status.SoftExceeded = status.Used >= limit
+	}
+	if hard := p.hard(&project.Quotas); hard != nil {
+		limit := *hard + status.TopUp
+		remaining := max(limit-status.Used, 0)
+		status.HardLimit = &limit
+		status.Remaining = &remaining
+		status.HardExceeded = status.Used >= limit
+	}
+	return status, nil
+}
+
+// checkQuotas refuses the request once a hard quota is used up and adds an
+// X-Quota-Warning header once a soft quota is crossed
+func checkQuotas(c responseHeaders, project *models.Project) *requestError {
+	now := time.Now()
+	var warnings []string
+	for _, p := range quotaPeriods {
+		if p.soft(&project.Quotas) == nil && p.hard(&project.Quotas) == nil {
+			continue
+		}
+
+		status, err := quotaStatus(project, p, now)
+		if err != nil {
+			// Fail open, like the rate limiter
+			log.Printf("Failed to check %s quota for project %d: %v", p.name, project.ID, err)
+			continue
+		}
+
+		if status.HardExceeded {
+			c.Set("Retry-After", strconv.Itoa(int(status.ResetsAt.Sub(now).Seconds())+1))
+			return &requestError{fiber.StatusTooManyRequests, models.ErrorResponse{
+				Error:   "Quota exceeded",
+				Message: fmt.Sprintf("The %s token quota of %d is used up until %s", p.name, *status.HardLimit, status.ResetsAt.Format(time.RFC3339)),
+				Code:    "quota_exceeded",
+			}}
+		}
+		if status.SoftExceeded {
+			warnings = append(warnings, fmt.Sprintf("%s soft limit reached (%d of %d tokens used)", p.name, status.Used, *status.SoftLimit))
+		}
+	}
+
+	if len(warnings) > 0 {
+		c.Set("X-Quota-Warning", strings.Join(warnings, "; "))
+	}
+	return nil
+}
+
+// GetProjectQuota godoc
+// @Summary Get project quota status
+// @Description Get the token consumption, top-ups and remaining quota of a project for the current daily and monthly periods
+// @Tags projects
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Project ID"
+// @Success 200 {array} models.QuotaStatus
+// @Failure 404 {object} models.ErrorResponse
+// @Router /api/projects/{id}/quota [get]
+func GetProjectQuota(c *fiber.Ctx) error {
+	id := c.Params("id")
+	var project models.Project
+	if err := database.DB.First(&project, id).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+			Error:   "Project not found",
+			Message: err.Error(),
+		})
+	}
+
+	now := time.Now()
+	statuses := make([]models.QuotaStatus, 0, len(quotaPeriods))
+	for _, p := range quotaPeriods {
+		status, err := quotaStatus(&project, p, now)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+				Error:   "Failed to fetch quota",
+				Message: err.Error(),
+			})
+		}
+		statuses = append(statuses, *status)
+	}
+
+	return c.JSON(statuses)
+}
+
+// GrantQuotaTopUp godoc
+// @Summary Grant a quota top-up
+// @Description Grant a project extra tokens for the current daily or monthly period. The top-up expires when the period resets.
+// @Tags projects
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Project ID"
+// @Param request body models.GrantTopUpRequest true "Top-up details"
+// @Success 201 {object} models.QuotaTopUp
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /api/projects/{id}/quota/topups [post]
+func GrantQuotaTopUp(c *fiber.Ctx) error {
+	id := c.Params("id")
+	var project models.Project
+	if err := database.DB.First(&project, id).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+			Error:   "Project not found",
+			Message: err.Error(),
+		})
+	}
+
+	var req models.GrantTopUpRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: err.Error(),
+		})
+	}
+
+	p, ok := findQuotaPeriod(req.Period)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: "period must be daily or monthly",
+		})
+	}
+	if req.Tokens <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: "tokens must be positive",
+		})
+	}
+
+	topUp := models.QuotaTopUp{
+		ProjectID:   project.ID,
+		Period:      p.name,
+		PeriodStart: p.start(time.Now().UTC()),
+		Tokens:      req.Tokens,
+		Note:        req.Note,
+	}
+	if err := database.DB.Create(&topUp).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to grant top-up",
+			Message: err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(topUp)
+}
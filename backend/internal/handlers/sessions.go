@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ollama-web-api/internal/audit"
+	"github.com/ollama-web-api/internal/database"
+	"github.com/ollama-web-api/internal/models"
+	"github.com/ollama-web-api/internal/sessions"
+)
+
+// ListSessions godoc
+// @Summary List active sessions
+// @Description List the sessions that are neither expired nor revoked, most recently used first, with their users
+// @Tags sessions
+// @Security BearerAuth
+// @Produce json
+// @Param user_id query int false "Filter by user ID"
+// @Success 200 {array} models.Session
+// @Failure 400 {object} models.ErrorResponse
+// @Router /api/sessions [get]
+func ListSessions(c *fiber.Ctx) error {
+	query := database.DB.Preload("User").
+		Where("revoked_at IS NULL AND expires_at > ?", time.Now()).
+		Order("last_used_at DESC")
+	if v := c.Query("user_id"); v != "" {
+		userID, err := strconv.Atoi(v)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+				Error:   "Invalid request",
+				Message: "user_id must be a number",
+			})
+		}
+		query = query.Where("user_id = ?", userID)
+	}
+
+	var active []models.Session
+	if err := query.Find(&active).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to fetch sessions",
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(active)
+}
+
+// RevokeSession godoc
+// @Summary End a session
+// @Description Revoke a session right away. Its refresh token stops working and its current access token is revoked.
+// @Tags sessions
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Session ID"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /api/sessions/{id} [delete]
+func RevokeSession(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil || id <= 0 {
+		return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+			Error:   "Session not found",
+			Message: "Invalid session ID",
+		})
+	}
+	session, err := sessions.Find(uint(id))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to fetch session",
+			Message: err.Error(),
+		})
+	}
+	if session == nil {
+		return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+			Error:   "Session not found",
+			Message: "The session does not exist or has already ended",
+		})
+	}
+
+	if err := sessions.Revoke(session); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to revoke session",
+			Message: err.Error(),
+		})
+	}
+
+	recordAdminAction(c, audit.ActionSessionRevoked, fmt.Sprintf("session %d of user %d", session.ID, session.UserID))
+	return c.JSON(models.SuccessResponse{
+		Message: "Session revoked successfully",
+	})
+}
@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ollama-web-api/internal/models"
+)
+
+// validateSettings checks that generation settings contain no negative limits
+func validateSettings(s *models.GenerationSettings) error {
+	limits := map[string]*int{
+		"default_num_ctx":   s.DefaultNumCtx,
+		"max_num_predict":   s.MaxNumPredict,
+		"max_num_ctx":       s.MaxNumCtx,
+		"max_prompt_length": s.MaxPromptLength,
+		"max_images":        s.MaxImages,
+	}
+	for name, v := range limits {
+		if v != nil && *v < 0 {
+			return fmt.Errorf("%s must not be negative", name)
+		}
+	}
+	if s.DefaultTemperature != nil && *s.DefaultTemperature < 0 {
+		return fmt.Errorf("default_temperature must not be negative")
+	}
+	return nil
+}
+
+// validateRateLimits checks that rate limits contain no negative values
+func validateRateLimits(r *models.RateLimitSettings) error {
+	limits := map[string]*int{
+		"requests_per_minute": r.RequestsPerMinute,
+		"tokens_per_minute":   r.TokensPerMinute,
+		"max_concurrent":      r.MaxConcurrent,
+	}
+	for name, v := range limits {
+		if v != nil && *v < 0 {
+			return fmt.Errorf("%s must not be negative", name)
+		}
+	}
+	return nil
+}
+
+// effectiveSettings merges the project settings with the overrides of the
+// assigned model. Fields set on the model take precedence.
+func effectiveSettings(project *models.Project, modelName string) models.GenerationSettings {
+	settings := project.Settings
+	for _, pm := range project.Models {
+		if pm.ModelName != modelName {
+			continue
+		}
+		override := pm.Settings
+		if override.DefaultSystem != "" {
+			settings.DefaultSystem = override.DefaultSystem
+		}
+		if override.DefaultTemperature != nil {
+			settings.DefaultTemperature = override.DefaultTemperature
+		}
+		if override.DefaultNumCtx != nil {
+			settings.DefaultNumCtx = override.DefaultNumCtx
+		}
+		if override.MaxNumPredict != nil {
+			settings.MaxNumPredict = override.MaxNumPredict
+		}
+		if override.MaxNumCtx != nil {
+			settings.MaxNumCtx = override.MaxNumCtx
+		}
+		if override.MaxPromptLength != nil {
+			settings.MaxPromptLength = override.MaxPromptLength
+		}
+		if override.MaxImages != nil {
+			settings.MaxImages = override.MaxImages
+		}
+		if override.ClampToLimits != nil {
+			settings.ClampToLimits = override.ClampToLimits
+		}
+		break
+	}
+	return settings
+}
+
+// limitError builds the error returned when a request exceeds a hard limit
+func limitError(format string, args ...interface{}) *requestError {
+	return &requestError{fiber.StatusBadRequest, models.ErrorResponse{
+		Error:   "Limit exceeded",
+		Message: fmt.Sprintf(format, args...),
+	}}
+}
+
+// applyOptionSettings fills in default options and enforces the num_predict
+// and num_ctx caps, clamping or rejecting depending on the settings
+func applyOptionSettings(options *models.GenerateOptions, settings models.GenerationSettings) (*models.GenerateOptions, *requestError) {
+	if options == nil {
+		options = &models.GenerateOptions{}
+	}
+
+	if options.Temperature == nil && settings.DefaultTemperature != nil {
+		temperature := *settings.DefaultTemperature
+		options.Temperature = &temperature
+	}
+	if options.NumCtx == nil && settings.DefaultNumCtx != nil {
+		numCtx := *settings.DefaultNumCtx
+		options.NumCtx = &numCtx
+	}
+
+	clamp := settings.ClampToLimits != nil && *settings.ClampToLimits
+	if max := settings.MaxNumPredict; max != nil {
+		// Unset or negative (infinite/fill context) num_predict is capped to the limit
+		if options.NumPredict == nil || *options.NumPredict < 0 {
+			numPredict := *max
+			options.NumPredict = &numPredict
+		} else if *options.NumPredict > *max {
+			if !clamp {
+				return nil, limitError("num_predict %d exceeds the maximum of %d", *options.NumPredict, *max)
+			}
+			numPredict := *max
+			options.NumPredict = &numPredict
+		}
+	}
+
+	if rerr := capNumCtx(options, settings); rerr != nil {
+		return nil, rerr
+	}
+
+	return options, nil
+}
+
+// capNumCtx enforces the num_ctx cap, clamping or rejecting depending on the settings
+func capNumCtx(options *models.GenerateOptions, settings models.GenerationSettings) *requestError {
+	max := settings.MaxNumCtx
+	if max == nil || options.NumCtx == nil || *options.NumCtx <= *max {
+		return nil
+	}
+	if settings.ClampToLimits == nil || !*settings.ClampToLimits {
+		return limitError("num_ctx %d exceeds the maximum of %d", *options.NumCtx, *max)
+	}
+	numCtx := *max
+	options.NumCtx = &numCtx
+	return nil
+}
+
+// checkInputLimits enforces the prompt length and image count caps
+func checkInputLimits(promptLength, images int, settings models.GenerationSettings) *requestError {
+	if max := settings.MaxPromptLength; max != nil && promptLength > *max {
+		return limitError("Prompt length of %d characters exceeds the maximum of %d", promptLength, *max)
+	}
+	if max := settings.MaxImages; max != nil && images > *max {
+		return limitError("%d images exceed the maximum of %d", images, *max)
+	}
+	return nil
+}
+
+// applyGenerateSettings merges the project and model settings into a
+// generate request and enforces their limits
+func applyGenerateSettings(req *models.OllamaRequest, project *models.Project) *requestError {
+	settings := effectiveSettings(project, req.Model)
+
+	if req.System == "" {
+		req.System = settings.DefaultSystem
+	}
+
+	promptLength := utf8.RuneCountInString(req.Prompt) + utf8.RuneCountInString(req.System) + utf8.RuneCountInString(req.Suffix)
+	if rerr := checkInputLimits(promptLength, len(req.Images), settings); rerr != nil {
+		return rerr
+	}
+
+	options, rerr := applyOptionSettings(req.Options, settings)
+	if rerr != nil {
+		return rerr
+	}
+	req.Options = options
+	return nil
+}
+
+// applyChatSettings merges the project and model settings into a chat
+// request and enforces their limits. The default system prompt is only
+// added when the conversation has no system message of its own.
+func applyChatSettings(req *models.OllamaChatRequest, project *models.Project) *requestError {
+	settings := effectiveSettings(project, req.Model)
+
+	hasSystem := false
+	promptLength, images := 0, 0
+	for _, msg := range req.Messages {
+		if msg.Role == "system" {
+			hasSystem = true
+		}
+		promptLength += utf8.RuneCountInString(msg.Content)
+		images += len(msg.Images)
+	}
+
+	if !hasSystem && settings.DefaultSystem != "" {
+		req.Messages = append([]models.ChatMessage{{Role: "system", Content: settings.DefaultSystem}}, req.Messages...)
+		promptLength += utf8.RuneCountInString(settings.DefaultSystem)
+	}
+
+	if rerr := checkInputLimits(promptLength, images, settings); rerr != nil {
+		return rerr
+	}
+
+	options, rerr := applyOptionSettings(req.Options, settings)
+	if rerr != nil {
+		return rerr
+	}
+	req.Options = options
+	return nil
+}
+
+// applyEmbedSettings enforces the prompt length and num_ctx limits on an
+// embed request
+func applyEmbedSettings(req *models.OllamaEmbedRequest, inputs []string, project *models.Project) *requestError {
+	settings := effectiveSettings(project, req.Model)
+
+	promptLength := 0
+	for _, input := range inputs {
+		promptLength += utf8.RuneCountInString(input)
+	}
+	if rerr := checkInputLimits(promptLength, 0, settings); rerr != nil {
+		return rerr
+	}
+
+	// Generation defaults and num_predict do not apply to embeddings
+	if req.Options == nil && settings.DefaultNumCtx == nil {
+		return nil
+	}
+	options := req.Options
+	if options == nil {
+		options = &models.GenerateOptions{}
+	}
+	if options.NumCtx == nil && settings.DefaultNumCtx != nil {
+		numCtx := *settings.DefaultNumCtx
+		options.NumCtx = &numCtx
+	}
+	if rerr := capNumCtx(options, settings); rerr != nil {
+		return rerr
+	}
+	req.Options = options
+	return nil
+}
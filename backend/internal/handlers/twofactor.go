@@ -0,0 +1,335 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ollama-web-api/internal/audit"
+	"github.com/ollama-web-api/internal/clientip"
+	"github.com/ollama-web-api/internal/database"
+	"github.com/ollama-web-api/internal/models"
+	"github.com/ollama-web-api/internal/users"
+)
+
+// invalidCode is the response to a wrong two-factor code
+var invalidCode = &requestError{fiber.StatusBadRequest, models.ErrorResponse{
+	Error:   "Invalid code",
+	Message: "The two-factor code is wrong, expired or already used",
+}}
+
+// checkCode checks a two-factor code of the logged-in user. Wrong codes count
+// as failed logins, so a stolen access token cannot be used to guess the code.
+func checkCode(c *fiber.Ctx, user *models.User) *requestError {
+	var req models.TwoFactorCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return &requestError{fiber.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: err.Error(),
+		}}
+	}
+
+	ip := clientip.Get(c).String()
+	if rerr := checkLoginThrottle(c, ip, user.Username); rerr != nil {
+		return rerr
+	}
+	if _, err := users.CheckSecondFactor(user, req.Code); err != nil {
+		if errors.Is(err, users.ErrInvalidCode) {
+			recordLoginFailure(ip, user.Username, "invalid two-factor code")
+			return invalidCode
+		}
+		return &requestError{fiber.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to check code",
+			Message: err.Error(),
+		}}
+	}
+	return nil
+}
+
+// GetTwoFactorStatus godoc
+// @Summary Two-factor status
+// @Description Tell whether the logged-in user has two-factor authentication, whether the policy requires it and how many recovery codes are left
+// @Tags two-factor
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} models.TwoFactorStatusResponse
+// @Router /api/auth/2fa [get]
+func GetTwoFactorStatus(c *fiber.Ctx) error {
+	user := c.Locals("user").(*models.User)
+	required, err := users.TwoFactorRequired()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to check security policy",
+			Message: err.Error(),
+		})
+	}
+	left, err := users.RecoveryCodesLeft(user.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to count recovery codes",
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(models.TwoFactorStatusResponse{
+		Enabled:           user.TOTPEnabled,
+		Required:          required && user.Provider != users.ProviderOIDC,
+		RecoveryCodesLeft: left,
+	})
+}
+
+// SetupTwoFactor godoc
+// @Summary Start setting up two-factor authentication
+// @Description Create a new secret for the logged-in user to add to an authenticator app, usually by showing the otpauth URI as a QR code. Two-factor authentication is turned on once a code is confirmed at /api/auth/2fa/enable.
+// @Tags two-factor
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} models.TwoFactorSetupResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /api/auth/2fa/setup [post]
+func SetupTwoFactor(c *fiber.Ctx) error {
+	user := c.Locals("user").(*models.User)
+	if user.Provider == users.ProviderOIDC {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: "Single sign-on users set up two-factor authentication at their identity provider",
+		})
+	}
+	if user.TOTPEnabled {
+		return c.Status(fiber.StatusConflict).JSON(models.ErrorResponse{
+			Error:   "Two-factor authentication already enabled",
+			Message: "Disable it first to set up a new authenticator",
+		})
+	}
+
+	setup, err := users.BeginTwoFactor(user)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to set up two-factor authentication",
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(setup)
+}
+
+// EnableTwoFactor godoc
+// @Summary Turn on two-factor authentication
+// @Description Confirm a code of the secret from /api/auth/2fa/setup to turn on two-factor authentication. The response holds recovery codes, each of which replaces a code once; they are only shown here.
+// @Tags two-factor
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body models.TwoFactorCodeRequest true "Code from the authenticator app"
+// @Success 200 {object} models.RecoveryCodesResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
+// @Router /api/auth/2fa/enable [post]
+func EnableTwoFactor(c *fiber.Ctx) error {
+	user := c.Locals("user").(*models.User)
+	if user.TOTPEnabled {
+		return c.Status(fiber.StatusConflict).JSON(models.ErrorResponse{
+			Error:   "Two-factor authentication already enabled",
+			Message: "Two-factor authentication is already on",
+		})
+	}
+
+	var req models.TwoFactorCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: err.Error(),
+		})
+	}
+	ip := clientip.Get(c).String()
+	if rerr := checkLoginThrottle(c, ip, user.Username); rerr != nil {
+		return rerr.send(c)
+	}
+	codes, err := users.EnableTwoFactor(user, req.Code)
+	if errors.Is(err, users.ErrInvalidCode) {
+		recordLoginFailure(ip, user.Username, "invalid two-factor code")
+		return invalidCode.send(c)
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to enable two-factor authentication",
+			Message: err.Error(),
+		})
+	}
+
+	recordAdminAction(c, audit.ActionTwoFactorEnabled, fmt.Sprintf("user %s (%d)", user.Username, user.ID))
+	return c.JSON(models.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// DisableTwoFactor godoc
+// @Summary Turn off two-factor authentication
+// @Description Turn off two-factor authentication of the logged-in user, confirmed with a code from the authenticator app or a recovery code. Not possible while the security policy requires two-factor authentication.
+// @Tags two-factor
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body models.TwoFactorCodeRequest true "Code from the authenticator app or a recovery code"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
+// @Router /api/auth/2fa/disable [post]
+func DisableTwoFactor(c *fiber.Ctx) error {
+	user := c.Locals("user").(*models.User)
+	if !user.TOTPEnabled {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: "Two-factor authentication is not enabled",
+		})
+	}
+	required, err := users.TwoFactorRequired()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to check security policy",
+			Message: err.Error(),
+		})
+	}
+	if required {
+		return c.Status(fiber.StatusForbidden).JSON(models.ErrorResponse{
+			Error:   "Two-factor authentication required",
+			Message: "The security policy requires two-factor authentication; an owner can reset it instead",
+		})
+	}
+	if rerr := checkCode(c, user); rerr != nil {
+		return rerr.send(c)
+	}
+
+	if err := users.DisableTwoFactor(user); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to disable two-factor authentication",
+			Message: err.Error(),
+		})
+	}
+
+	recordAdminAction(c, audit.ActionTwoFactorDisabled, fmt.Sprintf("user %s (%d)", user.Username, user.ID))
+	return c.JSON(models.SuccessResponse{
+		Message: "Two-factor authentication disabled successfully",
+	})
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary Replace recovery codes
+// @Description Replace the recovery codes of the logged-in user, confirmed with a code from the authenticator app or a recovery code. The old codes stop working.
+// @Tags two-factor
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body models.TwoFactorCodeRequest true "Code from the authenticator app or a recovery code"
+// @Success 200 {object} models.RecoveryCodesResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
+// @Router /api/auth/2fa/recovery-codes [post]
+func RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	user := c.Locals("user").(*models.User)
+	if !user.TOTPEnabled {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: "Two-factor authentication is not enabled",
+		})
+	}
+	if rerr := checkCode(c, user); rerr != nil {
+		return rerr.send(c)
+	}
+
+	codes, err := users.NewRecoveryCodes(user)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to create recovery codes",
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(models.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// ResetUserTwoFactor godoc
+// @Summary Reset the two-factor authentication of a user
+// @Description Turn off two-factor authentication of a user who lost their authenticator and recovery codes. When the security policy requires it, they have to set it up again after logging in.
+// @Tags users
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /api/users/{id}/2fa [delete]
+func ResetUserTwoFactor(c *fiber.Ctx) error {
+	var user models.User
+	if err := database.DB.First(&user, c.Params("id")).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+			Error:   "User not found",
+			Message: err.Error(),
+		})
+	}
+
+	if err := users.DisableTwoFactor(&user); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to reset two-factor authentication",
+			Message: err.Error(),
+		})
+	}
+
+	recordAdminAction(c, audit.ActionTwoFactorReset, fmt.Sprintf("user %s (%d)", user.Username, user.ID))
+	return c.JSON(models.SuccessResponse{
+		Message: "Two-factor authentication reset successfully",
+	})
+}
+
+// GetSecurityPolicy godoc
+// @Summary Get the security policy
+// @Description Get the settings that apply to all users
+// @Tags security
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} models.SecurityPolicy
+// @Router /api/security/policy [get]
+func GetSecurityPolicy(c *fiber.Ctx) error {
+	policy, err := users.Policy()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to fetch security policy",
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(policy)
+}
+
+// UpdateSecurityPolicy godoc
+// @Summary Change the security policy
+// @Description Change the settings that apply to all users. With require_2fa, users without two-factor authentication can only set it up until they have; single sign-on users are exempt.
+// @Tags security
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body models.UpdateSecurityPolicyRequest true "Policy"
+// @Success 200 {object} models.SecurityPolicy
+// @Failure 400 {object} models.ErrorResponse
+// @Router /api/security/policy [put]
+func UpdateSecurityPolicy(c *fiber.Ctx) error {
+	var req models.UpdateSecurityPolicyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: err.Error(),
+		})
+	}
+
+	updatedBy, _ := c.Locals("username").(string)
+	policy, err := users.SetPolicy(req.Require2FA, updatedBy)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to update security policy",
+			Message: err.Error(),
+		})
+	}
+
+	recordAdminAction(c, audit.ActionPolicyChanged, fmt.Sprintf("require_2fa=%t", policy.Require2FA))
+	return c.JSON(policy)
+}
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ollama-web-api/internal/database"
+	"github.com/ollama-web-api/internal/models"
+)
+
+// usageBuckets lists the supported aggregation periods
+var usageBuckets = map[string]bool{"hour": true, "day": true, "month": true}
+
+// parseUsageTime parses an RFC3339 timestamp or a YYYY-MM-DD date
+func parseUsageTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// queryUsage aggregates usage records according to the request query
+// parameters: from, to, bucket, project_id and model
+func queryUsage(c *fiber.Ctx) ([]models.UsageSummary, *requestError) {
+	bucket := c.Query("bucket", "day")
+	if !usageBuckets[bucket] {
+		return nil, &requestError{fiber.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: "bucket must be one of hour, day or month",
+		}}
+	}
+
+	to := time.Now()
+	from := to.AddDate(0, 0, -30)
+	if v := c.Query("from"); v != "" {
+		t, err := parseUsageTime(v)
+		if err != nil {
+			return nil, &requestError{fiber.StatusBadRequest, models.ErrorResponse{
+				Error:   "Invalid request",
+				Message: "from must be an RFC3339 timestamp or a YYYY-MM-DD date",
+			}}
+		}
+		from = t
+	}
+	if v := c.Query("to"); v != "" {
+		t, err := parseUsageTime(v)
+		if err != nil {
+			return nil, &requestError{fiber.StatusBadRequest, models.ErrorResponse{
+				Error:   "Invalid request",
+				Message: "to must be an RFC3339 timestamp or a YYYY-MM-DD date",
+			}}
+		}
+		to = t
+	}
+
+	// bucket is validated above, so it is safe to inline. Sums are cast back
+	// to bigint because Postgres widens them to numeric.
+	query := database.DB.Table("usage_records AS u").
+		Select(fmt.Sprintf(`date_trunc('%s', u.created_at) AS bucket,
+			u.project_id, COALESCE(p.name, '') AS project_name, u.model,
+			COUNT(*) AS requests,
+			SUM(CASE WHEN u.status = 'success' THEN 0 ELSE 1 END)::bigint AS errors,
+			SUM(u.prompt_tokens)::bigint AS prompt_tokens,
+			SUM(u.completion_tokens)::bigint AS completion_tokens,
+			SUM(u.total_tokens)::bigint AS total_tokens,
+			SUM(u.total_duration)::bigint AS total_duration,
+			SUM(u.eval_duration)::bigint AS eval_duration`, bucket)).
+		Joins("LEFT JOIN projects p ON p.id = u.project_id").
+		Where("u.created_at >= ? AND u.created_at < ?", from, to)
+
+	if v := c.Query("project_id"); v != "" {
+		projectID, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, &requestError{fiber.StatusBadRequest, models.ErrorResponse{
+				Error:   "Invalid request",
+				Message: "project_id must be a number",
+			}}
+		}
+		query = query.Where("u.project_id = ?", projectID)
+	}
+	if v := c.Query("model"); v != "" {
+		query = query.Where("u.model = ?", v)
+	}
+
+	var summaries []models.UsageSummary
+	err := query.Group("bucket, u.project_id, p.name, u.model").
+		Order("bucket, u.project_id, u.model").
+		Scan(&summaries).Error
+	if err != nil {
+		return nil, &requestError{fiber.StatusInternalServerError, models.ErrorResponse{
+			Error:   "Failed to fetch usage",
+			Message: err.Error(),
+		}}
+	}
+
+	return summaries, nil
+}
+
+// GetUsage godoc
+// @Summary Get aggregated usage
+// @Description Aggregate token usage and durations by time bucket, project and model
+// @Tags usage
+// @Security BearerAuth
+// @Produce json
+// @Param from query string false "Start (RFC3339 or YYYY-MM-DD), defaults to 30 days ago"
+// @Param to query string false "End (RFC3339 or YYYY-MM-DD), defaults to now"
+// @Param bucket query string false "Time bucket: hour, day or month" default(day)
+// @Param project_id query int false "Filter by project ID"
+// @Param model query string false "Filter by model name"
+// @Success 200 {array} models.UsageSummary
+// @Failure 400 {object} models.ErrorResponse
+// @Router /api/usage [get]
+func GetUsage(c *fiber.Ctx) error {
+	summaries, rerr := queryUsage(c)
+	if rerr != nil {
+		return rerr.send(c)
+	}
+
+	if summaries == nil {
+		summaries = []models.UsageSummary{}
+	}
+	return c.JSON(summaries)
+}
+
+// ExportUsage godoc
+// @Summary Export aggregated usage as CSV
+// @Description Same aggregation as GET /api/usage, returned as a CSV file for chargeback
+// @Tags usage
+// @Security BearerAuth
+// @Produce text/csv
+// @Param from query string false "Start (RFC3339 or YYYY-MM-DD), defaults to 30 days ago"
+// @Param to query string false "End (RFC3339 or YYYY-MM-DD), defaults to now"
+// @Param bucket query string false "Time bucket: hour, day or month" default(day)
+// @Param project_id query int false "Filter by project ID"
+// @Param model query string false "Filter by model name"
+// @Success 200 {string} string "CSV file"
+// @Failure 400 {object} models.ErrorResponse
+// @Router /api/usage/export [get]
+func ExportUsage(c *fiber.Ctx) error {
+	summaries, rerr := queryUsage(c)
+	if rerr != nil {
+		return rerr.send(c)
+	}
+
+	c.Set("Content-Type", "text/csv")
+	c.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="usage-%s.csv"`, time.Now().Format("20060102")))
+
+	w := csv.NewWriter(c)
+	w.Write([]string{
+		"bucket", "project_id", "project_name", "model", "requests", "errors",
+		"prompt_tokens", "completion_tokens", "total_tokens", "total_duration_ms", "eval_duration_ms",
+	})
+	for _, s := range summaries {
+		w.Write([]string{
+			s.Bucket.Format(time.RFC3339),
+			strconv.FormatUint(uint64(s.ProjectID), 10),
+			s.ProjectName,
+			s.Model,
+			strconv.FormatInt(s.Requests, 10),
+			strconv.FormatInt(s.Errors, 10),
+			strconv.FormatInt(s.PromptTokens, 10),
+			strconv.FormatInt(s.CompletionTokens, 10),
+			strconv.FormatInt(s.TotalTokens, 10),
+			strconv.FormatInt(s.TotalDuration/int64(time.Millisecond), 10),
+			strconv.FormatInt(s.EvalDuration/int64(time.Millisecond), 10),
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
@@ -0,0 +1,295 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ollama-web-api/internal/audit"
+	"github.com/ollama-web-api/internal/database"
+	"github.com/ollama-web-api/internal/models"
+	"github.com/ollama-web-api/internal/roles"
+	"github.com/ollama-web-api/internal/sessions"
+	"github.com/ollama-web-api/internal/users"
+)
+
+// validateRole checks that role is a known role
+func validateRole(role string) *requestError {
+	if !roles.Valid(role) {
+		return &requestError{fiber.StatusBadRequest, models.ErrorResponse{
+			Error:   "Invalid role",
+			Message: fmt.Sprintf("role must be one of %s", strings.Join(roles.All, ", ")),
+		}}
+	}
+	return nil
+}
+
+// ListUsers godoc
+// @Summary List users
+// @Description Get all admin users, including disabled ones
+// @Tags users
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} models.User
+// @Failure 401 {object} models.ErrorResponse
+// @Router /api/users [get]
+func ListUsers(c *fiber.Ctx) error {
+	var list []models.User
+	if err := database.DB.Order("username").Find(&list).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to fetch users",
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(list)
+}
+
+// CreateUser godoc
+// @Summary Create a user
+// @Description Create an admin user with a password of at least 12 characters and a role of viewer (the default), operator, admin or owner
+// @Tags users
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param user body models.CreateUserRequest true "User details"
+// @Success 201 {object} models.User
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /api/users [post]
+func CreateUser(c *fiber.Ctx) error {
+	var req models.CreateUserRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: err.Error(),
+		})
+	}
+	req.Username = strings.TrimSpace(req.Username)
+	if req.Username == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: "username is required",
+		})
+	}
+	if err := users.ValidatePassword(req.Password); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "Invalid password",
+			Message: err.Error(),
+		})
+	}
+	if req.Role == "" {
+		req.Role = roles.Viewer
+	}
+	if rerr := validateRole(req.Role); rerr != nil {
+		return rerr.send(c)
+	}
+
+	var existing int64
+	if err := database.DB.Model(&models.User{}).Where("username = ?", req.Username).Count(&existing).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to create user",
+			Message: err.Error(),
+		})
+	}
+	if existing > 0 {
+		return c.Status(fiber.StatusConflict).JSON(models.ErrorResponse{
+			Error:   "User already exists",
+			Message: fmt.Sprintf("A user named '%s' already exists", req.Username),
+		})
+	}
+
+	hash, err := users.HashPassword(req.Password)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to hash password",
+			Message: err.Error(),
+		})
+	}
+	createdBy, _ := c.Locals("username").(string)
+	user := models.User{
+		Username:     req.Username,
+		PasswordHash: hash,
+		Role:         req.Role,
+		Provider:     users.ProviderLocal,
+		IsActive:     true,
+		CreatedBy:    createdBy,
+	}
+	if err := database.DB.Create(&user).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to create user",
+			Message: err.Error(),
+		})
+	}
+
+	recordAdminAction(c, audit.ActionUserCreated, fmt.Sprintf("user %s (%d) as %s", user.Username, user.ID, user.Role))
+	return c.Status(fiber.StatusCreated).JSON(user)
+}
+
+// ToggleUserStatus godoc
+// @Summary Disable or enable a user
+// @Description Disable an active user or enable a disabled one. A disabled user cannot log in and their sessions end. Users cannot disable themselves.
+// @Tags users
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} models.User
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /api/users/{id}/toggle [patch]
+func ToggleUserStatus(c *fiber.Ctx) error {
+	var user models.User
+	if err := database.DB.First(&user, c.Params("id")).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+			Error:   "User not found",
+			Message: err.Error(),
+		})
+	}
+	if currentID, _ := c.Locals("user_id").(uint); user.ID == currentID && user.IsActive {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: "You cannot disable your own user",
+		})
+	}
+
+	user.IsActive = !user.IsActive
+	if err := database.DB.Model(&user).Update("is_active", user.IsActive).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to update user",
+			Message: err.Error(),
+		})
+	}
+
+	action := audit.ActionUserDisabled
+	if user.IsActive {
+		action = audit.ActionUserEnabled
+	} else if err := sessions.RevokeUser(user.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to end sessions",
+			Message: err.Error(),
+		})
+	}
+	recordAdminAction(c, action, fmt.Sprintf("user %s (%d)", user.Username, user.ID))
+	return c.JSON(user)
+}
+
+// UpdateUserRole godoc
+// @Summary Change the role of a user
+// @Description Change the role of a user to viewer, operator, admin or owner. The user's tokens stop working and refreshing them issues tokens with the new role. Users cannot change their own role. Single sign-on users get their role from the role mappings again on their next login.
+// @Tags users
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param request body models.UpdateUserRoleRequest true "New role"
+// @Success 200 {object} models.User
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /api/users/{id}/role [put]
+func UpdateUserRole(c *fiber.Ctx) error {
+	var user models.User
+	if err := database.DB.First(&user, c.Params("id")).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+			Error:   "User not found",
+			Message: err.Error(),
+		})
+	}
+	if currentID, _ := c.Locals("user_id").(uint); user.ID == currentID {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: "You cannot change your own role",
+		})
+	}
+
+	var req models.UpdateUserRoleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: err.Error(),
+		})
+	}
+	if rerr := validateRole(req.Role); rerr != nil {
+		return rerr.send(c)
+	}
+
+	previous := user.Role
+	user.Role = req.Role
+	if err := database.DB.Model(&user).Update("role", user.Role).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to update user",
+			Message: err.Error(),
+		})
+	}
+
+	recordAdminAction(c, audit.ActionRoleChanged, fmt.Sprintf("user %s (%d) from %s to %s", user.Username, user.ID, previous, user.Role))
+	return c.JSON(user)
+}
+
+// ResetUserPassword godoc
+// @Summary Reset the password of a user
+// @Description Set a new password of at least 12 characters for a user and end their sessions. Single sign-on users have no password.
+// @Tags users
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param request body models.ResetPasswordRequest true "New password"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /api/users/{id}/password [post]
+func ResetUserPassword(c *fiber.Ctx) error {
+	var user models.User
+	if err := database.DB.First(&user, c.Params("id")).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+			Error:   "User not found",
+			Message: err.Error(),
+		})
+	}
+
+	if user.Provider == users.ProviderOIDC {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: "This user signs in through single sign-on and has no password",
+		})
+	}
+
+	var req models.ResetPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "Invalid request",
+			Message: err.Error(),
+		})
+	}
+	if err := users.ValidatePassword(req.Password); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error:   "Invalid password",
+			Message: err.Error(),
+		})
+	}
+
+	hash, err := users.HashPassword(req.Password)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to hash password",
+			Message: err.Error(),
+		})
+	}
+	if err := database.DB.Model(&user).Update("password_hash", hash).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to reset password",
+			Message: err.Error(),
+		})
+	}
+	if err := sessions.RevokeUser(user.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Error:   "Failed to end sessions",
+			Message: err.Error(),
+		})
+	}
+
+	recordAdminAction(c, audit.ActionPasswordReset, fmt.Sprintf("user %s (%d)", user.Username, user.ID))
+	return c.JSON(models.SuccessResponse{
+		Message: "Password reset successfully",
+	})
+}
@@ -0,0 +1,294 @@
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ollama-web-api/internal/database"
+	"github.com/ollama-web-api/internal/models"
+)
+
+// Job states
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+	StatusCancelled = "cancelled"
+)
+
+const (
+	// pollInterval is how often idle workers look for queued jobs and
+	// running jobs refresh their heartbeat
+	pollInterval = 2 * time.Second
+	// staleAfter is how long a running job may go without a heartbeat
+	// before it is considered abandoned by a crashed replica and requeued
+	staleAfter = time.Minute
+)
+
+// ErrCancelled is the cancellation cause of the context of a job that was
+// cancelled through the API
+var ErrCancelled = errors.New("job cancelled")
+
+// Runner generates the response of a job. It must stop when ctx is done.
+type Runner func(ctx context.Context, job *models.GenerationJob) (*models.OllamaResponse, error)
+
+var (
+	runner  Runner
+	timeout = time.Hour
+	wake    = make(chan struct{}, 1)
+
+	// running holds the cancel functions of the jobs running in this process
+	running   = make(map[string]context.CancelCauseFunc)
+	runningMu sync.Mutex
+)
+
+// NewID returns a random job ID
+func NewID() (string, error) {
+	bytes := make([]byte, 12)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return "job-" + hex.EncodeToString(bytes), nil
+}
+
+// Setup starts JOB_WORKERS workers (default 4) that run queued jobs with
+// run, each bounded by JOB_TIMEOUT (default 1h). Jobs are claimed through
+// the database, so several replicas can share the queue.
+func Setup(run Runner) {
+	runner = run
+
+	workers := 4
+	if v := os.Getenv("JOB_WORKERS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			workers = n
+		} else {
+			log.Printf("Invalid JOB_WORKERS %q, using %d", v, workers)
+		}
+	}
+	if v := os.Getenv("JOB_TIMEOUT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			timeout = d
+		} else {
+			log.Printf("Invalid JOB_TIMEOUT %q, using %s", v, timeout)
+		}
+	}
+
+	for i := 0; i < workers; i++ {
+		go work()
+	}
+	log.Printf("Started %d generation job workers", workers)
+}
+
+// Notify wakes an idle worker to pick up a newly queued job
+func Notify() {
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+}
+
+// work runs queued jobs until the process exits
+func work() {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		requeueStale()
+
+		job, err := claim()
+		if err != nil {
+			log.Printf("Failed to claim generation job: %v", err)
+		}
+		if job != nil {
+			run(job)
+			continue
+		}
+
+		select {
+		case <-wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// claim marks the oldest queued job as running and returns it, or nil when
+// no job is queued
+func claim() (*models.GenerationJob, error) {
+	for {
+		var queued []models.GenerationJob
+		if err := database.DB.Where("status = ?", StatusQueued).Order("created_at").Limit(1).Find(&queued).Error; err != nil {
+			return nil, err
+		}
+		if len(queued) == 0 {
+			return nil, nil
+		}
+		job := queued[0]
+
+		now := time.Now()
+		result := database.DB.Model(&models.GenerationJob{}).
+			Where("id = ? AND status = ?", job.ID, StatusQueued).
+			Updates(map[string]interface{}{"status": StatusRunning, "started_at": now, "heartbeat_at": now})
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 1 {
+			job.Status = StatusRunning
+			job.StartedAt = &now
+			job.HeartbeatAt = &now
+			return &job, nil
+		}
+		// Another worker got there first; try the next job
+	}
+}
+
+// requeueStale puts running jobs whose worker stopped sending heartbeats
+// back in the queue
+func requeueStale() {
+	result := database.DB.Model(&models.GenerationJob{}).
+		Where("status = ? AND heartbeat_at < ?", StatusRunning, time.Now().Add(-staleAfter)).
+		Updates(map[string]interface{}{"status": StatusQueued, "started_at": nil, "heartbeat_at": nil})
+	if result.Error != nil {
+		log.Printf("Failed to requeue stale generation jobs: %v", result.Error)
+	} else if result.RowsAffected > 0 {
+		log.Printf("Requeued %d abandoned generation jobs", result.RowsAffected)
+	}
+}
+
+// run executes a claimed job and stores its outcome
+func run(job *models.GenerationJob) {
+	parent, cancel := context.WithCancelCause(context.Background())
+	ctx, stop := context.WithTimeout(parent, timeout)
+	defer stop()
+	defer cancel(nil)
+
+	runningMu.Lock()
+	running[job.ID] = cancel
+	runningMu.Unlock()
+	defer func() {
+		runningMu.Lock()
+		delete(running, job.ID)
+		runningMu.Unlock()
+	}()
+
+	go heartbeat(ctx, job.ID, cancel)
+
+	log.Printf("Running generation job %s for project %d", job.ID, job.ProjectID)
+	resp, err := runner(ctx, job)
+
+	now := time.Now()
+	updates := map[string]interface{}{"completed_at": now}
+	switch {
+	case err == nil:
+		result, merr := json.Marshal(resp)
+		if merr != nil {
+			updates["status"] = StatusFailed
+			updates["error"] = merr.Error()
+			break
+		}
+		updates["status"] = StatusSucceeded
+		updates["result"] = string(result)
+	case errors.Is(context.Cause(ctx), ErrCancelled):
+		// Cancel already stored the outcome
+		return
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		updates["status"] = StatusFailed
+		updates["error"] = fmt.Sprintf("job did not finish within %s", timeout)
+	default:
+		updates["status"] = StatusFailed
+		updates["error"] = err.Error()
+	}
+
+	result := database.DB.Model(&models.GenerationJob{}).
+		Where("id = ? AND status = ?", job.ID, StatusRunning).
+		Updates(updates)
+	if result.Error != nil {
+		log.Printf("Failed to store result of generation job %s: %v", job.ID, result.Error)
+		return
+	}
+	if result.RowsAffected == 0 {
+		// Cancelled on another replica in the meantime
+		return
+	}
+	log.Printf("Generation job %s %s", job.ID, updates["status"])
+	go deliver(job.ID)
+}
+
+// heartbeat marks the job as alive until ctx is done and cancels it once its
+// status changes, which happens when it is cancelled on another replica
+func heartbeat(ctx context.Context, id string, cancel context.CancelCauseFunc) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			result := database.DB.Model(&models.GenerationJob{}).
+				Where("id = ? AND status = ?", id, StatusRunning).
+				Update("heartbeat_at", time.Now())
+			if result.Error == nil && result.RowsAffected == 0 {
+				cancel(ErrCancelled)
+				return
+			}
+		}
+	}
+}
+
+// Cancel stops a queued or running job. It reports false when the job had
+// already finished.
+func Cancel(job *models.GenerationJob) (bool, error) {
+	now := time.Now()
+	result := database.DB.Model(&models.GenerationJob{}).
+		Where("id = ? AND status IN ?", job.ID, []string{StatusQueued, StatusRunning}).
+		Updates(map[string]interface{}{"status": StatusCancelled, "completed_at": now})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+
+	runningMu.Lock()
+	if cancel, ok := running[job.ID]; ok {
+		cancel(ErrCancelled)
+	}
+	runningMu.Unlock()
+
+	log.Printf("Generation job %s cancelled", job.ID)
+	go deliver(job.ID)
+	return true, nil
+}
+
+// Load returns the job with its response decoded
+func Load(id string, projectID uint) (*models.GenerationJob, error) {
+	var job models.GenerationJob
+	if err := database.DB.Where("id = ? AND project_id = ?", id, projectID).First(&job).Error; err != nil {
+		return nil, err
+	}
+	if err := decodeResult(&job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// decodeResult fills in the response of a succeeded job
+func decodeResult(job *models.GenerationJob) error {
+	if job.Result == "" {
+		return nil
+	}
+	var resp models.OllamaResponse
+	if err := json.Unmarshal([]byte(job.Result), &resp); err != nil {
+		return fmt.Errorf("failed to decode job result: %w", err)
+	}
+	job.Response = &resp
+	return nil
+}
@@ -0,0 +1,101 @@
+package jobs
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ollama-web-api/internal/database"
+	"github.com/ollama-web-api/internal/models"
+)
+
+// webhookAttempts is how often a webhook is tried before giving up
+const webhookAttempts = 3
+
+var webhookClient = &http.Client{Timeout: 10 * time.Second}
+
+// WebhookEvent is the body posted to the project webhook when a job finishes
+type WebhookEvent struct {
+	Event string                `json:"event" example:"job.succeeded"`
+	Job   *models.GenerationJob `json:"job"`
+}
+
+// Sign returns the signature of a webhook body: the hex HMAC-SHA256 of
+// "<timestamp>.<body>" keyed with the project webhook secret
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// deliver posts the outcome of a finished job to the webhook of its project,
+// retrying with backoff, and records whether it arrived
+func deliver(id string) {
+	var job models.GenerationJob
+	if err := database.DB.First(&job, "id = ?", id).Error; err != nil {
+		log.Printf("Failed to load generation job %s for its webhook: %v", id, err)
+		return
+	}
+	var project models.Project
+	if err := database.DB.First(&project, job.ProjectID).Error; err != nil || project.WebhookURL == "" {
+		return
+	}
+	if err := decodeResult(&job); err != nil {
+		log.Printf("Failed to decode generation job %s for its webhook: %v", id, err)
+	}
+
+	event := WebhookEvent{Event: "job." + job.Status, Job: &job}
+	body, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Failed to encode webhook for generation job %s: %v", id, err)
+		return
+	}
+
+	status := "failed"
+	for attempt := 1; attempt <= webhookAttempts; attempt++ {
+		if attempt > 1 {
+			time.Sleep(time.Duration(attempt*attempt) * time.Second)
+		}
+		if err = post(project.WebhookURL, project.WebhookSecret, event.Event, body); err == nil {
+			status = "delivered"
+			break
+		}
+		log.Printf("Webhook for generation job %s failed (attempt %d of %d): %v", id, attempt, webhookAttempts, err)
+	}
+
+	if err := database.DB.Model(&models.GenerationJob{}).Where("id = ?", id).Update("webhook_status", status).Error; err != nil {
+		log.Printf("Failed to record webhook status of generation job %s: %v", id, err)
+	}
+}
+
+// post sends a signed webhook body
+func post(url, secret, event string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Event", event)
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", "sha256="+Sign(secret, timestamp, body))
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
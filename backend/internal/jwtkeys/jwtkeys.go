@@ -0,0 +1,220 @@
+package jwtkeys
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/ollama-web-api/internal/models"
+)
+
+// defaultSecret was the fallback secret of earlier versions. It and the
+// placeholder from .env.example are only accepted in development.
+const defaultSecret = "default-secret-please-change-in-production"
+
+var placeholderSecrets = []string{
+	defaultSecret,
+	"your-secret-key-change-this-in-production",
+}
+
+// minSecretLength is the length below which a secret is warned about
+const minSecretLength = 32
+
+// Key signs or verifies admin tokens. Public is what verifies, and Private,
+// when set, what signs: a secret for HS256, or the private key of RS256 and
+// EdDSA keys.
+type Key struct {
+	ID      string
+	Method  jwt.SigningMethod
+	Private interface{}
+	Public  interface{}
+}
+
+var (
+	mu      sync.RWMutex
+	signing *Key
+	keys    map[string]*Key
+)
+
+// Setup loads the keys of admin tokens. Tokens are signed with the private
+// key in JWT_SIGNING_KEY (RS256 or EdDSA, as a PEM file or PEM text) when
+// set, and with JWT_SECRET (HS256) otherwise. Tokens signed with JWT_SECRET,
+// the secrets in JWT_PREVIOUS_SECRETS or the public keys in the PEM files of
+// JWT_VERIFICATION_KEYS are still accepted, so keys can be rotated without
+// logging everyone out. Unless APP_ENV is development, the server refuses to
+// start without a key or with the default secret.
+func Setup() error {
+	development := strings.EqualFold(os.Getenv("APP_ENV"), "development")
+	secret := os.Getenv("JWT_SECRET")
+	signingKey := strings.TrimSpace(os.Getenv("JWT_SIGNING_KEY"))
+
+	var (
+		sign     *Key
+		accepted []*Key
+		err      error
+	)
+	if signingKey != "" {
+		if sign, err = loadPrivateKey(signingKey); err != nil {
+			return fmt.Errorf("JWT_SIGNING_KEY: %w", err)
+		}
+		accepted = append(accepted, sign)
+	}
+
+	if secret == "" && sign == nil {
+		if !development {
+			return errors.New("JWT_SECRET or JWT_SIGNING_KEY is required; set APP_ENV=development to use a default secret")
+		}
+		log.Println("Warning: JWT_SECRET is not set; using the default secret, which is only fit for development")
+		secret = defaultSecret
+	}
+	if secret != "" {
+		key, err := secretKey(secret, !development)
+		if err != nil {
+			return fmt.Errorf("JWT_SECRET: %w", err)
+		}
+		if sign == nil {
+			sign = key
+		}
+		accepted = append(accepted, key)
+	}
+
+	for _, previous := range splitList(os.Getenv("JWT_PREVIOUS_SECRETS")) {
+		key, err := secretKey(previous, !development)
+		if err != nil {
+			return fmt.Errorf("JWT_PREVIOUS_SECRETS: %w", err)
+		}
+		accepted = append(accepted, key)
+	}
+	for _, path := range splitList(os.Getenv("JWT_VERIFICATION_KEYS")) {
+		key, err := loadPublicKey(path)
+		if err != nil {
+			return fmt.Errorf("JWT_VERIFICATION_KEYS: %w", err)
+		}
+		accepted = append(accepted, key)
+	}
+
+	byID := make(map[string]*Key, len(accepted))
+	for _, key := range accepted {
+		if _, ok := byID[key.ID]; !ok {
+			byID[key.ID] = key
+		}
+	}
+
+	mu.Lock()
+	signing, keys = sign, byID
+	mu.Unlock()
+	log.Printf("Admin tokens are signed with %s key %s, %d keys accepted", sign.Method.Alg(), sign.ID, len(byID))
+	return nil
+}
+
+// secretKey makes an HS256 key of a secret. With strict, placeholder secrets
+// are refused, since anyone could sign tokens with them.
+func secretKey(secret string, strict bool) (*Key, error) {
+	for _, placeholder := range placeholderSecrets {
+		if secret == placeholder && strict {
+			return nil, errors.New("default and example secrets must not be used; set a random secret of at least 32 characters")
+		}
+	}
+	if len(secret) < minSecretLength && secret != defaultSecret {
+		log.Printf("Warning: a JWT secret is shorter than %d characters", minSecretLength)
+	}
+
+	// The ID is a hash, so it does not reveal the secret
+	sum := sha256.Sum256([]byte(secret))
+	return &Key{
+		ID:      "hs256-" + hex.EncodeToString(sum[:8]),
+		Method:  jwt.SigningMethodHS256,
+		Private: []byte(secret),
+		Public:  []byte(secret),
+	}, nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Sign signs claims with the signing key and names the key in the kid header
+func Sign(claims jwt.Claims) (string, error) {
+	mu.RLock()
+	key := signing
+	mu.RUnlock()
+	if key == nil {
+		return "", errors.New("no JWT signing key is configured")
+	}
+
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.Private)
+}
+
+// Methods lists the signing methods of the accepted keys, for
+// jwt.WithValidMethods
+func Methods() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	seen := map[string]bool{}
+	var methods []string
+	for _, key := range keys {
+		if alg := key.Method.Alg(); !seen[alg] {
+			seen[alg] = true
+			methods = append(methods, alg)
+		}
+	}
+	return methods
+}
+
+// Keyfunc returns the key that verifies a token, by its kid header. The key
+// must be of the token's algorithm, so a public key cannot be used as an
+// HS256 secret. Tokens without a key ID are refused.
+func Keyfunc(token *jwt.Token) (interface{}, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, errors.New("token has no key ID")
+	}
+
+	key, ok := keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+	if key.Method.Alg() != token.Method.Alg() {
+		return nil, fmt.Errorf("key %q is not a %s key", kid, token.Method.Alg())
+	}
+	return key.Public, nil
+}
+
+// JWKS returns the public keys that verify admin tokens. Secrets are never
+// published, so it is empty when tokens are signed with JWT_SECRET.
+func JWKS() models.JSONWebKeySet {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	set := models.JSONWebKeySet{Keys: []models.JSONWebKey{}}
+	// The signing key comes first, then the others by key ID
+	if signing != nil && signing.Method != jwt.SigningMethodHS256 {
+		set.Keys = append(set.Keys, publicJWK(signing))
+	}
+	for _, id := range sortedIDs(keys) {
+		key := keys[id]
+		if key == signing || key.Method == jwt.SigningMethodHS256 {
+			continue
+		}
+		set.Keys = append(set.Keys, publicJWK(key))
+	}
+	return set
+}
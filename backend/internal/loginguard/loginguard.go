@@ -0,0 +1,285 @@
+package loginguard
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ollama-web-api/internal/database"
+	"github.com/ollama-web-api/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Kinds of throttled keys
+const (
+	KindIP       = "ip"
+	KindUsername = "username"
+)
+
+// baseDelay is how long the first failure blocks further attempts. It
+// doubles with every further failure up to maxDelay, until the lockout.
+const (
+	baseDelay = time.Second
+	maxDelay  = time.Minute
+)
+
+var (
+	maxUsernameFailures = 5
+	maxIPFailures       = 20
+	lockout             = 15 * time.Minute
+)
+
+// Setup reads LOGIN_MAX_FAILURES, the failures after which a username is
+// locked out (default 5), LOGIN_MAX_FAILURES_PER_IP, the same for a client
+// IP (default 20), and LOGIN_LOCKOUT, how long a lockout lasts and how long
+// failures are remembered (default 15m)
+func Setup() {
+	maxUsernameFailures = intEnv("LOGIN_MAX_FAILURES", maxUsernameFailures)
+	maxIPFailures = intEnv("LOGIN_MAX_FAILURES_PER_IP", maxIPFailures)
+	if v := os.Getenv("LOGIN_LOCKOUT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			lockout = d
+		} else {
+			log.Printf("Invalid LOGIN_LOCKOUT %q, using %s", v, lockout)
+		}
+	}
+	log.Printf("Logins are locked out for %s after %d failures per username or %d per IP",
+		lockout, maxUsernameFailures, maxIPFailures)
+}
+
+func intEnv(name string, fallback int) int {
+	v := os.Getenv(name)
+	if v == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		log.Printf("Invalid %s %q, using %d", name, v, fallback)
+		return fallback
+	}
+	return n
+}
+
+// Window is how long failures are remembered
+func Window() time.Duration {
+	return lockout
+}
+
+// keys returns the throttled keys of a login attempt. Usernames are compared
+// case-insensitively, so variants of a name share one count.
+func keys(ip, username string) map[string]string {
+	k := map[string]string{KindIP: ip}
+	if username = strings.ToLower(strings.TrimSpace(username)); username != "" {
+		k[KindUsername] = username
+	}
+	return k
+}
+
+// Check returns how long the client IP and the username have to wait before
+// they may try to log in again, or 0
+func Check(ip, username string) (time.Duration, error) {
+	now := time.Now()
+	var wait time.Duration
+	for kind, key := range keys(ip, username) {
+		var throttle models.LoginThrottle
+		err := database.DB.Where("kind = ? AND key = ? AND blocked_until > ?", kind, key, now).Limit(1).Find(&throttle).Error
+		if err != nil {
+			return 0, err
+		}
+		if throttle.ID != 0 && throttle.BlockedUntil.Sub(now) > wait {
+			wait = throttle.BlockedUntil.Sub(now)
+		}
+	}
+	return wait, nil
+}
+
+// Reserve counts a login attempt of the client IP and the username as
+// failed before it is checked, so concurrent attempts cannot get past the
+// limit. It returns how long they have to wait when the attempt is refused,
+// or 0. An attempt that fails is completed with Confirm, one that succeeds
+// with Succeeded and any other with Release.
+func Reserve(ip, username string) (time.Duration, error) {
+	now := time.Now()
+	cleanup(now)
+
+	var (
+		reserved []models.LoginThrottle
+		wait     time.Duration
+	)
+	for kind, key := range keys(ip, username) {
+		throttle, err := increment(kind, key, now)
+		if err != nil {
+			releaseAll(reserved)
+			return 0, err
+		}
+		reserved = append(reserved, *throttle)
+
+		switch {
+		case throttle.BlockedUntil.After(now):
+			wait = max(wait, throttle.BlockedUntil.Sub(now))
+		case throttle.Failures > maxFailures(kind):
+			// Attempts in progress took the remaining ones
+			wait = max(wait, baseDelay)
+		}
+	}
+	if wait > 0 {
+		releaseAll(reserved)
+	}
+	return wait, nil
+}
+
+// Release takes back an attempt reserved by Reserve that neither failed nor
+// succeeded
+func Release(ip, username string) error {
+	for kind, key := range keys(ip, username) {
+		err := database.DB.Model(&models.LoginThrottle{}).
+			Where("kind = ? AND key = ? AND failures > 0", kind, key).
+			Update("failures", gorm.Expr("failures - 1")).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func releaseAll(reserved []models.LoginThrottle) {
+	for _, throttle := range reserved {
+		err := database.DB.Model(&models.LoginThrottle{}).
+			Where("id = ? AND failures > 0", throttle.ID).
+			Update("failures", gorm.Expr("failures - 1")).Error
+		if err != nil {
+			log.Printf("Failed to release login attempt of %s %s: %v", throttle.Kind, throttle.Key, err)
+		}
+	}
+}
+
+// Confirm blocks the client IP and the username after an attempt reserved
+// by Reserve failed and returns the throttles that were locked out by it
+func Confirm(ip, username string) ([]models.LoginThrottle, error) {
+	now := time.Now()
+	var locked []models.LoginThrottle
+	for kind, key := range keys(ip, username) {
+		var throttle models.LoginThrottle
+		if err := database.DB.Where("kind = ? AND key = ?", kind, key).Limit(1).Find(&throttle).Error; err != nil {
+			return locked, err
+		}
+		if throttle.ID == 0 {
+			continue
+		}
+		if err := block(&throttle, now); err != nil {
+			return locked, err
+		}
+		if throttle.Locked {
+			locked = append(locked, throttle)
+		}
+	}
+	return locked, nil
+}
+
+// Failed counts a failed login of the client IP and the username and returns
+// the throttles that were locked out by it
+func Failed(ip, username string) ([]models.LoginThrottle, error) {
+	now := time.Now()
+	cleanup(now)
+
+	var locked []models.LoginThrottle
+	for kind, key := range keys(ip, username) {
+		throttle, err := increment(kind, key, now)
+		if err != nil {
+			return locked, err
+		}
+		if err := block(throttle, now); err != nil {
+			return locked, err
+		}
+		if throttle.Locked {
+			locked = append(locked, *throttle)
+		}
+	}
+	return locked, nil
+}
+
+// increment atomically counts a failure of a key, starting over when the
+// last one is older than the window, and returns the throttle as it is now
+func increment(kind, key string, now time.Time) (*models.LoginThrottle, error) {
+	throttle := models.LoginThrottle{Kind: kind, Key: key, Failures: 1, LastFailureAt: now}
+	err := database.DB.Clauses(
+		clause.OnConflict{
+			Columns: []clause.Column{{Name: "kind"}, {Name: "key"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"failures":        gorm.Expr("CASE WHEN login_throttles.last_failure_at < ? THEN 1 ELSE login_throttles.failures + 1 END", now.Add(-lockout)),
+				"last_failure_at": now,
+			}),
+		},
+		clause.Returning{},
+	).Create(&throttle).Error
+	if err != nil {
+		return nil, err
+	}
+	return &throttle, nil
+}
+
+// block blocks a key according to its failures. Locked is only left set
+// when this failure starts a lockout.
+func block(throttle *models.LoginThrottle, now time.Time) error {
+	wasLocked := throttle.Locked && throttle.BlockedUntil.After(now)
+	if throttle.Failures >= maxFailures(throttle.Kind) {
+		throttle.BlockedUntil = now.Add(lockout)
+		throttle.Locked = true
+	} else {
+		throttle.BlockedUntil = now.Add(backoff(throttle.Failures))
+		throttle.Locked = false
+	}
+	err := database.DB.Model(throttle).Updates(map[string]interface{}{
+		"blocked_until": throttle.BlockedUntil,
+		"locked":        throttle.Locked,
+	}).Error
+	if err != nil {
+		return err
+	}
+	if wasLocked {
+		throttle.Locked = false
+	}
+	return nil
+}
+
+// maxFailures returns the failures after which a key of the kind is locked
+// out
+func maxFailures(kind string) int {
+	if kind == KindIP {
+		return maxIPFailures
+	}
+	return maxUsernameFailures
+}
+
+// backoff returns how long the given number of failures blocks further
+// attempts: 1s, 2s, 4s and so on, up to a minute or the lockout
+func backoff(failures int) time.Duration {
+	delay := baseDelay
+	for i := 1; i < failures && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxDelay, lockout)
+}
+
+// Succeeded forgets the failures of the username after a successful login.
+// The failures of the client IP are left to expire, so logging in to an
+// account of one's own does not reset the limit on guessing others.
+func Succeeded(username string) error {
+	key, ok := keys("", username)[KindUsername]
+	if !ok {
+		return nil
+	}
+	return database.DB.Where("kind = ? AND key = ?", KindUsername, key).Delete(&models.LoginThrottle{}).Error
+}
+
+// cleanup deletes throttles whose failures are forgotten and that no longer
+// block, as failures are counted
+func cleanup(now time.Time) {
+	err := database.DB.Where("last_failure_at < ? AND blocked_until < ?", now.Add(-lockout), now).Delete(&models.LoginThrottle{}).Error
+	if err != nil {
+		log.Printf("Failed to delete old login throttles: %v", err)
+	}
+}
@@ -1,45 +1,99 @@
 package middleware
 
 import (
-	"os"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/netip"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/ollama-web-api/internal/apikeys"
+	"github.com/ollama-web-api/internal/audit"
+	"github.com/ollama-web-api/internal/clientip"
+	"github.com/ollama-web-api/internal/database"
+	"github.com/ollama-web-api/internal/jwtkeys"
+	"github.com/ollama-web-api/internal/models"
+	"github.com/ollama-web-api/internal/roles"
+	"github.com/ollama-web-api/internal/users"
 )
 
-var jwtSecret []byte
-
-func init() {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		secret = "default-secret-please-change-in-production"
-	}
-	jwtSecret = []byte(secret)
-}
-
-// Claims represents JWT claims
+// Claims represents JWT claims. UserID attributes actions to the user and
+// Role decides which admin endpoints they may call. SessionID is the login
+// the token was issued for, and the token ID (jti) lets single tokens be
+// revoked.
 type Claims struct {
-	Username string `json:"username"`
+	UserID    uint   `json:"uid"`
+	Username  string `json:"username"`
+	Role      string `json:"role"`
+	SessionID uint   `json:"sid"`
 	jwt.RegisteredClaims
 }
 
-// GenerateToken generates a JWT token for the given username
-func GenerateToken(username string) (string, error) {
+// AccessToken is a signed JWT with its ID and expiry
+type AccessToken struct {
+	Token     string
+	ID        string
+	ExpiresAt time.Time
+}
+
+// GenerateToken generates a JWT token for the given user and session that
+// expires after ttl
+func GenerateToken(user *models.User, sessionID uint, ttl time.Duration) (*AccessToken, error) {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	access := &AccessToken{ID: hex.EncodeToString(bytes), ExpiresAt: now.Add(ttl)}
+
 	claims := &Claims{
-		Username: username,
+		UserID:    user.ID,
+		Username:  user.Username,
+		Role:      user.Role,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ID:        access.ID,
+			Subject:   strconv.FormatUint(uint64(user.ID), 10),
+			ExpiresAt: jwt.NewNumericDate(access.ExpiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+	token, err := jwtkeys.Sign(claims)
+	if err != nil {
+		return nil, err
+	}
+	access.Token = token
+	return access, nil
 }
 
-// AuthRequired middleware validates JWT token
+// tokenRevoked reports whether a token ID is on the revocation list or the
+// session of the token ended. The session check also covers tokens issued
+// before the session's last refresh, which are not on the list.
+func tokenRevoked(claims *Claims) (bool, error) {
+	var count int64
+	err := database.DB.Model(&models.RevokedToken{}).Where("jti = ?", claims.ID).Count(&count).Error
+	if err != nil || count > 0 {
+		return count > 0, err
+	}
+
+	err = database.DB.Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL AND expires_at > ?", claims.SessionID, time.Now()).
+		Count(&count).Error
+	return count == 0, err
+}
+
+// AuthRequired middleware validates JWT token. The token must not be revoked
+// and its session must not have ended, the user must still exist and be
+// active, so disabling a user ends their sessions, and must still have the
+// role of the token. The user is stored in the "user" local, its ID, name and
+// role in "user_id", "username" and "role", and the session of the token in
+// "session_id".
 func AuthRequired() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		authHeader := c.Get("Authorization")
@@ -57,33 +111,222 @@ func AuthRequired() fiber.Handler {
 		}
 
 		claims := &Claims{}
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return jwtSecret, nil
-		})
+		token, err := jwt.ParseWithClaims(tokenString, claims, jwtkeys.Keyfunc, jwt.WithValidMethods(jwtkeys.Methods()))
 
-		if err != nil || !token.Valid {
+		// Tokens of earlier versions have no ID or session and cannot be
+		// revoked
+		if err != nil || !token.Valid || claims.UserID == 0 || claims.ID == "" || claims.SessionID == 0 {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"error": "Invalid or expired token",
 			})
 		}
 
-		c.Locals("username", claims.Username)
+		revoked, err := tokenRevoked(claims)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error":   "Failed to validate token",
+				"message": err.Error(),
+			})
+		}
+		if revoked {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Token revoked",
+			})
+		}
+
+		var user models.User
+		if err := database.DB.Limit(1).Find(&user, claims.UserID).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error":   "Failed to validate token",
+				"message": err.Error(),
+			})
+		}
+		if user.ID == 0 || !user.IsActive {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "User not found or disabled",
+			})
+		}
+		if claims.Role != user.Role {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error":   "Role changed",
+				"message": "The role of the user has changed; log in again",
+			})
+		}
+
+		c.Locals("user", &user)
+		c.Locals("user_id", user.ID)
+		c.Locals("username", user.Username)
+		c.Locals("role", user.Role)
+		c.Locals("session_id", claims.SessionID)
+		return c.Next()
+	}
+}
+
+// RequireRole middleware allows the request when the user authenticated by
+// AuthRequired has at least the given role. When the security policy makes
+// two-factor authentication mandatory, users without it are refused until
+// they set it up; the routes to do so require no role.
+func RequireRole(minimum string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		role, _ := c.Locals("role").(string)
+		if !roles.AtLeast(role, minimum) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error":   "Insufficient role",
+				"message": fmt.Sprintf("This action requires the '%s' role", minimum),
+			})
+		}
+
+		if user, ok := c.Locals("user").(*models.User); ok {
+			needsSetup, err := users.NeedsTwoFactorSetup(user)
+			if err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error":   "Failed to check security policy",
+					"message": err.Error(),
+				})
+			}
+			if needsSetup {
+				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+					"error":   "Two-factor authentication required",
+					"message": "Set up two-factor authentication at /api/auth/2fa/setup to continue",
+					"code":    "two_factor_setup_required",
+				})
+			}
+		}
 		return c.Next()
 	}
 }
 
-// ValidateAPIKey middleware validates project API key
+// lastUsedInterval is how stale the last use of an API key may get before
+// it is written again, to avoid a write on every request
+const lastUsedInterval = time.Minute
+
+// findAPIKey returns the valid API key matching key, or nil. Keys are looked
+// up by their prefix and then compared in constant time.
+func findAPIKey(key string) (*models.APIKey, error) {
+	var candidates []models.APIKey
+	if err := database.DB.
+		Where("prefix = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", apikeys.Prefix(key), time.Now()).
+		Find(&candidates).Error; err != nil {
+		return nil, err
+	}
+	for i := range candidates {
+		if apikeys.Verify(key, candidates[i].Salt, candidates[i].Hash) {
+			return &candidates[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// touchAPIKey records that an API key was used
+func touchAPIKey(key *models.APIKey) {
+	now := time.Now()
+	if key.LastUsedAt != nil && now.Sub(*key.LastUsedAt) < lastUsedInterval {
+		return
+	}
+	if err := database.DB.Model(&models.APIKey{}).Where("id = ?", key.ID).Update("last_used_at", now).Error; err != nil {
+		log.Printf("Failed to record use of API key %d: %v", key.ID, err)
+	}
+}
+
+// clientIPAllowed reports whether the client lies in the allowlists of the
+// project and the key. Refused clients are recorded in the audit log.
+func clientIPAllowed(c *fiber.Ctx, project *models.Project, key *models.APIKey) (netip.Addr, bool) {
+	ip := clientip.Get(c)
+	if clientip.Allowed(project.AllowedCIDRs, ip) && clientip.Allowed(key.AllowedCIDRs, ip) {
+		return ip, true
+	}
+
+	audit.Record(&models.AuditEvent{
+		Action:    audit.ActionIPDenied,
+		ProjectID: &project.ID,
+		APIKeyID:  &key.ID,
+		IP:        ip.String(),
+		Detail:    c.Method() + " " + c.Path(),
+	})
+	return ip, false
+}
+
+// apiKeyError writes an error of ValidateAPIKey. title is the short error,
+// message may be empty and code is the OpenAI error code.
+type apiKeyError func(c *fiber.Ctx, status int, title, message, code string) error
+
+// sendAPIKeyError writes an error in the format of the other API routes
+func sendAPIKeyError(c *fiber.Ctx, status int, title, message, code string) error {
+	body := fiber.Map{"error": title}
+	if message != "" {
+		body["message"] = message
+	}
+	return c.Status(status).JSON(body)
+}
+
+// sendOpenAIKeyError writes an error in the OpenAI error response format
+func sendOpenAIKeyError(c *fiber.Ctx, status int, title, message, code string) error {
+	errType := "authentication_error"
+	switch {
+	case status == fiber.StatusForbidden:
+		errType = "permission_error"
+	case status >= fiber.StatusInternalServerError:
+		errType = "api_error"
+	}
+	if message == "" {
+		message = title
+	}
+	var errCode interface{}
+	if code != "" {
+		errCode = code
+	}
+	return c.Status(status).JSON(models.OpenAIErrorResponse{
+		Error: models.OpenAIErrorDetail{Message: message, Type: errType, Code: errCode},
+	})
+}
+
+// ValidateAPIKey middleware validates project API key. The key is read from
+// the X-API-Key header, or from an "Authorization: Bearer" header for
+// OpenAI-compatible clients. Any valid key of a project authenticates it.
+// The key and its project are stored in the "api_key" and "project" locals
+// for the handlers, which check whether the project is active. Requests from
+// outside the allowed CIDRs of the project or key are refused.
 func ValidateAPIKey() fiber.Handler {
+	return validateAPIKey(sendAPIKeyError)
+}
+
+// ValidateOpenAIKey is ValidateAPIKey for the OpenAI-compatible routes, whose
+// errors are in the OpenAI error response format
+func ValidateOpenAIKey() fiber.Handler {
+	return validateAPIKey(sendOpenAIKeyError)
+}
+
+func validateAPIKey(sendError apiKeyError) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		apiKey := c.Get("X-API-Key")
+		if authHeader := c.Get("Authorization"); apiKey == "" && strings.HasPrefix(authHeader, "Bearer ") {
+			apiKey = strings.TrimSpace(strings.TrimPrefix(authHeader, "Bearer "))
+		}
 		if apiKey == "" {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Missing API key",
-			})
+			return sendError(c, fiber.StatusUnauthorized, "Missing API key", "", "missing_api_key")
+		}
+
+		key, err := findAPIKey(apiKey)
+		if err != nil {
+			return sendError(c, fiber.StatusInternalServerError, "Failed to validate API key", err.Error(), "")
+		}
+		if key == nil {
+			return sendError(c, fiber.StatusUnauthorized, "Invalid API key", "The API key is unknown, revoked or expired", "invalid_api_key")
+		}
+
+		var project models.Project
+		if err := database.DB.Preload("Models").First(&project, key.ProjectID).Error; err != nil {
+			// The project was deleted
+			return sendError(c, fiber.StatusUnauthorized, "Invalid API key", "Project not found with the provided API key", "invalid_api_key")
+		}
+		if ip, ok := clientIPAllowed(c, &project, key); !ok {
+			return sendError(c, fiber.StatusForbidden, "IP address not allowed",
+				fmt.Sprintf("Requests from %s are not allowed for this API key", ip), "ip_not_allowed")
 		}
+		go touchAPIKey(key)
 
-		// Store the API key in context for use in handlers
-		c.Locals("api_key", apiKey)
+		c.Locals("api_key", key)
+		c.Locals("project", &project)
 		return c.Next()
 	}
 }
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// DefaultBodyLimit is the largest request body accepted by routes without
+// a limit of their own
+const DefaultBodyLimit = 4 << 20
+
+// BodyLimit middleware reads the request body, refusing bodies larger than
+// limit bytes with 413. The server streams request bodies, so before this
+// runs it has read ahead at most its own body limit, DefaultBodyLimit by
+// default. Requests for which skip
+// returns true are passed on unread, for routes that apply a larger limit
+// later in their chain, e.g. after authentication; their connection is closed
+// if the body is left unread.
+func BodyLimit(limit int, skip func(c *fiber.Ctx) bool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if skip != nil && skip(c) {
+			err := c.Next()
+			if c.Request().IsBodyStream() {
+				// The route refused the request without reading the body
+				c.Context().SetConnectionClose()
+			}
+			return err
+		}
+
+		req := c.Request()
+		tooLarge := req.Header.ContentLength() > limit
+		if !tooLarge && req.IsBodyStream() {
+			body, err := io.ReadAll(io.LimitReader(req.BodyStream(), int64(limit)+1))
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error":   "Invalid request",
+					"message": err.Error(),
+				})
+			}
+			if tooLarge = len(body) > limit; !tooLarge {
+				req.SetBodyRaw(body)
+			}
+		}
+		if tooLarge {
+			// The rest of the body is not read, so the connection cannot be
+			// reused
+			c.Context().SetConnectionClose()
+			return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+				"error":   "Request body too large",
+				"message": fmt.Sprintf("Request bodies are limited to %d bytes", limit),
+			})
+		}
+		return c.Next()
+	}
+}
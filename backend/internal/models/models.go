@@ -1,52 +1,696 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
 	"gorm.io/gorm"
 )
 
-// Project represents a project in the system
+// GenerationSettings holds default generation options and hard limits that
+// are applied to every inference request. Unset fields are not enforced.
+type GenerationSettings struct {
+	DefaultSystem      string   `json:"default_system,omitempty" example:"You are a helpful assistant."`
+	DefaultTemperature *float64 `json:"default_temperature,omitempty" example:"0.7"`
+	DefaultNumCtx      *int     `json:"default_num_ctx,omitempty" example:"4096"`
+	MaxNumPredict      *int     `json:"max_num_predict,omitempty" example:"1024"`
+	MaxNumCtx          *int     `json:"max_num_ctx,omitempty" example:"8192"`
+	MaxPromptLength    *int     `json:"max_prompt_length,omitempty" example:"32000"` // in characters
+	MaxImages          *int     `json:"max_images,omitempty" example:"4"`
+	ClampToLimits      *bool    `json:"clamp_to_limits,omitempty" example:"false"` // clamp num_predict/num_ctx instead of rejecting
+}
+
+// RateLimitSettings configures per-project request budgets. Unset fields are not enforced.
+type RateLimitSettings struct {
+	RequestsPerMinute *int `json:"requests_per_minute,omitempty" example:"60"`
+	TokensPerMinute   *int `json:"tokens_per_minute,omitempty" example:"100000"`
+	MaxConcurrent     *int `json:"max_concurrent,omitempty" example:"2"`
+}
+
+// QuotaSettings configures per-project token quotas. Crossing a soft limit
+// adds a warning header; crossing a hard limit refuses requests until the
+// period resets. Unset fields are not enforced.
+type QuotaSettings struct {
+	DailySoftLimit   *int `json:"daily_soft_limit,omitempty" example:"800000"`
+	DailyHardLimit   *int `json:"daily_hard_limit,omitempty" example:"1000000"`
+	MonthlySoftLimit *int `json:"monthly_soft_limit,omitempty" example:"16000000"`
+	MonthlyHardLimit *int `json:"monthly_hard_limit,omitempty" example:"20000000"`
+}
+
+// SchedulingSettings configures how the requests of a project are queued
+// while the backends are saturated. Higher priority tiers are always served
+// first; within a tier, projects share capacity in proportion to their weight.
+type SchedulingSettings struct {
+	Priority string `json:"priority,omitempty" example:"normal"` // high, normal or low (default normal)
+	Weight   *int   `json:"weight,omitempty" example:"1"`
+}
+
+// Project represents a project in the system. APIKey holds the first key of
+// the project only in the response that creates it. Results of generation
+// jobs are posted to WebhookURL, signed with WebhookSecret, which is never
+// shown; NewWebhookSecret holds it only in the response that creates or
+// rotates it. When AllowedCIDRs is set, the project's keys only work from
+// those networks.
 type Project struct {
-	ID          uint           `gorm:"primaryKey" json:"id"`
-	Name        string         `gorm:"uniqueIndex;not null" json:"name"`
-	Description string         `json:"description"`
-	APIKey      string         `gorm:"uniqueIndex;not null" json:"api_key"`
-	IsActive    bool           `gorm:"default:true" json:"is_active"`
-	Models      []ProjectModel `gorm:"foreignKey:ProjectID" json:"models,omitempty"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
-}
-
-// ProjectModel represents the many-to-many relationship between projects and available models
+	ID               uint               `gorm:"primaryKey" json:"id"`
+	Name             string             `gorm:"uniqueIndex;not null" json:"name"`
+	Description      string             `json:"description"`
+	APIKey           string             `gorm:"-" json:"api_key,omitempty"`
+	IsActive         bool               `gorm:"default:true" json:"is_active"`
+	Settings         GenerationSettings `gorm:"embedded;embeddedPrefix:settings_" json:"settings"`
+	RateLimits       RateLimitSettings  `gorm:"embedded;embeddedPrefix:rate_limit_" json:"rate_limits"`
+	Quotas           QuotaSettings      `gorm:"embedded;embeddedPrefix:quota_" json:"quotas"`
+	Scheduling       SchedulingSettings `gorm:"embedded;embeddedPrefix:queue_" json:"scheduling"`
+	WebhookURL       string             `json:"webhook_url,omitempty" example:"https://example.com/hooks/ollama"`
+	WebhookSecret    string             `json:"-"`
+	NewWebhookSecret string             `gorm:"-" json:"webhook_secret,omitempty"`
+	AllowedCIDRs     []string           `gorm:"serializer:json" json:"allowed_cidrs,omitempty" example:"10.0.0.0/8"`
+	Models           []ProjectModel     `gorm:"foreignKey:ProjectID" json:"models,omitempty"`
+	APIKeys          []APIKey           `gorm:"foreignKey:ProjectID" json:"api_keys,omitempty"`
+	CreatedAt        time.Time          `json:"created_at"`
+	UpdatedAt        time.Time          `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt     `gorm:"index" json:"-"`
+}
+
+// APIKey is a named API key of a project. Only its prefix and salted hash
+// are stored; Key holds the key itself only in the response that creates it.
+// A key stops working once revoked or past its expiry. Scopes limits what the
+// key may do, Models which of the project's models it may use and
+// AllowedCIDRs where it may be used from; when empty there is no limit.
+type APIKey struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	ProjectID    uint       `gorm:"not null;index" json:"project_id"`
+	Name         string     `gorm:"not null" json:"name" example:"production"`
+	Key          string     `gorm:"-" json:"key,omitempty"`
+	Prefix       string     `gorm:"size:16;not null;index" json:"prefix" example:"3f9a1c2b"`
+	Salt         string     `gorm:"not null" json:"-"`
+	Hash         string     `gorm:"not null" json:"-"`
+	Scopes       []string   `gorm:"serializer:json" json:"scopes,omitempty" example:"generate,chat"` // generate, chat, embed, models:read or batches
+	Models       []string   `gorm:"serializer:json" json:"models,omitempty" example:"llama2"`
+	AllowedCIDRs []string   `gorm:"serializer:json" json:"allowed_cidrs,omitempty" example:"203.0.113.0/24"`
+	CreatedBy    string     `json:"created_by,omitempty" example:"admin"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt   *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// ProjectModel represents the many-to-many relationship between projects and available models.
+// Settings override the project-level settings for this model.
 type ProjectModel struct {
+	ID        uint               `gorm:"primaryKey" json:"id"`
+	ProjectID uint               `gorm:"not null;index" json:"project_id"`
+	ModelName string             `gorm:"not null" json:"model_name"`
+	Settings  GenerationSettings `gorm:"embedded;embeddedPrefix:settings_" json:"settings"`
+	CreatedAt time.Time          `json:"created_at"`
+}
+
+// GenerateOptions represents the model parameters Ollama accepts in the
+// options field. Unset fields fall back to the model's Modelfile defaults.
+type GenerateOptions struct {
+	NumKeep          *int     `json:"num_keep,omitempty"`
+	Seed             *int     `json:"seed,omitempty" example:"42"`
+	NumPredict       *int     `json:"num_predict,omitempty" example:"128"`
+	TopK             *int     `json:"top_k,omitempty"`
+	TopP             *float64 `json:"top_p,omitempty"`
+	MinP             *float64 `json:"min_p,omitempty"`
+	TypicalP         *float64 `json:"typical_p,omitempty"`
+	RepeatLastN      *int     `json:"repeat_last_n,omitempty"`
+	Temperature      *float64 `json:"temperature,omitempty" example:"0.7"`
+	RepeatPenalty    *float64 `json:"repeat_penalty,omitempty"`
+	PresencePenalty  *float64 `json:"presence_penalty,omitempty"`
+	FrequencyPenalty *float64 `json:"frequency_penalty,omitempty"`
+	PenalizeNewline  *bool    `json:"penalize_newline,omitempty"`
+	Stop             []string `json:"stop,omitempty"`
+	Numa             *bool    `json:"numa,omitempty"`
+	NumCtx           *int     `json:"num_ctx,omitempty" example:"4096"`
+	NumBatch         *int     `json:"num_batch,omitempty"`
+	NumGPU           *int     `json:"num_gpu,omitempty"`
+	MainGPU          *int     `json:"main_gpu,omitempty"`
+	UseMMap          *bool    `json:"use_mmap,omitempty"`
+	NumThread        *int     `json:"num_thread,omitempty"`
+}
+
+// UsageRecord represents a single proxied generation and the resources it consumed.
+// Durations are in nanoseconds as reported by Ollama.
+type UsageRecord struct {
+	ID                 uint      `gorm:"primaryKey" json:"id"`
+	ProjectID          uint      `gorm:"not null;index:idx_usage_project_created" json:"project_id"`
+	Model              string    `gorm:"not null;index" json:"model"`
+	Endpoint           string    `gorm:"not null" json:"endpoint" example:"generate"`
+	Status             string    `gorm:"not null" json:"status" example:"success"` // success, error, incomplete or cancelled
+	StatusCode         int       `json:"status_code" example:"200"`
+	PromptTokens       int       `json:"prompt_tokens"`
+	CompletionTokens   int       `json:"completion_tokens"`
+	TotalTokens        int       `json:"total_tokens"`
+	TotalDuration      int64     `json:"total_duration"`
+	LoadDuration       int64     `json:"load_duration"`
+	PromptEvalDuration int64     `json:"prompt_eval_duration"`
+	EvalDuration       int64     `json:"eval_duration"`
+	CreatedAt          time.Time `gorm:"index:idx_usage_project_created;index" json:"created_at"`
+}
+
+// GenerationJob is a generate request processed in the background. Request
+// and Result hold the JSON of the Ollama request and response. When it
+// starts, APIKeyID, the key that queued it, must still be valid and allowed
+// the model.
+type GenerationJob struct {
+	ID            string          `gorm:"primaryKey;size:64" json:"id" example:"job-5f1c0d3a9b2e4c7d8e6f0a1b"`
+	ProjectID     uint            `gorm:"not null;index" json:"project_id"`
+	APIKeyID      *uint           `json:"api_key_id,omitempty"`
+	Model         string          `gorm:"not null" json:"model" example:"llama2"`
+	Status        string          `gorm:"not null;index" json:"status" example:"queued"` // queued, running, succeeded, failed or cancelled
+	Request       string          `gorm:"type:text;not null" json:"-"`
+	Result        string          `gorm:"type:text" json:"-"`
+	Response      *OllamaResponse `gorm:"-" json:"response,omitempty"`
+	Error         string          `json:"error,omitempty"`
+	WebhookStatus string          `json:"webhook_status,omitempty" example:"delivered"` // delivered or failed
+	CreatedAt     time.Time       `gorm:"index" json:"created_at"`
+	StartedAt     *time.Time      `json:"started_at,omitempty"`
+	CompletedAt   *time.Time      `json:"completed_at,omitempty"`
+	HeartbeatAt   *time.Time      `json:"-"`
+}
+
+// Batch is a JSONL file of generate and chat requests processed in the
+// background. It stops once APIKeyID, the key that uploaded it, is revoked
+// or expires.
+type Batch struct {
+	ID          string     `gorm:"primaryKey;size:64" json:"id" example:"batch-5f1c0d3a9b2e4c7d8e6f0a1b"`
+	ProjectID   uint       `gorm:"not null;index" json:"project_id"`
+	APIKeyID    *uint      `json:"api_key_id,omitempty"`
+	Filename    string     `json:"filename,omitempty" example:"evals.jsonl"`
+	Status      string     `gorm:"not null;index" json:"status" example:"in_progress"` // queued, in_progress, completed, failed or cancelled
+	Total       int        `json:"total" example:"1000"`
+	Completed   int        `json:"completed" example:"750"`
+	Failed      int        `json:"failed" example:"3"`
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   time.Time  `gorm:"index" json:"created_at"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	HeartbeatAt *time.Time `json:"-"`
+}
+
+// BatchItem is a single request of a batch. Request and Result hold the
+// JSON of the request body and of the response.
+type BatchItem struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	BatchID     string     `gorm:"size:64;not null;index:idx_batch_items_batch_line,priority:1" json:"batch_id"`
+	Line        int        `gorm:"not null;index:idx_batch_items_batch_line,priority:2" json:"line"`
+	CustomID    string     `gorm:"not null" json:"custom_id"`
+	URL         string     `gorm:"not null" json:"url"`
+	Request     string     `gorm:"type:text;not null" json:"-"`
+	Status      string     `gorm:"not null" json:"status"` // pending, succeeded, failed or cancelled
+	StatusCode  int        `json:"status_code,omitempty"`
+	Result      string     `gorm:"type:text" json:"-"`
+	Error       string     `json:"error,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// BatchRequestLine is a line of an uploaded batch file
+type BatchRequestLine struct {
+	CustomID string          `json:"custom_id" example:"request-1"`
+	Method   string          `json:"method,omitempty" example:"POST"`
+	URL      string          `json:"url" example:"/api/ollama/generate"` // /api/ollama/generate or /api/ollama/chat
+	Body     json.RawMessage `json:"body" swaggertype:"object"`
+}
+
+// BatchResultLine is a line of the results file of a batch. Response is set
+// for requests that ran, Error for requests that failed or never ran.
+type BatchResultLine struct {
+	ID       string               `json:"id" example:"batch-5f1c0d3a9b2e4c7d8e6f0a1b-1"`
+	CustomID string               `json:"custom_id" example:"request-1"`
+	Response *BatchResultResponse `json:"response"`
+	Error    *BatchResultError    `json:"error"`
+}
+
+// BatchResultResponse is the response to a batch request
+type BatchResultResponse struct {
+	StatusCode int             `json:"status_code" example:"200"`
+	Body       json.RawMessage `json:"body" swaggertype:"object"`
+}
+
+// BatchResultError describes why a batch request failed
+type BatchResultError struct {
+	Code    string `json:"code" example:"request_failed"` // request_failed or cancelled
+	Message string `json:"message"`
+}
+
+// UsageSummary represents aggregated usage for one time bucket, project and model
+type UsageSummary struct {
+	Bucket           time.Time `json:"bucket"`
+	ProjectID        uint      `json:"project_id"`
+	ProjectName      string    `json:"project_name"`
+	Model            string    `json:"model"`
+	Requests         int64     `json:"requests"`
+	Errors           int64     `json:"errors"`
+	PromptTokens     int64     `json:"prompt_tokens"`
+	CompletionTokens int64     `json:"completion_tokens"`
+	TotalTokens      int64     `json:"total_tokens"`
+	TotalDuration    int64     `json:"total_duration"` // nanoseconds
+	EvalDuration     int64     `json:"eval_duration"`  // nanoseconds
+}
+
+// QuotaTopUp grants a project extra tokens for a single quota period
+type QuotaTopUp struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	ProjectID   uint      `gorm:"not null;index" json:"project_id"`
+	Period      string    `gorm:"not null" json:"period" example:"daily"` // daily or monthly
+	PeriodStart time.Time `gorm:"not null" json:"period_start"`
+	Tokens      int       `gorm:"not null" json:"tokens" example:"100000"`
+	Note        string    `json:"note,omitempty" example:"Release week"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// QuotaStatus reports the consumption of a project in its current quota period
+type QuotaStatus struct {
+	Period       string    `json:"period" example:"daily"`
+	PeriodStart  time.Time `json:"period_start"`
+	ResetsAt     time.Time `json:"resets_at"`
+	Used         int       `json:"used" example:"420000"`
+	TopUp        int       `json:"top_up" example:"0"`
+	SoftLimit    *int      `json:"soft_limit,omitempty" example:"800000"`
+	HardLimit    *int      `json:"hard_limit,omitempty" example:"1000000"`
+	Remaining    *int      `json:"remaining,omitempty" example:"580000"` // until the hard limit, including top-ups
+	SoftExceeded bool      `json:"soft_exceeded"`
+	HardExceeded bool      `json:"hard_exceeded"`
+}
+
+// OllamaBackend is an Ollama server registered in the backend pool. Backends
+// from OLLAMA_BACKENDS are added to the pool without being stored.
+type OllamaBackend struct {
 	ID        uint      `gorm:"primaryKey" json:"id"`
-	ProjectID uint      `gorm:"not null;index" json:"project_id"`
-	ModelName string    `gorm:"not null" json:"model_name"`
+	Name      string    `gorm:"uniqueIndex;not null" json:"name" example:"gpu-1"`
+	URL       string    `gorm:"not null" json:"url" example:"http://10.0.0.11:11434"`
+	Enabled   bool      `gorm:"default:true" json:"enabled"`
 	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// BackendStatus reports the health of a backend in the pool
+type BackendStatus struct {
+	ID           uint      `json:"id,omitempty"` // zero for backends from OLLAMA_BACKENDS
+	Name         string    `json:"name" example:"gpu-1"`
+	URL          string    `json:"url" example:"http://10.0.0.11:11434"`
+	Healthy      bool      `json:"healthy"`
+	Models       []string  `json:"models"`
+	LoadedModels []string  `json:"loaded_models"`
+	InFlight     int64     `json:"in_flight"`
+	LastChecked  time.Time `json:"last_checked"`
+	LastError    string    `json:"last_error,omitempty"`
+}
+
+// QueueStatus reports the live queue depth of a model
+type QueueStatus struct {
+	Model             string               `json:"model" example:"llama2:latest"`
+	Waiting           int                  `json:"waiting"`
+	InFlight          int                  `json:"in_flight"`
+	OldestWaitSeconds float64              `json:"oldest_wait_seconds"`
+	Projects          []QueueProjectStatus `json:"projects"`
+}
+
+// QueueProjectStatus reports the queued and running requests of a project
+// for a model
+type QueueProjectStatus struct {
+	ProjectID uint   `json:"project_id"`
+	Priority  string `json:"priority,omitempty" example:"normal"`
+	Waiting   int    `json:"waiting"`
+	InFlight  int    `json:"in_flight"`
+}
+
+// RateLimitCounter counts requests and tokens per bucket and minute window.
+// It is only used when rate limits are shared through Postgres.
+type RateLimitCounter struct {
+	Bucket      string    `gorm:"primaryKey" json:"bucket"`
+	WindowStart time.Time `gorm:"primaryKey" json:"window_start"`
+	Requests    int       `gorm:"not null;default:0" json:"requests"`
+	Tokens      int       `gorm:"not null;default:0" json:"tokens"`
+}
+
+// RateLimitLease represents an in-flight request holding a concurrency slot.
+// It is only used when rate limits are shared through Postgres.
+type RateLimitLease struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Bucket    string    `gorm:"not null;index" json:"bucket"`
+	ExpiresAt time.Time `gorm:"not null;index" json:"expires_at"`
+}
+
+// User is an admin who can log in to the management API. Only the bcrypt
+// hash of the password is stored. Role decides which admin endpoints the user
+// may call. Disabled users cannot log in and their tokens stop working. Users
+// of the "oidc" provider sign in through single sign-on, have no password and
+// get their role from the role mappings on every login.
+type User struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	Username     string     `gorm:"uniqueIndex;not null" json:"username" example:"alice"`
+	PasswordHash string     `gorm:"not null" json:"-"`
+	Role         string     `gorm:"not null;default:admin" json:"role" example:"operator"`  // viewer, operator, admin or owner
+	Provider     string     `gorm:"not null;default:local" json:"provider" example:"local"` // local or oidc
+	ExternalID   *string    `gorm:"uniqueIndex" json:"-"`                                   // issuer and subject of OIDC users
+	TOTPSecret   string     `json:"-"`
+	TOTPEnabled  bool       `gorm:"not null;default:false" json:"totp_enabled"`
+	TOTPLastStep int64      `json:"-"` // time step of the last code used, so codes work once
+	IsActive     bool       `gorm:"default:true" json:"is_active"`
+	LastLoginAt  *time.Time `json:"last_login_at,omitempty"`
+	CreatedBy    string     `json:"created_by,omitempty" example:"admin"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// AuditEvent records a security-relevant event, such as a call refused by an
+// IP allowlist
+type AuditEvent struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Action    string    `gorm:"not null;index" json:"action" example:"ip_denied"`
+	Actor     string    `json:"actor,omitempty" example:"admin"`
+	ProjectID *uint     `gorm:"index" json:"project_id,omitempty"`
+	APIKeyID  *uint     `json:"api_key_id,omitempty"`
+	IP        string    `json:"ip,omitempty" example:"203.0.113.7"`
+	Detail    string    `json:"detail,omitempty" example:"POST /api/ollama/generate"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
+
+// Session is a login of a user. The access tokens of a session are short-lived
+// and renewed with its refresh token, which changes on every use; only the
+// hashes of the current and previous refresh tokens are stored, so that reuse
+// of a replaced token can be detected. Sessions expire when they are not
+// refreshed in time, and end when revoked.
+type Session struct {
+	ID                   uint       `gorm:"primaryKey" json:"id"`
+	UserID               uint       `gorm:"not null;index" json:"user_id"`
+	User                 *User      `json:"user,omitempty"`
+	RefreshHash          string     `gorm:"uniqueIndex;not null" json:"-"`
+	PreviousRefreshHash  string     `gorm:"index" json:"-"`
+	AccessTokenID        string     `json:"-"`
+	AccessTokenExpiresAt time.Time  `json:"-"`
+	IP                   string     `json:"ip,omitempty" example:"203.0.113.7"`
+	UserAgent            string     `json:"user_agent,omitempty"`
+	ExpiresAt            time.Time  `gorm:"not null;index" json:"expires_at"`
+	LastUsedAt           time.Time  `json:"last_used_at"`
+	RevokedAt            *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt            time.Time  `json:"created_at"`
+}
+
+// RevokedToken is an access token that was revoked before it expired. It is
+// kept until then.
+type RevokedToken struct {
+	JTI       string    `gorm:"primaryKey;size:64"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+}
+
+// LoginThrottle counts the recent failed logins of a client IP or username.
+// Each failure blocks further attempts for twice as long as the one before;
+// after too many failures the IP or username is locked out for a while.
+type LoginThrottle struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	Kind          string    `gorm:"not null;uniqueIndex:idx_login_throttle" json:"kind" example:"username"` // ip or username
+	Key           string    `gorm:"not null;uniqueIndex:idx_login_throttle" json:"key" example:"admin"`
+	Failures      int       `gorm:"not null" json:"failures" example:"3"`
+	LastFailureAt time.Time `gorm:"not null;index" json:"last_failure_at"`
+	BlockedUntil  time.Time `json:"blocked_until"`
+	Locked        bool      `json:"locked"`
+}
+
+// RecoveryCode is a single-use code that replaces a two-factor code when the
+// authenticator is lost. Only its hash is stored.
+type RecoveryCode struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"not null;index"`
+	Hash      string `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// LoginChallenge is a login whose password was right and that waits for a
+// two-factor code. It is found by the hash of its token and deleted when
+// used or after too many wrong codes.
+type LoginChallenge struct {
+	ID        uint      `gorm:"primaryKey"`
+	TokenHash string    `gorm:"uniqueIndex;not null"`
+	UserID    uint      `gorm:"not null"`
+	Attempts  int       `gorm:"not null;default:0"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+}
+
+// SecurityPolicy holds settings that apply to all users. There is a single
+// row.
+type SecurityPolicy struct {
+	ID         uint      `gorm:"primaryKey" json:"-"`
+	Require2FA bool      `gorm:"column:require_2fa;not null;default:false" json:"require_2fa"`
+	UpdatedBy  string    `json:"updated_by,omitempty" example:"admin"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// OIDCLogin is a single sign-on login that was started but has not returned
+// from the provider yet. It is found by its state and deleted when used.
+type OIDCLogin struct {
+	State     string    `gorm:"primaryKey;size:64"`
+	Nonce     string    `gorm:"not null"`
+	Verifier  string    `gorm:"not null"`
+	ExpiresAt time.Time `gorm:"not null;index"`
 }
 
 // OllamaRequest represents a request to the Ollama API
 type OllamaRequest struct {
-	Model  string   `json:"model" example:"llama2"`
-	Prompt string   `json:"prompt" example:"Why is the sky blue?"`
-	Stream bool     `json:"stream" example:"false"`
-	Images []string `json:"images,omitempty"` // base64-encoded images for vision models
+	Model     string           `json:"model" example:"llama2"`
+	Prompt    string           `json:"prompt" example:"Why is the sky blue?"`
+	Suffix    string           `json:"suffix,omitempty"`
+	Stream    bool             `json:"stream" example:"false"`
+	Images    []string         `json:"images,omitempty"`                      // base64-encoded images for vision models
+	Format    interface{}      `json:"format,omitempty" swaggertype:"object"` // "json" or a JSON schema
+	Options   *GenerateOptions `json:"options,omitempty"`
+	System    string           `json:"system,omitempty"`
+	Template  string           `json:"template,omitempty"`
+	Context   []int            `json:"context,omitempty"`
+	Raw       bool             `json:"raw,omitempty"`
+	KeepAlive interface{}      `json:"keep_alive,omitempty" swaggertype:"string" example:"5m"` // duration string or seconds
 }
 
 // OllamaResponse represents a response from the Ollama API
 type OllamaResponse struct {
-	Model     string `json:"model"`
-	CreatedAt string `json:"created_at"`
-	Response  string `json:"response"`
-	Done      bool   `json:"done"`
+	Model              string `json:"model"`
+	CreatedAt          string `json:"created_at"`
+	Response           string `json:"response"`
+	Done               bool   `json:"done"`
+	DoneReason         string `json:"done_reason,omitempty"`
+	Context            []int  `json:"context,omitempty"`
+	TotalDuration      int64  `json:"total_duration,omitempty"`
+	LoadDuration       int64  `json:"load_duration,omitempty"`
+	PromptEvalCount    int    `json:"prompt_eval_count,omitempty"`
+	PromptEvalDuration int64  `json:"prompt_eval_duration,omitempty"`
+	EvalCount          int    `json:"eval_count,omitempty"`
+	EvalDuration       int64  `json:"eval_duration,omitempty"`
+}
+
+// ChatMessage represents a single message in a chat conversation
+type ChatMessage struct {
+	Role      string        `json:"role" example:"user"` // system, user, assistant or tool
+	Content   string        `json:"content" example:"Why is the sky blue?"`
+	Images    []string      `json:"images,omitempty"` // base64-encoded images for vision models
+	ToolCalls []interface{} `json:"tool_calls,omitempty"`
+	// ToolCallID and ToolName identify the call a tool message answers
+	ToolCallID string `json:"tool_call_id,omitempty"`
+	ToolName   string `json:"tool_name,omitempty"`
+}
+
+// OllamaChatRequest represents a chat request to the Ollama API
+type OllamaChatRequest struct {
+	Model     string           `json:"model" example:"llama2"`
+	Messages  []ChatMessage    `json:"messages"`
+	Stream    bool             `json:"stream" example:"false"`
+	Tools     []interface{}    `json:"tools,omitempty"`
+	Format    interface{}      `json:"format,omitempty" swaggertype:"object"` // "json" or a JSON schema
+	Options   *GenerateOptions `json:"options,omitempty"`
+	KeepAlive interface{}      `json:"keep_alive,omitempty" swaggertype:"string" example:"5m"` // duration string or seconds
+}
+
+// OllamaChatResponse represents a chat response from the Ollama API
+type OllamaChatResponse struct {
+	Model              string      `json:"model"`
+	CreatedAt          string      `json:"created_at"`
+	Message            ChatMessage `json:"message"`
+	Done               bool        `json:"done"`
+	DoneReason         string      `json:"done_reason,omitempty"`
+	TotalDuration      int64       `json:"total_duration,omitempty"`
+	LoadDuration       int64       `json:"load_duration,omitempty"`
+	PromptEvalCount    int         `json:"prompt_eval_count,omitempty"`
+	PromptEvalDuration int64       `json:"prompt_eval_duration,omitempty"`
+	EvalCount          int         `json:"eval_count,omitempty"`
+	EvalDuration       int64       `json:"eval_duration,omitempty"`
+}
+
+// OllamaEmbedRequest represents an embedding request to the Ollama API.
+// Input is either a single string or an array of strings.
+type OllamaEmbedRequest struct {
+	Model      string           `json:"model" example:"nomic-embed-text"`
+	Input      interface{}      `json:"input" swaggertype:"array,string" example:"Why is the sky blue?"`
+	Truncate   *bool            `json:"truncate,omitempty"`
+	Dimensions *int             `json:"dimensions,omitempty"`
+	Options    *GenerateOptions `json:"options,omitempty"`
+	KeepAlive  interface{}      `json:"keep_alive,omitempty" swaggertype:"string" example:"5m"` // duration string or seconds
+}
+
+// OllamaEmbedResponse represents an embedding response from the Ollama API
+type OllamaEmbedResponse struct {
+	Model           string      `json:"model"`
+	Embeddings      [][]float64 `json:"embeddings"`
+	TotalDuration   int64       `json:"total_duration,omitempty"`
+	LoadDuration    int64       `json:"load_duration,omitempty"`
+	PromptEvalCount int         `json:"prompt_eval_count,omitempty"`
+}
+
+// OpenAIChatMessage represents a message in an OpenAI chat completion request.
+// Content is either a string or an array of text/image_url parts.
+type OpenAIChatMessage struct {
+	Role       string        `json:"role,omitempty" example:"user"`
+	Content    interface{}   `json:"content"`
+	Name       string        `json:"name,omitempty"`
+	ToolCalls  []interface{} `json:"tool_calls,omitempty"`
+	ToolCallID string        `json:"tool_call_id,omitempty"`
+}
+
+// OpenAIResponseFormat represents the response_format field of an OpenAI request
+type OpenAIResponseFormat struct {
+	Type       string      `json:"type" example:"json_object"`
+	JSONSchema interface{} `json:"json_schema,omitempty"`
+}
+
+// OpenAIStreamOptions represents the stream_options field of an OpenAI request
+type OpenAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// OpenAIChatCompletionRequest represents an OpenAI chat completion request
+type OpenAIChatCompletionRequest struct {
+	Model            string                `json:"model" example:"llama2"`
+	Messages         []OpenAIChatMessage   `json:"messages"`
+	Stream           bool                  `json:"stream" example:"false"`
+	StreamOptions    *OpenAIStreamOptions  `json:"stream_options,omitempty"`
+	Temperature      *float64              `json:"temperature,omitempty"`
+	TopP             *float64              `json:"top_p,omitempty"`
+	MaxTokens        *int                  `json:"max_tokens,omitempty"`
+	Stop             interface{}           `json:"stop,omitempty"`
+	Seed             *int                  `json:"seed,omitempty"`
+	PresencePenalty  *float64              `json:"presence_penalty,omitempty"`
+	FrequencyPenalty *float64              `json:"frequency_penalty,omitempty"`
+	ResponseFormat   *OpenAIResponseFormat `json:"response_format,omitempty"`
+	Tools            []interface{}         `json:"tools,omitempty"`
+}
+
+// OpenAICompletionRequest represents an OpenAI (legacy) completion request
+type OpenAICompletionRequest struct {
+	Model            string               `json:"model" example:"llama2"`
+	Prompt           interface{}          `json:"prompt" swaggertype:"string" example:"Why is the sky blue?"`
+	Suffix           string               `json:"suffix,omitempty"`
+	Stream           bool                 `json:"stream" example:"false"`
+	StreamOptions    *OpenAIStreamOptions `json:"stream_options,omitempty"`
+	Temperature      *float64             `json:"temperature,omitempty"`
+	TopP             *float64             `json:"top_p,omitempty"`
+	MaxTokens        *int                 `json:"max_tokens,omitempty"`
+	Stop             interface{}          `json:"stop,omitempty"`
+	Seed             *int                 `json:"seed,omitempty"`
+	PresencePenalty  *float64             `json:"presence_penalty,omitempty"`
+	FrequencyPenalty *float64             `json:"frequency_penalty,omitempty"`
+}
+
+// OpenAIUsage represents token usage in an OpenAI response
+type OpenAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// OpenAIChatChoice represents a choice in an OpenAI chat completion response
+type OpenAIChatChoice struct {
+	Index        int                `json:"index"`
+	Message      *OpenAIChatMessage `json:"message,omitempty"`
+	Delta        *OpenAIChatMessage `json:"delta,omitempty"`
+	FinishReason *string            `json:"finish_reason"`
+}
+
+// OpenAIChatCompletionResponse represents an OpenAI chat completion (or chunk) response
+type OpenAIChatCompletionResponse struct {
+	ID      string             `json:"id" example:"chatcmpl-3f1c2a9b"`
+	Object  string             `json:"object" example:"chat.completion"`
+	Created int64              `json:"created"`
+	Model   string             `json:"model" example:"llama2"`
+	Choices []OpenAIChatChoice `json:"choices"`
+	Usage   *OpenAIUsage       `json:"usage,omitempty"`
+}
+
+// OpenAICompletionChoice represents a choice in an OpenAI completion response
+type OpenAICompletionChoice struct {
+	Index        int         `json:"index"`
+	Text         string      `json:"text"`
+	Logprobs     interface{} `json:"logprobs"`
+	FinishReason *string     `json:"finish_reason"`
+}
+
+// OpenAICompletionResponse represents an OpenAI completion (or chunk) response
+type OpenAICompletionResponse struct {
+	ID      string                   `json:"id" example:"cmpl-3f1c2a9b"`
+	Object  string                   `json:"object" example:"text_completion"`
+	Created int64                    `json:"created"`
+	Model   string                   `json:"model" example:"llama2"`
+	Choices []OpenAICompletionChoice `json:"choices"`
+	Usage   *OpenAIUsage             `json:"usage,omitempty"`
+}
+
+// OpenAIEmbeddingRequest represents an OpenAI embeddings request.
+// Input is either a single string or an array of strings.
+type OpenAIEmbeddingRequest struct {
+	Model          string      `json:"model" example:"nomic-embed-text"`
+	Input          interface{} `json:"input" swaggertype:"array,string" example:"Why is the sky blue?"`
+	EncodingFormat string      `json:"encoding_format,omitempty" example:"float"`
+	Dimensions     *int        `json:"dimensions,omitempty"`
+}
+
+// OpenAIEmbedding represents a single embedding in an OpenAI embeddings response
+type OpenAIEmbedding struct {
+	Object    string    `json:"object" example:"embedding"`
+	Index     int       `json:"index"`
+	Embedding []float64 `json:"embedding"`
+}
+
+// OpenAIEmbeddingResponse represents an OpenAI embeddings response
+type OpenAIEmbeddingResponse struct {
+	Object string            `json:"object" example:"list"`
+	Data   []OpenAIEmbedding `json:"data"`
+	Model  string            `json:"model" example:"nomic-embed-text"`
+	Usage  OpenAIUsage       `json:"usage"`
+}
+
+// OpenAIModel represents a model entry in the OpenAI model list
+type OpenAIModel struct {
+	ID      string `json:"id" example:"llama2"`
+	Object  string `json:"object" example:"model"`
+	Created int64  `json:"created"`
+	OwnedBy string `json:"owned_by" example:"ollama"`
+}
+
+// OpenAIModelList represents the OpenAI model list response
+type OpenAIModelList struct {
+	Object string        `json:"object" example:"list"`
+	Data   []OpenAIModel `json:"data"`
+}
+
+// OpenAIErrorDetail represents the body of an OpenAI error
+type OpenAIErrorDetail struct {
+	Message string      `json:"message"`
+	Type    string      `json:"type"`
+	Param   interface{} `json:"param"`
+	Code    interface{} `json:"code"`
+}
+
+// OpenAIErrorResponse represents an error in the OpenAI response format
+type OpenAIErrorResponse struct {
+	Error OpenAIErrorDetail `json:"error"`
 }
 
 // ErrorResponse represents an error response
 type ErrorResponse struct {
 	Error   string `json:"error" example:"Invalid request"`
 	Message string `json:"message,omitempty" example:"Detailed error message"`
+	Code    string `json:"code,omitempty" example:"quota_exceeded"` // machine-readable code for errors clients must tell apart
 }
 
 // SuccessResponse represents a success response
@@ -61,18 +705,144 @@ type LoginRequest struct {
 	Password string `json:"password" example:"password"`
 }
 
-// LoginResponse represents a login response
+// LoginResponse represents a login response. The token expires after
+// expires_in seconds and is renewed with the refresh token. Users with
+// two-factor authentication get no tokens yet but a challenge, which is
+// completed with a code at /api/auth/2fa/verify.
 type LoginResponse struct {
-	Token string `json:"token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	Token             string `json:"token,omitempty" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	RefreshToken      string `json:"refresh_token,omitempty" example:"3f9a..."`
+	ExpiresIn         int    `json:"expires_in,omitempty" example:"900"`
+	Role              string `json:"role,omitempty" example:"admin"`
+	TwoFactorRequired bool   `json:"two_factor_required,omitempty"`
+	Challenge         string `json:"challenge,omitempty" example:"8c1d..."`
+}
+
+// TwoFactorVerifyRequest completes a login with a code from the
+// authenticator or a recovery code
+type TwoFactorVerifyRequest struct {
+	Challenge string `json:"challenge"`
+	Code      string `json:"code" example:"123456"`
+}
+
+// TwoFactorCodeRequest confirms a two-factor change with a code from the
+// authenticator, or a recovery code where noted
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" example:"123456"`
+}
+
+// TwoFactorSetupResponse holds a new secret to add to an authenticator. The
+// otpauth URI is usually shown as a QR code.
+type TwoFactorSetupResponse struct {
+	Secret     string `json:"secret" example:"JBSWY3DPEHPK3PXP"`
+	OTPAuthURI string `json:"otpauth_uri" example:"otpauth://totp/Ollama%20Web%20API:alice?secret=JBSWY3DPEHPK3PXP&issuer=Ollama%20Web%20API"`
+}
+
+// TwoFactorStatusResponse describes the two-factor authentication of the
+// logged-in user
+type TwoFactorStatusResponse struct {
+	Enabled           bool `json:"enabled"`
+	Required          bool `json:"required"`
+	RecoveryCodesLeft int  `json:"recovery_codes_left"`
+}
+
+// RecoveryCodesResponse holds new recovery codes, shown once
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// UpdateSecurityPolicyRequest changes the security policy
+type UpdateSecurityPolicyRequest struct {
+	Require2FA bool `json:"require_2fa"`
+}
+
+// RefreshRequest represents a request for new tokens
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// JSONWebKey is the public part of a key that signs admin tokens (RFC 7517)
+type JSONWebKey struct {
+	Kty string `json:"kty" example:"RSA"`
+	Kid string `json:"kid"`
+	Use string `json:"use" example:"sig"`
+	Alg string `json:"alg" example:"RS256"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty" example:"AQAB"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JSONWebKeySet lists the keys that verify admin tokens
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// OIDCConfigResponse tells the admin UI whether to offer single sign-on
+type OIDCConfigResponse struct {
+	Enabled bool `json:"enabled"`
+}
+
+// CreateUserRequest represents a request to create an admin user
+type CreateUserRequest struct {
+	Username string `json:"username" example:"alice"`
+	Password string `json:"password" example:"correct horse battery staple"`
+	Role     string `json:"role,omitempty" example:"operator"` // defaults to viewer
+}
+
+// UpdateUserRoleRequest represents a request to change the role of a user
+type UpdateUserRoleRequest struct {
+	Role string `json:"role" example:"admin"`
+}
+
+// ResetPasswordRequest represents a request to set a new password for a user
+type ResetPasswordRequest struct {
+	Password string `json:"password" example:"correct horse battery staple"`
 }
 
 // CreateProjectRequest represents a request to create a new project
 type CreateProjectRequest struct {
-	Name        string `json:"name" example:"My Project"`
-	Description string `json:"description" example:"A test project"`
+	Name         string              `json:"name" example:"My Project"`
+	Description  string              `json:"description" example:"A test project"`
+	Settings     *GenerationSettings `json:"settings,omitempty"`
+	RateLimits   *RateLimitSettings  `json:"rate_limits,omitempty"`
+	Quotas       *QuotaSettings      `json:"quotas,omitempty"`
+	Scheduling   *SchedulingSettings `json:"scheduling,omitempty"`
+	WebhookURL   *string             `json:"webhook_url,omitempty" example:"https://example.com/hooks/ollama"`
+	AllowedCIDRs *[]string           `json:"allowed_cidrs,omitempty" example:"10.0.0.0/8"`
+}
+
+// GrantTopUpRequest represents a request to grant a one-off quota top-up
+type GrantTopUpRequest struct {
+	Period string `json:"period" example:"monthly"` // daily or monthly
+	Tokens int    `json:"tokens" example:"100000"`
+	Note   string `json:"note,omitempty" example:"Release week"`
+}
+
+// CreateAPIKeyRequest represents a request to create an API key for a project
+type CreateAPIKeyRequest struct {
+	Name         string     `json:"name" example:"production"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	Scopes       []string   `json:"scopes,omitempty" example:"generate,chat"`
+	Models       []string   `json:"models,omitempty" example:"llama2"`
+	AllowedCIDRs []string   `json:"allowed_cidrs,omitempty" example:"203.0.113.0/24"`
+}
+
+// RotateAPIKeyRequest represents a request to replace an API key. The old key
+// keeps working for GracePeriod (default 24h, "0s" revokes it right away).
+type RotateAPIKeyRequest struct {
+	GracePeriod string     `json:"grace_period,omitempty" example:"24h"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
+
+// CreateBackendRequest represents a request to register an Ollama backend
+type CreateBackendRequest struct {
+	Name string `json:"name" example:"gpu-1"`
+	URL  string `json:"url" example:"http://10.0.0.11:11434"`
 }
 
 // AssignModelRequest represents a request to assign a model to a project
 type AssignModelRequest struct {
-	ModelName string `json:"model_name" example:"llama2"`
+	ModelName string              `json:"model_name" example:"llama2"`
+	Settings  *GenerationSettings `json:"settings,omitempty"`
 }
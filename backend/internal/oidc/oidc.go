@@ -0,0 +1,385 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/ollama-web-api/internal/roles"
+)
+
+// httpTimeout bounds each call to the provider
+const httpTimeout = 10 * time.Second
+
+// ErrDisabled is returned when no provider is configured
+var ErrDisabled = errors.New("OpenID Connect is not configured")
+
+// RoleMapping grants Role to users in the group Value, or with a verified
+// email address in the domain Value
+type RoleMapping struct {
+	Kind  string // "group" or "domain"
+	Value string
+	Role  string
+}
+
+// Config describes the OpenID Connect provider and how its users map to
+// roles
+type Config struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	GroupsClaim  string
+	RoleMappings []RoleMapping
+	PostLoginURL string
+}
+
+// Identity is the verified user of an ID token
+type Identity struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Username      string
+	Groups        []string
+}
+
+// metadata is the part of the provider's discovery document that is used
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+var (
+	mu       sync.Mutex
+	config   *Config
+	provider *metadata
+	keys     *keySet
+
+	httpClient = &http.Client{Timeout: httpTimeout}
+)
+
+// Setup reads the provider from OIDC_ISSUER, OIDC_CLIENT_ID,
+// OIDC_CLIENT_SECRET, OIDC_REDIRECT_URL, OIDC_SCOPES, OIDC_GROUPS_CLAIM,
+// OIDC_ROLE_MAPPINGS and OIDC_POST_LOGIN_URL. Single sign-on is disabled
+// when OIDC_ISSUER is empty. The provider is discovered on the first login.
+func Setup() error {
+	issuer := strings.TrimRight(os.Getenv("OIDC_ISSUER"), "/")
+	if issuer == "" {
+		return nil
+	}
+
+	cfg := &Config{
+		Issuer:       issuer,
+		ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:  os.Getenv("OIDC_REDIRECT_URL"),
+		Scopes:       strings.Fields(strings.ReplaceAll(os.Getenv("OIDC_SCOPES"), ",", " ")),
+		GroupsClaim:  os.Getenv("OIDC_GROUPS_CLAIM"),
+		PostLoginURL: os.Getenv("OIDC_POST_LOGIN_URL"),
+	}
+	if cfg.ClientID == "" || cfg.RedirectURL == "" {
+		return fmt.Errorf("OIDC_CLIENT_ID and OIDC_REDIRECT_URL are required with OIDC_ISSUER")
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "email", "profile"}
+	}
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = "groups"
+	}
+	if cfg.PostLoginURL == "" {
+		cfg.PostLoginURL = "/"
+	}
+	mappings, err := ParseRoleMappings(os.Getenv("OIDC_ROLE_MAPPINGS"))
+	if err != nil {
+		return err
+	}
+	if len(mappings) == 0 {
+		return fmt.Errorf("OIDC_ROLE_MAPPINGS is required with OIDC_ISSUER, or nobody could log in")
+	}
+	cfg.RoleMappings = mappings
+
+	mu.Lock()
+	config, provider, keys = cfg, nil, nil
+	mu.Unlock()
+	log.Printf("OpenID Connect login enabled with %s", issuer)
+	return nil
+}
+
+// ParseRoleMappings parses a comma-separated list of group:<name>=<role> and
+// domain:<domain>=<role> entries
+func ParseRoleMappings(value string) ([]RoleMapping, error) {
+	var mappings []RoleMapping
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		subject, role, ok := strings.Cut(entry, "=")
+		kind, match, hasKind := strings.Cut(subject, ":")
+		if !ok || !hasKind || (kind != "group" && kind != "domain") || match == "" {
+			return nil, fmt.Errorf("invalid role mapping %q, expected group:<name>=<role> or domain:<domain>=<role>", entry)
+		}
+		if !roles.Valid(role) {
+			return nil, fmt.Errorf("invalid role %q in role mapping %q", role, entry)
+		}
+		mappings = append(mappings, RoleMapping{Kind: kind, Value: match, Role: role})
+	}
+	return mappings, nil
+}
+
+// Enabled reports whether single sign-on is configured
+func Enabled() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return config != nil
+}
+
+// Current returns the configuration, or nil when single sign-on is disabled
+func Current() *Config {
+	mu.Lock()
+	defer mu.Unlock()
+	return config
+}
+
+// Role returns the most privileged role the mappings grant the identity.
+// Email domains only count for verified addresses.
+func (cfg *Config) Role(identity *Identity) (string, bool) {
+	best := ""
+	for _, m := range cfg.RoleMappings {
+		matched := false
+		switch m.Kind {
+		case "group":
+			for _, group := range identity.Groups {
+				if group == m.Value {
+					matched = true
+				}
+			}
+		case "domain":
+			_, domain, _ := strings.Cut(identity.Email, "@")
+			matched = identity.EmailVerified && strings.EqualFold(domain, m.Value)
+		}
+		if matched && (best == "" || roles.AtLeast(m.Role, best)) {
+			best = m.Role
+		}
+	}
+	return best, best != ""
+}
+
+// RandomString returns a random URL-safe string for states, nonces and PKCE
+// verifiers
+func RandomString() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
+
+// challenge returns the S256 PKCE challenge of a verifier
+func challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// discover fetches and caches the discovery document of the provider
+func discover(ctx context.Context, cfg *Config) (*metadata, error) {
+	mu.Lock()
+	cached := provider
+	mu.Unlock()
+	if cached != nil {
+		return cached, nil
+	}
+
+	var m metadata
+	if err := getJSON(ctx, cfg.Issuer+"/.well-known/openid-configuration", &m); err != nil {
+		return nil, fmt.Errorf("discovery failed: %w", err)
+	}
+	if strings.TrimRight(m.Issuer, "/") != cfg.Issuer {
+		return nil, fmt.Errorf("discovery returned issuer %q instead of %q", m.Issuer, cfg.Issuer)
+	}
+	if m.AuthorizationEndpoint == "" || m.TokenEndpoint == "" || m.JWKSURI == "" {
+		return nil, fmt.Errorf("discovery document lacks endpoints")
+	}
+
+	mu.Lock()
+	provider = &m
+	keys = newKeySet(m.JWKSURI)
+	mu.Unlock()
+	return &m, nil
+}
+
+// AuthCodeURL returns the URL of the provider's login page for an
+// authorization code flow with PKCE
+func AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	cfg := Current()
+	if cfg == nil {
+		return "", ErrDisabled
+	}
+	m, err := discover(ctx, cfg)
+	if err != nil {
+		return "", err
+	}
+
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {cfg.ClientID},
+		"redirect_uri":          {cfg.RedirectURL},
+		"scope":                 {strings.Join(cfg.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {challenge(verifier)},
+		"code_challenge_method": {"S256"},
+	}
+	separator := "?"
+	if strings.Contains(m.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return m.AuthorizationEndpoint + separator + params.Encode(), nil
+}
+
+// Exchange redeems an authorization code and returns the identity of its
+// verified ID token
+func Exchange(ctx context.Context, code, verifier, nonce string) (*Identity, error) {
+	cfg := Current()
+	if cfg == nil {
+		return nil, ErrDisabled
+	}
+	m, err := discover(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {cfg.RedirectURL},
+		"client_id":     {cfg.ClientID},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(cfg.ClientID), url.QueryEscape(cfg.ClientSecret))
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("token request failed: %w", err)
+	}
+
+	var token struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return nil, fmt.Errorf("invalid token response (status %d)", resp.StatusCode)
+	}
+	if resp.StatusCode != http.StatusOK || token.Error != "" {
+		return nil, fmt.Errorf("token request refused: %s %s", token.Error, token.ErrorDescription)
+	}
+	if token.IDToken == "" {
+		return nil, fmt.Errorf("token response has no id_token")
+	}
+
+	return verify(ctx, cfg, m, token.IDToken, nonce)
+}
+
+// verify checks the signature, issuer, audience, expiry and nonce of an ID
+// token and returns its identity. The issuer must be exactly the one of the
+// discovery document, as ID tokens carry it unchanged, trailing slash and all.
+func verify(ctx context.Context, cfg *Config, m *metadata, raw, nonce string) (*Identity, error) {
+	mu.Lock()
+	ks := keys
+	mu.Unlock()
+
+	parser := jwt.NewParser(
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}),
+		jwt.WithIssuer(m.Issuer),
+		jwt.WithAudience(cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(time.Minute),
+	)
+	claims := jwt.MapClaims{}
+	_, err := parser.ParseWithClaims(raw, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return ks.key(ctx, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID token: %w", err)
+	}
+	if got, _ := claims["nonce"].(string); got == "" || got != nonce {
+		return nil, fmt.Errorf("invalid ID token: nonce does not match")
+	}
+
+	identity := &Identity{Issuer: cfg.Issuer}
+	identity.Subject, _ = claims["sub"].(string)
+	identity.Email, _ = claims["email"].(string)
+	identity.Username, _ = claims["preferred_username"].(string)
+	switch verified := claims["email_verified"].(type) {
+	case bool:
+		identity.EmailVerified = verified
+	case string:
+		// Some providers send the flag as a string
+		identity.EmailVerified = verified == "true"
+	}
+	switch groups := claims[cfg.GroupsClaim].(type) {
+	case []interface{}:
+		for _, g := range groups {
+			if s, ok := g.(string); ok {
+				identity.Groups = append(identity.Groups, s)
+			}
+		}
+	case string:
+		identity.Groups = []string{groups}
+	}
+	if identity.Subject == "" {
+		return nil, fmt.Errorf("invalid ID token: no subject")
+	}
+	return identity, nil
+}
+
+// getJSON fetches a JSON document
+func getJSON(ctx context.Context, url string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s returned status %d", url, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(out)
+}
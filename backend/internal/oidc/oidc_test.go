@@ -0,0 +1,223 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// testProvider is an identity provider serving its discovery document and keys
+type testProvider struct {
+	*httptest.Server
+	issuer string
+	rsaKey *rsa.PrivateKey
+	ecKey  *ecdsa.PrivateKey
+}
+
+// newTestProvider starts a provider whose issuer ends in a slash, as some
+// providers' do
+func newTestProvider(t *testing.T) *testProvider {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &testProvider{rsaKey: rsaKey, ecKey: ecKey}
+
+	encode := func(i *big.Int) string { return base64.RawURLEncoding.EncodeToString(i.Bytes()) }
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 p.issuer,
+			"authorization_endpoint": p.URL + "/authorize",
+			"token_endpoint":         p.URL + "/token",
+			"jwks_uri":               p.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []jwk{
+			{Kty: "RSA", Kid: "rsa", Use: "sig", N: encode(rsaKey.N), E: encode(big.NewInt(int64(rsaKey.E)))},
+			{Kty: "EC", Kid: "ec", Use: "sig", Crv: "P-256", X: encode(ecKey.X), Y: encode(ecKey.Y)},
+			{Kty: "RSA", Kid: "enc", Use: "enc", N: encode(rsaKey.N), E: encode(big.NewInt(int64(rsaKey.E)))},
+		}})
+	})
+	p.Server = httptest.NewServer(mux)
+	p.issuer = p.URL + "/"
+	t.Cleanup(p.Close)
+	return p
+}
+
+// use makes the provider the configured one and returns its configuration
+func (p *testProvider) use(t *testing.T) *Config {
+	t.Helper()
+	cfg := &Config{
+		Issuer:      strings.TrimRight(p.issuer, "/"),
+		ClientID:    "client",
+		GroupsClaim: "groups",
+	}
+	mu.Lock()
+	config, provider, keys = cfg, nil, nil
+	mu.Unlock()
+	t.Cleanup(func() {
+		mu.Lock()
+		config, provider, keys = nil, nil, nil
+		mu.Unlock()
+	})
+	return cfg
+}
+
+// sign signs claims with the key named by kid
+func (p *testProvider) sign(t *testing.T, method jwt.SigningMethod, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	var key interface{}
+	switch method.(type) {
+	case *jwt.SigningMethodRSA:
+		key = p.rsaKey
+	case *jwt.SigningMethodECDSA:
+		key = p.ecKey
+	}
+	raw, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+// claims returns valid claims, changed by edit
+func (p *testProvider) claims(edit func(jwt.MapClaims)) jwt.MapClaims {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":            p.issuer,
+		"aud":            "client",
+		"sub":            "user-1",
+		"email":          "ada@example.com",
+		"email_verified": true,
+		"groups":         []string{"admins", "staff"},
+		"nonce":          "n-1",
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Hour).Unix(),
+	}
+	if edit != nil {
+		edit(claims)
+	}
+	return claims
+}
+
+func TestVerify(t *testing.T) {
+	p := newTestProvider(t)
+	cfg := p.use(t)
+	m, err := discover(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("discover: %v", err)
+	}
+	if m.Issuer != p.issuer {
+		t.Fatalf("discovered issuer %q, want %q", m.Issuer, p.issuer)
+	}
+
+	rs256 := func(edit func(jwt.MapClaims)) string {
+		return p.sign(t, jwt.SigningMethodRS256, "rsa", p.claims(edit))
+	}
+	tests := []struct {
+		name    string
+		token   string
+		nonce   string
+		wantErr string
+	}{
+		{name: "RS256", token: rs256(nil), nonce: "n-1"},
+		{name: "ES256", token: p.sign(t, jwt.SigningMethodES256, "ec", p.claims(nil)), nonce: "n-1"},
+		{name: "audience list", token: rs256(func(c jwt.MapClaims) { c["aud"] = []string{"other", "client"} }), nonce: "n-1"},
+		{name: "issuer without its trailing slash", token: rs256(func(c jwt.MapClaims) { c["iss"] = cfg.Issuer }), nonce: "n-1", wantErr: "issuer"},
+		{name: "other issuer", token: rs256(func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com/" }), nonce: "n-1", wantErr: "issuer"},
+		{name: "other audience", token: rs256(func(c jwt.MapClaims) { c["aud"] = "other" }), nonce: "n-1", wantErr: "audience"},
+		{name: "wrong nonce", token: rs256(nil), nonce: "n-2", wantErr: "nonce"},
+		{name: "missing nonce", token: rs256(func(c jwt.MapClaims) { delete(c, "nonce") }), nonce: "n-1", wantErr: "nonce"},
+		{name: "expired", token: rs256(func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() }), nonce: "n-1", wantErr: "expired"},
+		{name: "no expiry", token: rs256(func(c jwt.MapClaims) { delete(c, "exp") }), nonce: "n-1", wantErr: "exp"},
+		{name: "no subject", token: rs256(func(c jwt.MapClaims) { delete(c, "sub") }), nonce: "n-1", wantErr: "subject"},
+		{name: "unknown key", token: p.sign(t, jwt.SigningMethodRS256, "gone", p.claims(nil)), nonce: "n-1", wantErr: "unknown key"},
+		{name: "encryption key", token: p.sign(t, jwt.SigningMethodRS256, "enc", p.claims(nil)), nonce: "n-1", wantErr: "unknown key"},
+		{name: "alg none", token: noneToken(t, p.claims(nil)), nonce: "n-1", wantErr: "signing method"},
+		{name: "HS256 keyed with the public key", token: publicKeyHMAC(t, p, p.claims(nil)), nonce: "n-1", wantErr: "signing method"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity, err := verify(context.Background(), cfg, m, tt.token, tt.nonce)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("verify() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("verify() error = %v", err)
+			}
+			want := &Identity{
+				Issuer:        cfg.Issuer,
+				Subject:       "user-1",
+				Email:         "ada@example.com",
+				EmailVerified: true,
+				Groups:        []string{"admins", "staff"},
+			}
+			if !reflect.DeepEqual(identity, want) {
+				t.Errorf("identity = %+v, want %+v", identity, want)
+			}
+		})
+	}
+}
+
+func TestDiscoverRejectsOtherIssuer(t *testing.T) {
+	p := newTestProvider(t)
+	p.issuer = "https://evil.example.com/"
+	cfg := p.use(t)
+	cfg.Issuer = strings.TrimRight(p.URL, "/")
+
+	if _, err := discover(context.Background(), cfg); err == nil || !strings.Contains(err.Error(), "issuer") {
+		t.Errorf("discover() error = %v, want an issuer mismatch", err)
+	}
+}
+
+// noneToken returns an unsigned token
+func noneToken(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodNone, claims)
+	token.Header["kid"] = "rsa"
+	raw, err := token.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+// publicKeyHMAC returns a token signed with HS256 keyed by the provider's
+// public RSA modulus, an algorithm confusion attack
+func publicKeyHMAC(t *testing.T, p *testProvider, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["kid"] = "rsa"
+	raw, err := token.SignedString(p.rsaKey.N.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
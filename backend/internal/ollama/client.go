@@ -0,0 +1,208 @@
+// Package ollama is a typed client for the Ollama HTTP API
+package ollama
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ollama-web-api/internal/models"
+)
+
+// Transport is shared by every client so that connections to the Ollama
+// servers are pooled and reused across requests
+var Transport = &http.Transport{
+	Proxy: http.ProxyFromEnvironment,
+	DialContext: (&net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}).DialContext,
+	ForceAttemptHTTP2:     true,
+	MaxIdleConns:          100,
+	MaxIdleConnsPerHost:   32,
+	IdleConnTimeout:       90 * time.Second,
+	TLSHandshakeTimeout:   10 * time.Second,
+	ExpectContinueTimeout: 1 * time.Second,
+}
+
+// Client talks to a single Ollama server. Requests have no timeout of their
+// own; bound them with the context passed to each method.
+type Client struct {
+	baseURL string
+	http    *http.Client
+}
+
+// NewClient creates a client for the Ollama server at baseURL
+func NewClient(baseURL string) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		http:    &http.Client{Transport: Transport},
+	}
+}
+
+// BaseURL returns the URL of the Ollama server
+func (c *Client) BaseURL() string {
+	return c.baseURL
+}
+
+// do sends in as JSON and returns the response. Responses with a status
+// other than 200 are turned into a *StatusError.
+func (c *Client) do(ctx context.Context, method, path string, in interface{}) (*http.Response, error) {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return nil, fmt.Errorf("ollama: failed to marshal request: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("ollama: failed to create request: %w", err)
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		var opErr *net.OpError
+		if errors.As(err, &opErr) && opErr.Op == "dial" {
+			return nil, &ConnectError{URL: c.baseURL, Err: err}
+		}
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, newStatusError(resp)
+	}
+	return resp, nil
+}
+
+// call sends in and decodes the JSON response into out, if out is not nil
+func (c *Client) call(ctx context.Context, method, path string, in, out interface{}) error {
+	resp, err := c.do(ctx, method, path, in)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil {
+		_, err = io.Copy(io.Discard, resp.Body)
+		return err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("ollama: failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// stream sends in and returns the newline-delimited JSON response as a stream
+func stream[T any](ctx context.Context, c *Client, path string, in interface{}) (*Stream[T], error) {
+	resp, err := c.do(ctx, http.MethodPost, path, in)
+	if err != nil {
+		return nil, err
+	}
+	return newStream[T](resp.Body), nil
+}
+
+// Generate runs a completion and returns the final response
+func (c *Client) Generate(ctx context.Context, req *models.OllamaRequest) (*models.OllamaResponse, error) {
+	r := *req
+	r.Stream = false
+	var resp models.OllamaResponse
+	if err := c.call(ctx, http.MethodPost, "/api/generate", &r, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GenerateStream runs a completion and streams its chunks
+func (c *Client) GenerateStream(ctx context.Context, req *models.OllamaRequest) (*Stream[models.OllamaResponse], error) {
+	r := *req
+	r.Stream = true
+	return stream[models.OllamaResponse](ctx, c, "/api/generate", &r)
+}
+
+// Chat runs a chat completion and returns the final response
+func (c *Client) Chat(ctx context.Context, req *models.OllamaChatRequest) (*models.OllamaChatResponse, error) {
+	r := *req
+	r.Stream = false
+	var resp models.OllamaChatResponse
+	if err := c.call(ctx, http.MethodPost, "/api/chat", &r, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ChatStream runs a chat completion and streams its chunks
+func (c *Client) ChatStream(ctx context.Context, req *models.OllamaChatRequest) (*Stream[models.OllamaChatResponse], error) {
+	r := *req
+	r.Stream = true
+	return stream[models.OllamaChatResponse](ctx, c, "/api/chat", &r)
+}
+
+// Embed generates embeddings for the request inputs
+func (c *Client) Embed(ctx context.Context, req *models.OllamaEmbedRequest) (*models.OllamaEmbedResponse, error) {
+	var resp models.OllamaEmbedResponse
+	if err := c.call(ctx, http.MethodPost, "/api/embed", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Tags lists the models installed on the server
+func (c *Client) Tags(ctx context.Context) (*ListResponse, error) {
+	var resp ListResponse
+	if err := c.call(ctx, http.MethodGet, "/api/tags", nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Ps lists the models currently loaded into memory
+func (c *Client) Ps(ctx context.Context) (*ProcessResponse, error) {
+	var resp ProcessResponse
+	if err := c.call(ctx, http.MethodGet, "/api/ps", nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Pull downloads a model from the registry and streams its progress
+func (c *Client) Pull(ctx context.Context, req *PullRequest) (*Stream[ProgressResponse], error) {
+	return stream[ProgressResponse](ctx, c, "/api/pull", req)
+}
+
+// Delete removes a model from the server
+func (c *Client) Delete(ctx context.Context, req *DeleteRequest) error {
+	return c.call(ctx, http.MethodDelete, "/api/delete", req, nil)
+}
+
+// Show returns the details of a model
+func (c *Client) Show(ctx context.Context, req *ShowRequest) (*ShowResponse, error) {
+	var resp ShowResponse
+	if err := c.call(ctx, http.MethodPost, "/api/show", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Copy creates a copy of a model under a new name
+func (c *Client) Copy(ctx context.Context, req *CopyRequest) error {
+	return c.call(ctx, http.MethodPost, "/api/copy", req, nil)
+}
+
+// Create creates a model and streams its progress
+func (c *Client) Create(ctx context.Context, req *CreateRequest) (*Stream[ProgressResponse], error) {
+	return stream[ProgressResponse](ctx, c, "/api/create", req)
+}
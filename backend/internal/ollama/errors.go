@@ -0,0 +1,102 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+var (
+	// ErrBadRequest is matched by errors for requests Ollama rejected as invalid
+	ErrBadRequest = errors.New("ollama: bad request")
+	// ErrModelNotFound is matched by errors for models the server does not have
+	ErrModelNotFound = errors.New("ollama: model not found")
+	// ErrUnavailable is matched by errors for servers that cannot be reached
+	// or failed to handle the request
+	ErrUnavailable = errors.New("ollama: server unavailable")
+)
+
+// StatusError is returned when Ollama responds with a status other than 200
+// or reports an error in the middle of a stream
+type StatusError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("ollama: %s (status %d)", e.Message, e.StatusCode)
+}
+
+// Unwrap lets callers match the error against ErrBadRequest,
+// ErrModelNotFound and ErrUnavailable
+func (e *StatusError) Unwrap() error {
+	switch {
+	case e.StatusCode == http.StatusNotFound:
+		return ErrModelNotFound
+	case e.StatusCode >= 400 && e.StatusCode < 500:
+		return ErrBadRequest
+	case e.StatusCode >= 500:
+		return ErrUnavailable
+	}
+	return nil
+}
+
+// newStatusError reads the error message from a failed response
+func newStatusError(resp *http.Response) *StatusError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	return &StatusError{StatusCode: resp.StatusCode, Message: errorMessage(body)}
+}
+
+// errorMessage extracts the message from an {"error": "..."} body
+func errorMessage(body []byte) string {
+	var payload struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &payload); err == nil && payload.Error != "" {
+		return payload.Error
+	}
+	if msg := strings.TrimSpace(string(body)); msg != "" {
+		return msg
+	}
+	return http.StatusText(http.StatusInternalServerError)
+}
+
+// ConnectError is returned when the server could not be reached at all, so
+// the request was never sent and may be retried elsewhere
+type ConnectError struct {
+	URL string
+	Err error
+}
+
+func (e *ConnectError) Error() string {
+	return fmt.Sprintf("ollama: cannot connect to %s: %v", e.URL, e.Err)
+}
+
+func (e *ConnectError) Unwrap() []error {
+	return []error{ErrUnavailable, e.Err}
+}
+
+// IsConnectError reports whether err means the server could not be reached
+func IsConnectError(err error) bool {
+	var connErr *ConnectError
+	return errors.As(err, &connErr)
+}
+
+// GatewayStatus maps an error from the client to the status code the
+// gateway should answer with. Client errors from Ollama are passed through,
+// server errors and connection failures become 502 and timeouts 504.
+func GatewayStatus(err error) int {
+	var statusErr *StatusError
+	switch {
+	case errors.As(err, &statusErr) && statusErr.StatusCode < 500:
+		return statusErr.StatusCode
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusBadGateway
+	}
+}
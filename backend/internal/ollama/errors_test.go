@@ -0,0 +1,110 @@
+package ollama
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStatusErrorUnwrap(t *testing.T) {
+	sentinels := []error{ErrBadRequest, ErrModelNotFound, ErrUnavailable}
+	tests := []struct {
+		status int
+		want   error
+	}{
+		{http.StatusBadRequest, ErrBadRequest},
+		{http.StatusUnauthorized, ErrBadRequest},
+		{http.StatusNotFound, ErrModelNotFound},
+		{http.StatusInternalServerError, ErrUnavailable},
+		{http.StatusServiceUnavailable, ErrUnavailable},
+		{http.StatusFound, nil},
+	}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			// Wrapping must not hide the status error or its sentinel
+			err := fmt.Errorf("request failed: %w", &StatusError{StatusCode: tt.status, Message: "x"})
+
+			var statusErr *StatusError
+			if !errors.As(err, &statusErr) || statusErr.StatusCode != tt.status {
+				t.Fatalf("errors.As did not find the status error in %v", err)
+			}
+			for _, sentinel := range sentinels {
+				if got := errors.Is(err, sentinel); got != (sentinel == tt.want) {
+					t.Errorf("errors.Is(%v, %v) = %v", err, sentinel, got)
+				}
+			}
+		})
+	}
+}
+
+func TestConnectErrorUnwrap(t *testing.T) {
+	cause := errors.New("connection refused")
+	err := fmt.Errorf("pull: %w", &ConnectError{URL: "http://ollama:11434", Err: cause})
+
+	if !IsConnectError(err) {
+		t.Error("IsConnectError = false for a wrapped connect error")
+	}
+	if !errors.Is(err, ErrUnavailable) {
+		t.Error("a connect error does not match ErrUnavailable")
+	}
+	if !errors.Is(err, cause) {
+		t.Error("a connect error does not match its cause")
+	}
+	if IsConnectError(&StatusError{StatusCode: http.StatusBadGateway}) {
+		t.Error("IsConnectError = true for a status error")
+	}
+}
+
+func TestGatewayStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"client error passed through", &StatusError{StatusCode: http.StatusNotFound}, http.StatusNotFound},
+		{"server error", &StatusError{StatusCode: http.StatusInternalServerError}, http.StatusBadGateway},
+		{"connect error", &ConnectError{URL: "http://ollama", Err: errors.New("refused")}, http.StatusBadGateway},
+		{"timeout", fmt.Errorf("generate: %w", context.DeadlineExceeded), http.StatusGatewayTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GatewayStatus(tt.err); got != tt.want {
+				t.Errorf("GatewayStatus(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientStatusErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		message string
+	}{
+		{"json error", http.StatusNotFound, `{"error":"model 'x' not found"}`, "model 'x' not found"},
+		{"plain text", http.StatusBadGateway, "bad gateway\n", "bad gateway"},
+		{"empty body", http.StatusInternalServerError, "", "Internal Server Error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			_, err := NewClient(srv.URL).Tags(context.Background())
+			var statusErr *StatusError
+			if !errors.As(err, &statusErr) {
+				t.Fatalf("Tags returned %v, want a *StatusError", err)
+			}
+			if statusErr.StatusCode != tt.status || statusErr.Message != tt.message {
+				t.Errorf("got status %d message %q, want %d %q", statusErr.StatusCode, statusErr.Message, tt.status, tt.message)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package ollama
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+)
+
+// maxChunkSize bounds a single line of a streamed response
+const maxChunkSize = 16 * 1024 * 1024
+
+// Stream iterates over the chunks of a newline-delimited JSON response.
+// It must be closed when no longer needed.
+type Stream[T any] struct {
+	body    io.ReadCloser
+	scanner *bufio.Scanner
+	current *T
+	raw     []byte
+	err     error
+}
+
+func newStream[T any](body io.ReadCloser) *Stream[T] {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxChunkSize)
+	return &Stream[T]{body: body, scanner: scanner}
+}
+
+// Next advances to the next chunk. It returns false at the end of the
+// stream or on error; check Err afterwards.
+func (s *Stream[T]) Next() bool {
+	if s.err != nil {
+		return false
+	}
+
+	for s.scanner.Scan() {
+		line := bytes.TrimSpace(s.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var chunkErr struct {
+			Error string `json:"error"`
+		}
+		if err := json.Unmarshal(line, &chunkErr); err == nil && chunkErr.Error != "" {
+			s.err = &StatusError{StatusCode: http.StatusInternalServerError, Message: chunkErr.Error}
+			return false
+		}
+
+		var chunk T
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			s.err = fmt.Errorf("ollama: failed to decode stream chunk: %w", err)
+			return false
+		}
+		s.current = &chunk
+		s.raw = append(s.raw[:0], line...)
+		return true
+	}
+
+	s.err = s.scanner.Err()
+	return false
+}
+
+// Current returns the chunk read by the last call to Next
+func (s *Stream[T]) Current() *T {
+	return s.current
+}
+
+// Raw returns the undecoded JSON of the current chunk. It is only valid
+// until the next call to Next.
+func (s *Stream[T]) Raw() []byte {
+	return s.raw
+}
+
+// Err returns the error that ended the stream, if any
+func (s *Stream[T]) Err() error {
+	return s.err
+}
+
+// Close releases the underlying connection
+func (s *Stream[T]) Close() error {
+	return s.body.Close()
+}
+
+// All returns an iterator over the remaining chunks. A failure is yielded
+// as a final (nil, err) pair. The stream is closed when iteration ends.
+func (s *Stream[T]) All() iter.Seq2[*T, error] {
+	return func(yield func(*T, error) bool) {
+		defer s.Close()
+		for s.Next() {
+			if !yield(s.Current(), nil) {
+				return
+			}
+		}
+		if err := s.Err(); err != nil {
+			yield(nil, err)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package ollama
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ollama-web-api/internal/models"
+)
+
+func TestStreamDecoding(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "chunks",
+			body: `{"status":"pulling manifest"}` + "\n" + `{"status":"success"}` + "\n",
+			want: []string{"pulling manifest", "success"},
+		},
+		{
+			name: "blank lines and no final newline",
+			body: "\n" + `{"status":"a"}` + "\n\n  \n" + `{"status":"b"}`,
+			want: []string{"a", "b"},
+		},
+		{
+			name: "empty",
+			body: "",
+		},
+		{
+			name:    "error chunk",
+			body:    `{"status":"a"}` + "\n" + `{"error":"out of memory"}` + "\n" + `{"status":"b"}` + "\n",
+			want:    []string{"a"},
+			wantErr: "out of memory",
+		},
+		{
+			name:    "invalid json",
+			body:    `{"status":"a"}` + "\n" + `{"status":` + "\n",
+			want:    []string{"a"},
+			wantErr: "failed to decode stream chunk",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newStream[ProgressResponse](io.NopCloser(strings.NewReader(tt.body)))
+			defer s.Close()
+
+			var got []string
+			for s.Next() {
+				got = append(got, s.Current().Status)
+				if !strings.Contains(string(s.Raw()), s.Current().Status) {
+					t.Errorf("Raw() = %s does not match the chunk", s.Raw())
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("chunks = %v, want %v", got, tt.want)
+			}
+
+			err := s.Err()
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("Err() = %v, want nil", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("Err() = %v, want %q", err, tt.wantErr)
+			}
+			if s.Next() {
+				t.Error("Next() = true after the stream ended")
+			}
+		})
+	}
+}
+
+func TestStreamErrorChunkIsUnavailable(t *testing.T) {
+	s := newStream[ProgressResponse](io.NopCloser(strings.NewReader(`{"error":"boom"}`)))
+	for s.Next() {
+	}
+	var statusErr *StatusError
+	if !errors.As(s.Err(), &statusErr) || !errors.Is(s.Err(), ErrUnavailable) {
+		t.Errorf("Err() = %v, want a server status error", s.Err())
+	}
+}
+
+func TestStreamAll(t *testing.T) {
+	body := `{"status":"a"}` + "\n" + `{"status":"b"}` + "\n" + `{"error":"boom"}` + "\n"
+	s := newStream[ProgressResponse](io.NopCloser(strings.NewReader(body)))
+
+	var got []string
+	var last error
+	for chunk, err := range s.All() {
+		if err != nil {
+			last = err
+			continue
+		}
+		got = append(got, chunk.Status)
+	}
+	if !reflect.DeepEqual(got, []string{"a", "b"}) || last == nil {
+		t.Errorf("All() yielded %v and error %v", got, last)
+	}
+}
+
+func TestClientGenerateStream(t *testing.T) {
+	var stream bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		stream = strings.Contains(string(body), `"stream":true`)
+		w.Write([]byte(`{"model":"llama2","response":"Hel","done":false}` + "\n"))
+		w.Write([]byte(`{"model":"llama2","response":"lo","done":true,"eval_count":2}` + "\n"))
+	}))
+	defer srv.Close()
+
+	s, err := NewClient(srv.URL).GenerateStream(context.Background(), &models.OllamaRequest{Model: "llama2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	var text string
+	var final *models.OllamaResponse
+	for s.Next() {
+		text += s.Current().Response
+		final = s.Current()
+	}
+	if err := s.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if !stream {
+		t.Error("the request did not ask for a stream")
+	}
+	if text != "Hello" || final == nil || !final.Done || final.EvalCount != 2 {
+		t.Errorf("got %q and final chunk %+v", text, final)
+	}
+}
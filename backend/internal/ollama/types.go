@@ -0,0 +1,106 @@
+package ollama
+
+import (
+	"time"
+
+	"github.com/ollama-web-api/internal/models"
+)
+
+// ModelDetails describes the format and size of a model
+type ModelDetails struct {
+	ParentModel       string   `json:"parent_model,omitempty"`
+	Format            string   `json:"format,omitempty"`
+	Family            string   `json:"family,omitempty"`
+	Families          []string `json:"families,omitempty"`
+	ParameterSize     string   `json:"parameter_size,omitempty"`
+	QuantizationLevel string   `json:"quantization_level,omitempty"`
+}
+
+// ListModel is a model installed on the server
+type ListModel struct {
+	Name       string       `json:"name"`
+	Model      string       `json:"model"`
+	ModifiedAt time.Time    `json:"modified_at"`
+	Size       int64        `json:"size"`
+	Digest     string       `json:"digest"`
+	Details    ModelDetails `json:"details"`
+}
+
+// ListResponse is the response of /api/tags
+type ListResponse struct {
+	Models []ListModel `json:"models"`
+}
+
+// ProcessModel is a model loaded into memory
+type ProcessModel struct {
+	Name      string       `json:"name"`
+	Model     string       `json:"model"`
+	Size      int64        `json:"size"`
+	Digest    string       `json:"digest"`
+	Details   ModelDetails `json:"details"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	SizeVRAM  int64        `json:"size_vram"`
+}
+
+// ProcessResponse is the response of /api/ps
+type ProcessResponse struct {
+	Models []ProcessModel `json:"models"`
+}
+
+// PullRequest is the request of /api/pull
+type PullRequest struct {
+	Model    string `json:"model"`
+	Insecure bool   `json:"insecure,omitempty"`
+}
+
+// ProgressResponse is a progress update of /api/pull and /api/create
+type ProgressResponse struct {
+	Status    string `json:"status"`
+	Digest    string `json:"digest,omitempty"`
+	Total     int64  `json:"total,omitempty"`
+	Completed int64  `json:"completed,omitempty"`
+}
+
+// DeleteRequest is the request of /api/delete
+type DeleteRequest struct {
+	Model string `json:"model"`
+}
+
+// ShowRequest is the request of /api/show
+type ShowRequest struct {
+	Model   string `json:"model"`
+	Verbose bool   `json:"verbose,omitempty"`
+}
+
+// ShowResponse is the response of /api/show
+type ShowResponse struct {
+	License      string                 `json:"license,omitempty"`
+	Modelfile    string                 `json:"modelfile,omitempty"`
+	Parameters   string                 `json:"parameters,omitempty"`
+	Template     string                 `json:"template,omitempty"`
+	System       string                 `json:"system,omitempty"`
+	Details      ModelDetails           `json:"details,omitempty"`
+	ModelInfo    map[string]interface{} `json:"model_info,omitempty"`
+	Capabilities []string               `json:"capabilities,omitempty"`
+	ModifiedAt   time.Time              `json:"modified_at,omitempty"`
+}
+
+// CopyRequest is the request of /api/copy
+type CopyRequest struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+}
+
+// CreateRequest is the request of /api/create
+type CreateRequest struct {
+	Model      string                 `json:"model"`
+	From       string                 `json:"from,omitempty"`
+	Files      map[string]string      `json:"files,omitempty"`
+	Adapters   map[string]string      `json:"adapters,omitempty"`
+	Template   string                 `json:"template,omitempty"`
+	License    interface{}            `json:"license,omitempty"`
+	System     string                 `json:"system,omitempty"`
+	Parameters map[string]interface{} `json:"parameters,omitempty"`
+	Messages   []models.ChatMessage   `json:"messages,omitempty"`
+	Quantize   string                 `json:"quantize,omitempty"`
+}
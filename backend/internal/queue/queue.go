@@ -0,0 +1,412 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ollama-web-api/internal/backends"
+	"github.com/ollama-web-api/internal/models"
+)
+
+// Priority tiers. Waiting requests of a higher tier are always admitted
+// before those of a lower tier.
+const (
+	PriorityHigh   = "high"
+	PriorityNormal = "normal"
+	PriorityLow    = "low"
+)
+
+// ErrTimeout is returned when a request waited longer than the maximum
+// queue wait without getting a slot
+var ErrTimeout = errors.New("queue: maximum wait exceeded")
+
+// tier orders the priorities, lowest first
+func tier(priority string) int {
+	switch priority {
+	case PriorityHigh:
+		return 0
+	case PriorityLow:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// ValidPriority reports whether p is a known priority; empty means normal
+func ValidPriority(p string) bool {
+	return p == "" || p == PriorityHigh || p == PriorityNormal || p == PriorityLow
+}
+
+// Request describes a request waiting for a slot
+type Request struct {
+	Model     string
+	ProjectID uint
+	Priority  string
+	// Weight is the share of capacity of the project relative to the other
+	// projects in its tier; values below 1 count as 1
+	Weight int
+}
+
+// Ticket is an admitted request. It holds a slot on Backend until released.
+type Ticket struct {
+	// Backend is the backend the slot was granted on, nil when the pool is
+	// empty
+	Backend *backends.Backend
+	// Position is the number of requests that were ahead when it was queued
+	Position int
+	// Waited is the time spent in the queue
+	Waited time.Duration
+
+	once    sync.Once
+	release func()
+}
+
+// Release frees the slot. It is safe to call more than once.
+func (t *Ticket) Release() {
+	t.once.Do(func() {
+		if t.release != nil {
+			t.release()
+		}
+	})
+}
+
+type waiter struct {
+	req      Request
+	tier     int
+	start    float64
+	finish   float64
+	seq      uint64
+	enqueued time.Time
+	// granted receives the backend once a slot is assigned
+	granted chan *backends.Backend
+}
+
+// before reports whether w is admitted before o
+func (w *waiter) before(o *waiter) bool {
+	if w.tier != o.tier {
+		return w.tier < o.tier
+	}
+	if w.start != o.start {
+		return w.start < o.start
+	}
+	return w.seq < o.seq
+}
+
+// modelQueue schedules the requests for one model with start-time fair
+// queuing: each request is tagged with the virtual time at which its
+// project is due, which advances by 1/weight per request, and the lowest
+// tag is admitted first
+type modelQueue struct {
+	waiting []*waiter
+	vtime   float64
+	// due is the virtual time at which the next request of a project starts
+	due             map[uint]float64
+	inflight        map[*backends.Backend]int
+	projectInflight map[uint]int
+}
+
+func (q *modelQueue) idle() bool {
+	return len(q.waiting) == 0 && len(q.inflight) == 0
+}
+
+// remove takes w out of the queue and reports whether it was still waiting
+func (q *modelQueue) remove(w *waiter) bool {
+	for i, o := range q.waiting {
+		if o == w {
+			q.waiting = append(q.waiting[:i], q.waiting[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Scheduler admits requests to the backends, holding them in a queue per
+// model while every backend serving the model is at capacity
+type Scheduler struct {
+	// maxConcurrent is the number of requests a backend runs per model;
+	// zero disables queuing
+	maxConcurrent int
+	maxWait       time.Duration
+
+	mu     sync.Mutex
+	queues map[string]*modelQueue
+	seq    uint64
+}
+
+// NewScheduler creates a scheduler that runs up to maxConcurrent requests per
+// backend and model and lets requests wait up to maxWait for a slot
+func NewScheduler(maxConcurrent int, maxWait time.Duration) *Scheduler {
+	return &Scheduler{
+		maxConcurrent: maxConcurrent,
+		maxWait:       maxWait,
+		queues:        make(map[string]*modelQueue),
+	}
+}
+
+// MaxWait returns how long a request may wait for a slot
+func (s *Scheduler) MaxWait() time.Duration {
+	return s.maxWait
+}
+
+func (s *Scheduler) queue(model string) *modelQueue {
+	q, ok := s.queues[model]
+	if !ok {
+		q = &modelQueue{
+			due:             make(map[uint]float64),
+			inflight:        make(map[*backends.Backend]int),
+			projectInflight: make(map[uint]int),
+		}
+		s.queues[model] = q
+	}
+	return q
+}
+
+// Acquire waits for a slot for the request. It fails with ErrTimeout after
+// the maximum wait and with the context error when ctx ends first.
+func (s *Scheduler) Acquire(ctx context.Context, req Request) (*Ticket, error) {
+	model := backends.NormalizeModel(req.Model)
+	weight := max(req.Weight, 1)
+
+	s.mu.Lock()
+	q := s.queue(model)
+	s.seq++
+	w := &waiter{
+		req:      req,
+		tier:     tier(req.Priority),
+		start:    max(q.vtime, q.due[req.ProjectID]),
+		seq:      s.seq,
+		enqueued: time.Now(),
+		granted:  make(chan *backends.Backend, 1),
+	}
+	w.finish = w.start + 1/float64(weight)
+	q.due[req.ProjectID] = w.finish
+	q.waiting = append(q.waiting, w)
+
+	position := 0
+	for _, o := range q.waiting {
+		if o.before(w) {
+			position++
+		}
+	}
+	s.dispatch(model, q)
+	s.mu.Unlock()
+
+	timer := time.NewTimer(s.maxWait)
+	defer timer.Stop()
+
+	var err error
+	select {
+	case backend := <-w.granted:
+		return s.ticket(model, w, backend, position), nil
+	case <-timer.C:
+		err = ErrTimeout
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	s.mu.Lock()
+	if q.remove(w) {
+		// Give the unused share back so the project is not penalized
+		if q.due[req.ProjectID] == w.finish {
+			q.due[req.ProjectID] = w.start
+		}
+		if q.idle() {
+			delete(s.queues, model)
+		}
+		s.mu.Unlock()
+		return nil, err
+	}
+	s.mu.Unlock()
+
+	// The slot was granted while giving up; hand it back
+	s.ticket(model, w, <-w.granted, position).Release()
+	return nil, err
+}
+
+func (s *Scheduler) ticket(model string, w *waiter, backend *backends.Backend, position int) *Ticket {
+	return &Ticket{
+		Backend:  backend,
+		Position: position,
+		Waited:   time.Since(w.enqueued),
+		release: func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+
+			q := s.queue(model)
+			if backend != nil {
+				if q.inflight[backend]--; q.inflight[backend] <= 0 {
+					delete(q.inflight, backend)
+				}
+			}
+			if q.projectInflight[w.req.ProjectID]--; q.projectInflight[w.req.ProjectID] <= 0 {
+				delete(q.projectInflight, w.req.ProjectID)
+			}
+			s.dispatch(model, q)
+			if q.idle() {
+				delete(s.queues, model)
+			}
+		},
+	}
+}
+
+// slot returns a backend with a free slot for the model. The backend is nil
+// when the pool is empty, in which case the request is let through to fail.
+// The caller must hold s.mu.
+func (s *Scheduler) slot(model string, q *modelQueue) (*backends.Backend, bool) {
+	candidates := backends.Candidates(model)
+	if len(candidates) == 0 {
+		return nil, true
+	}
+	if s.maxConcurrent <= 0 {
+		return candidates[0], true
+	}
+	for _, b := range candidates {
+		if q.inflight[b] < s.maxConcurrent {
+			return b, true
+		}
+	}
+	return nil, false
+}
+
+// dispatch admits waiting requests in order while slots are free. The
+// caller must hold s.mu.
+func (s *Scheduler) dispatch(model string, q *modelQueue) {
+	for len(q.waiting) > 0 {
+		next := 0
+		for i, w := range q.waiting {
+			if w.before(q.waiting[next]) {
+				next = i
+			}
+		}
+
+		backend, ok := s.slot(model, q)
+		if !ok {
+			return
+		}
+
+		w := q.waiting[next]
+		q.waiting = append(q.waiting[:next], q.waiting[next+1:]...)
+		q.vtime = w.start
+		if backend != nil {
+			q.inflight[backend]++
+		}
+		q.projectInflight[w.req.ProjectID]++
+		w.granted <- backend
+	}
+}
+
+// redispatch admits waiting requests of every model, picking up capacity
+// from backends that joined the pool or recovered
+func (s *Scheduler) redispatch() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for model, q := range s.queues {
+		s.dispatch(model, q)
+	}
+}
+
+// Status returns the live queue depth of every model with waiting or running
+// requests
+func (s *Scheduler) Status() []models.QueueStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	statuses := make([]models.QueueStatus, 0, len(s.queues))
+	for model, q := range s.queues {
+		status := models.QueueStatus{Model: model}
+
+		projects := make(map[uint]*models.QueueProjectStatus)
+		project := func(id uint) *models.QueueProjectStatus {
+			p, ok := projects[id]
+			if !ok {
+				p = &models.QueueProjectStatus{ProjectID: id}
+				projects[id] = p
+			}
+			return p
+		}
+
+		for _, w := range q.waiting {
+			status.Waiting++
+			if wait := now.Sub(w.enqueued).Seconds(); wait > status.OldestWaitSeconds {
+				status.OldestWaitSeconds = wait
+			}
+			p := project(w.req.ProjectID)
+			p.Waiting++
+			p.Priority = w.req.Priority
+		}
+		for id, n := range q.projectInflight {
+			status.InFlight += n
+			project(id).InFlight = n
+		}
+		for _, p := range projects {
+			if p.Priority == "" {
+				p.Priority = PriorityNormal
+			}
+			status.Projects = append(status.Projects, *p)
+		}
+		sort.Slice(status.Projects, func(i, j int) bool {
+			return status.Projects[i].ProjectID < status.Projects[j].ProjectID
+		})
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Model < statuses[j].Model
+	})
+	return statuses
+}
+
+var (
+	scheduler = NewScheduler(4, 30*time.Second)
+	mu        sync.RWMutex
+)
+
+// Setup configures the scheduler from QUEUE_MAX_CONCURRENT, the requests a
+// backend runs at once per model (default 4, 0 disables queuing), and
+// QUEUE_MAX_WAIT, how long a request may wait for a slot (default 30s)
+func Setup() {
+	maxConcurrent := 4
+	if v := os.Getenv("QUEUE_MAX_CONCURRENT"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			maxConcurrent = n
+		} else {
+			log.Printf("Invalid QUEUE_MAX_CONCURRENT %q, using %d", v, maxConcurrent)
+		}
+	}
+
+	maxWait := 30 * time.Second
+	if v := os.Getenv("QUEUE_MAX_WAIT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			maxWait = d
+		} else {
+			log.Printf("Invalid QUEUE_MAX_WAIT %q, using %s", v, maxWait)
+		}
+	}
+
+	s := NewScheduler(maxConcurrent, maxWait)
+	mu.Lock()
+	scheduler = s
+	mu.Unlock()
+	log.Printf("Request queue allows %d concurrent requests per backend and model, waiting up to %s", maxConcurrent, maxWait)
+
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for range ticker.C {
+			s.redispatch()
+		}
+	}()
+}
+
+// Default returns the scheduler configured by Setup
+func Default() *Scheduler {
+	mu.RLock()
+	defer mu.RUnlock()
+	return scheduler
+}
@@ -0,0 +1,231 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ollama-web-api/internal/backends"
+)
+
+// setBackend makes the pool a single backend for the duration of a test
+func setBackend(t *testing.T) {
+	t.Helper()
+	t.Setenv("OLLAMA_BACKENDS", "test=http://test.invalid")
+	if err := backends.Reload(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// waiting returns the number of requests waiting for the model
+func waiting(s *Scheduler, model string) int {
+	for _, status := range s.Status() {
+		if status.Model == backends.NormalizeModel(model) {
+			return status.Waiting
+		}
+	}
+	return 0
+}
+
+// waitUntil fails the test unless cond becomes true within a second
+func waitUntil(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the queue")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// hold acquires the only slot of a scheduler with one slot
+func hold(t *testing.T, s *Scheduler) *Ticket {
+	t.Helper()
+	ticket, err := s.Acquire(context.Background(), Request{Model: "llama2", ProjectID: 99})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ticket
+}
+
+// dispatchOrder queues reqs one after another behind a held slot, releases
+// it and returns the labels of the requests in the order they were admitted
+func dispatchOrder(t *testing.T, reqs []Request, labels []string) []string {
+	t.Helper()
+	setBackend(t)
+	s := NewScheduler(1, 5*time.Second)
+	held := hold(t, s)
+
+	var (
+		mu    sync.Mutex
+		order []string
+		wg    sync.WaitGroup
+	)
+	for i, req := range reqs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ticket, err := s.Acquire(context.Background(), req)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			order = append(order, labels[i])
+			mu.Unlock()
+			ticket.Release()
+		}()
+		waitUntil(t, func() bool { return waiting(s, "llama2") == i+1 })
+	}
+
+	held.Release()
+	wg.Wait()
+	return order
+}
+
+func TestPriorityTiers(t *testing.T) {
+	reqs := []Request{
+		{Model: "llama2", ProjectID: 1, Priority: PriorityLow},
+		{Model: "llama2", ProjectID: 2, Priority: PriorityNormal},
+		{Model: "llama2", ProjectID: 3, Priority: PriorityHigh},
+		{Model: "llama2", ProjectID: 4},
+		{Model: "llama2", ProjectID: 5, Priority: PriorityHigh},
+	}
+	got := dispatchOrder(t, reqs, []string{"low", "normal", "high", "default", "high 2"})
+	want := []string{"high", "high 2", "normal", "default", "low"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("admitted %v, want %v", got, want)
+	}
+}
+
+func TestWeightedFairOrder(t *testing.T) {
+	// Project 2 has twice the weight, so it gets two turns for each of
+	// project 1's even though project 1 queued first
+	var reqs []Request
+	var labels []string
+	for range 3 {
+		reqs = append(reqs, Request{Model: "llama2", ProjectID: 1, Weight: 1})
+		labels = append(labels, "p1")
+	}
+	for range 4 {
+		reqs = append(reqs, Request{Model: "llama2", ProjectID: 2, Weight: 2})
+		labels = append(labels, "p2")
+	}
+	got := dispatchOrder(t, reqs, labels)
+	want := []string{"p1", "p2", "p2", "p1", "p2", "p2", "p1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("admitted %v, want %v", got, want)
+	}
+}
+
+func TestConcurrencyCap(t *testing.T) {
+	setBackend(t)
+	s := NewScheduler(2, 5*time.Second)
+
+	var tickets []*Ticket
+	for range 2 {
+		ticket, err := s.Acquire(context.Background(), Request{Model: "llama2", ProjectID: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ticket.Backend == nil || ticket.Backend.Name != "test" {
+			t.Fatalf("ticket on backend %v, want test", ticket.Backend)
+		}
+		tickets = append(tickets, ticket)
+	}
+
+	admitted := make(chan *Ticket)
+	go func() {
+		ticket, err := s.Acquire(context.Background(), Request{Model: "llama2", ProjectID: 1})
+		if err != nil {
+			t.Error(err)
+		}
+		admitted <- ticket
+	}()
+	waitUntil(t, func() bool { return waiting(s, "llama2") == 1 })
+
+	// Another model has its own slots
+	other, err := s.Acquire(context.Background(), Request{Model: "mistral", ProjectID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other.Release()
+
+	select {
+	case <-admitted:
+		t.Fatal("a third request was admitted while both slots were taken")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	tickets[0].Release()
+	tickets[0].Release() // releasing twice frees one slot
+	third := <-admitted
+	if third.Position != 0 {
+		t.Errorf("Position = %d, want 0", third.Position)
+	}
+	third.Release()
+	tickets[1].Release()
+
+	if status := s.Status(); len(status) != 0 {
+		t.Errorf("Status() = %+v after every ticket was released, want empty", status)
+	}
+}
+
+func TestMaxWait(t *testing.T) {
+	setBackend(t)
+	s := NewScheduler(1, 30*time.Millisecond)
+	held := hold(t, s)
+	defer held.Release()
+
+	started := time.Now()
+	_, err := s.Acquire(context.Background(), Request{Model: "llama2", ProjectID: 1})
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("Acquire error = %v, want ErrTimeout", err)
+	}
+	if waited := time.Since(started); waited < 30*time.Millisecond {
+		t.Errorf("gave up after %s, before the maximum wait", waited)
+	}
+	if n := waiting(s, "llama2"); n != 0 {
+		t.Errorf("%d requests still waiting after the timeout", n)
+	}
+}
+
+func TestAcquireCancelled(t *testing.T) {
+	setBackend(t)
+	s := NewScheduler(1, 5*time.Second)
+	held := hold(t, s)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		_, err := s.Acquire(ctx, Request{Model: "llama2", ProjectID: 1})
+		done <- err
+	}()
+	waitUntil(t, func() bool { return waiting(s, "llama2") == 1 })
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Acquire error = %v, want context.Canceled", err)
+	}
+
+	// The slot goes to the next request rather than the cancelled one
+	held.Release()
+	ticket, err := s.Acquire(context.Background(), Request{Model: "llama2", ProjectID: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ticket.Release()
+}
+
+func TestQueuingDisabled(t *testing.T) {
+	setBackend(t)
+	s := NewScheduler(0, time.Millisecond)
+	for range 10 {
+		if _, err := s.Acquire(context.Background(), Request{Model: "llama2", ProjectID: 1}); err != nil {
+			t.Fatalf("Acquire with queuing disabled: %v", err)
+		}
+	}
+}
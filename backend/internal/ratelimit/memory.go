@@ -0,0 +1,79 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+type counter struct {
+	window   time.Time
+	requests int
+	tokens   int
+}
+
+// MemoryStore keeps rate limit state in-process
+type MemoryStore struct {
+	mu       sync.Mutex
+	counters map[string]*counter
+	inflight map[string]int
+}
+
+// NewMemoryStore creates an empty in-process store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		counters: make(map[string]*counter),
+		inflight: make(map[string]int),
+	}
+}
+
+// counterFor returns the counter of key for window, resetting it when a new
+// window has started. The caller must hold s.mu.
+func (s *MemoryStore) counterFor(key string, window time.Time) *counter {
+	c, ok := s.counters[key]
+	if !ok || !c.window.Equal(window) {
+		c = &counter{window: window}
+		s.counters[key] = c
+	}
+	return c
+}
+
+// Hit implements Store
+func (s *MemoryStore) Hit(key string, window time.Time) (int, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.counterFor(key, window)
+	c.requests++
+	return c.requests, c.tokens, nil
+}
+
+// AddTokens implements Store
+func (s *MemoryStore) AddTokens(key string, window time.Time, tokens int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.counterFor(key, window).tokens += tokens
+	return nil
+}
+
+// Acquire implements Store
+func (s *MemoryStore) Acquire(key string, max int) (func(), bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.inflight[key] >= max {
+		return nil, false, nil
+	}
+	s.inflight[key]++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			if s.inflight[key]--; s.inflight[key] <= 0 {
+				delete(s.inflight, key)
+			}
+		})
+	}, true, nil
+}
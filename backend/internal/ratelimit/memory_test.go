@@ -0,0 +1,152 @@
+package ratelimit
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreWindows(t *testing.T) {
+	w1 := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	w2 := w1.Add(Window)
+
+	type step struct {
+		key          string
+		window       time.Time
+		tokens       int // added with AddTokens when set, otherwise a Hit
+		wantRequests int
+		wantTokens   int
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "hits count up",
+			steps: []step{
+				{key: "a", window: w1, wantRequests: 1},
+				{key: "a", window: w1, wantRequests: 2},
+				{key: "a", window: w1, wantRequests: 3},
+			},
+		},
+		{
+			name: "tokens add up within the window",
+			steps: []step{
+				{key: "a", window: w1, wantRequests: 1},
+				{key: "a", window: w1, tokens: 100},
+				{key: "a", window: w1, tokens: 50},
+				{key: "a", window: w1, wantRequests: 2, wantTokens: 150},
+			},
+		},
+		{
+			name: "a new window starts from zero",
+			steps: []step{
+				{key: "a", window: w1, wantRequests: 1},
+				{key: "a", window: w1, tokens: 100},
+				{key: "a", window: w2, wantRequests: 1},
+			},
+		},
+		{
+			name: "tokens of a new window",
+			steps: []step{
+				{key: "a", window: w1, wantRequests: 1},
+				{key: "a", window: w2, tokens: 30},
+				{key: "a", window: w2, wantRequests: 1, wantTokens: 30},
+			},
+		},
+		{
+			name: "keys are separate",
+			steps: []step{
+				{key: "a", window: w1, wantRequests: 1},
+				{key: "a", window: w1, tokens: 10},
+				{key: "b", window: w1, wantRequests: 1},
+				{key: "a", window: w1, wantRequests: 2, wantTokens: 10},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewMemoryStore()
+			for i, st := range tt.steps {
+				if st.tokens > 0 {
+					if err := s.AddTokens(st.key, st.window, st.tokens); err != nil {
+						t.Fatal(err)
+					}
+					continue
+				}
+				requests, tokens, err := s.Hit(st.key, st.window)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if requests != st.wantRequests || tokens != st.wantTokens {
+					t.Errorf("step %d: Hit(%s) = %d requests, %d tokens, want %d, %d",
+						i, st.key, requests, tokens, st.wantRequests, st.wantTokens)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryStoreAcquire(t *testing.T) {
+	s := NewMemoryStore()
+
+	var releases []func()
+	for i := 0; i < 2; i++ {
+		release, ok, err := s.Acquire("a", 2)
+		if err != nil || !ok {
+			t.Fatalf("Acquire %d = %v, %v, want a slot", i, ok, err)
+		}
+		releases = append(releases, release)
+	}
+	if _, ok, _ := s.Acquire("a", 2); ok {
+		t.Fatal("a third slot was granted with a maximum of 2")
+	}
+	if release, ok, _ := s.Acquire("b", 2); !ok {
+		t.Fatal("another key was refused")
+	} else {
+		release()
+	}
+
+	// Releasing twice frees one slot only
+	releases[0]()
+	releases[0]()
+	release, ok, _ := s.Acquire("a", 2)
+	if !ok {
+		t.Fatal("a released slot was not granted again")
+	}
+	if _, ok, _ := s.Acquire("a", 2); ok {
+		t.Fatal("a slot released twice was counted twice")
+	}
+
+	release()
+	releases[1]()
+	if n := len(s.inflight); n != 0 {
+		t.Errorf("%d keys still tracked after every slot was released", n)
+	}
+}
+
+func TestMemoryStoreAcquireConcurrent(t *testing.T) {
+	s := NewMemoryStore()
+	const limit = 3
+
+	var (
+		wg        sync.WaitGroup
+		grantedMu sync.Mutex
+		granted   int
+	)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, ok, _ := s.Acquire("a", limit); ok {
+				grantedMu.Lock()
+				granted++
+				grantedMu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if granted != limit {
+		t.Errorf("%d slots granted to parallel requests, want %d", granted, limit)
+	}
+}
@@ -0,0 +1,143 @@
+package ratelimit
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/ollama-web-api/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// leaseTTL bounds how long a concurrency slot is held if a replica dies
+// without releasing it. Leases are renewed every leaseRenewal while held, so
+// requests and background jobs of any length keep their slot.
+const (
+	leaseTTL     = 2 * time.Minute
+	leaseRenewal = leaseTTL / 4
+)
+
+// PostgresStore keeps rate limit state in the database so that all
+// replicas share one budget
+type PostgresStore struct {
+	db *gorm.DB
+}
+
+// NewPostgresStore creates a database-backed store and starts a background
+// cleanup of expired windows and leases
+func NewPostgresStore(db *gorm.DB) *PostgresStore {
+	s := &PostgresStore{db: db}
+	go s.cleanup()
+	return s
+}
+
+func (s *PostgresStore) cleanup() {
+	ticker := time.NewTicker(Window)
+	defer ticker.Stop()
+	for range ticker.C {
+		now := time.Now()
+		if err := s.db.Where("window_start < ?", windowStart(now).Add(-Window)).Delete(&models.RateLimitCounter{}).Error; err != nil {
+			log.Printf("Failed to clean up rate limit counters: %v", err)
+		}
+		if err := s.db.Where("expires_at < ?", now).Delete(&models.RateLimitLease{}).Error; err != nil {
+			log.Printf("Failed to clean up rate limit leases: %v", err)
+		}
+	}
+}
+
+// upsert increments the counters of key for window and returns the totals
+func (s *PostgresStore) upsert(key string, window time.Time, requests, tokens int) (*models.RateLimitCounter, error) {
+	row := models.RateLimitCounter{Bucket: key, WindowStart: window, Requests: requests, Tokens: tokens}
+	err := s.db.Clauses(
+		clause.OnConflict{
+			Columns: []clause.Column{{Name: "bucket"}, {Name: "window_start"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"requests": gorm.Expr("rate_limit_counters.requests + ?", requests),
+				"tokens":   gorm.Expr("rate_limit_counters.tokens + ?", tokens),
+			}),
+		},
+		clause.Returning{},
+	).Create(&row).Error
+	if err != nil {
+		return nil, err
+	}
+	return &row, nil
+}
+
+// Hit implements Store
+func (s *PostgresStore) Hit(key string, window time.Time) (int, int, error) {
+	row, err := s.upsert(key, window, 1, 0)
+	if err != nil {
+		return 0, 0, err
+	}
+	return row.Requests, row.Tokens, nil
+}
+
+// AddTokens implements Store
+func (s *PostgresStore) AddTokens(key string, window time.Time, tokens int) error {
+	_, err := s.upsert(key, window, 0, tokens)
+	return err
+}
+
+// Acquire implements Store. Leases of a bucket are counted and inserted in
+// one transaction under an advisory lock on the bucket, so concurrent
+// replicas take turns and never admit more than max requests. The lease is
+// renewed until it is released.
+func (s *PostgresStore) Acquire(key string, max int) (func(), bool, error) {
+	now := time.Now()
+	lease := models.RateLimitLease{Bucket: key, ExpiresAt: now.Add(leaseTTL)}
+	acquired := false
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", key).Error; err != nil {
+			return err
+		}
+
+		var inflight int64
+		if err := tx.Model(&models.RateLimitLease{}).
+			Where("bucket = ? AND expires_at > ?", key, now).
+			Count(&inflight).Error; err != nil {
+			return err
+		}
+		if inflight >= int64(max) {
+			return nil
+		}
+
+		acquired = true
+		return tx.Create(&lease).Error
+	})
+	if err != nil || !acquired {
+		return nil, false, err
+	}
+
+	done := make(chan struct{})
+	go s.renew(&lease, done)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			if err := s.db.Delete(&models.RateLimitLease{}, lease.ID).Error; err != nil {
+				log.Printf("Failed to release rate limit lease %d: %v", lease.ID, err)
+			}
+		})
+	}, true, nil
+}
+
+// renew extends a lease until done is closed
+func (s *PostgresStore) renew(lease *models.RateLimitLease, done <-chan struct{}) {
+	ticker := time.NewTicker(leaseRenewal)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case now := <-ticker.C:
+			err := s.db.Model(&models.RateLimitLease{}).Where("id = ?", lease.ID).
+				Update("expires_at", now.Add(leaseTTL)).Error
+			if err != nil {
+				log.Printf("Failed to renew rate limit lease %d: %v", lease.ID, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package ratelimit
+
+import (
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ollama-web-api/internal/database"
+)
+
+// Window is the length of a rate limit window
+const Window = time.Minute
+
+// Limits configures the budgets for a single key. Zero means unlimited.
+type Limits struct {
+	RequestsPerMinute int
+	TokensPerMinute   int
+	MaxConcurrent     int
+}
+
+// Result describes the outcome of a rate limit check
+type Result struct {
+	Allowed bool
+	// Reason names the exhausted budget: "requests", "tokens" or "concurrency"
+	Reason string
+
+	RequestLimit     int
+	RequestRemaining int
+	TokenLimit       int
+	TokenRemaining   int
+	Reset            time.Time
+	RetryAfter       time.Duration
+
+	release func()
+}
+
+// Release frees the concurrency slot held by an allowed request. It is safe
+// to call more than once.
+func (r *Result) Release() {
+	if r.release != nil {
+		r.release()
+		r.release = nil
+	}
+}
+
+// Store keeps rate limit counters and in-flight request leases
+type Store interface {
+	// Hit counts a request in the current window and returns the request and
+	// token totals of that window
+	Hit(key string, window time.Time) (requests, tokens int, err error)
+	// AddTokens adds consumed tokens to the current window
+	AddTokens(key string, window time.Time, tokens int) error
+	// Acquire takes a concurrency slot if fewer than max are in flight
+	Acquire(key string, max int) (release func(), ok bool, err error)
+}
+
+var (
+	store Store = NewMemoryStore()
+	mu    sync.RWMutex
+)
+
+// Setup selects the rate limit store from RATE_LIMIT_STORE. "postgres"
+// shares budgets across replicas through the database; anything else keeps
+// them in-process.
+func Setup() {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if os.Getenv("RATE_LIMIT_STORE") == "postgres" {
+		store = NewPostgresStore(database.DB)
+		log.Println("Rate limiting uses the Postgres store")
+		return
+	}
+	store = NewMemoryStore()
+	log.Println("Rate limiting uses the in-memory store")
+}
+
+func currentStore() Store {
+	mu.RLock()
+	defer mu.RUnlock()
+	return store
+}
+
+// windowStart returns the start of the window containing t
+func windowStart(t time.Time) time.Time {
+	return t.Truncate(Window)
+}
+
+// Allow counts a request against the limits for key and, when allowed,
+// takes a concurrency slot that must be freed with Result.Release
+func Allow(key string, limits Limits) (*Result, error) {
+	now := time.Now()
+	window := windowStart(now)
+	s := currentStore()
+
+	result := &Result{
+		Allowed:      true,
+		RequestLimit: limits.RequestsPerMinute,
+		TokenLimit:   limits.TokensPerMinute,
+		Reset:        window.Add(Window),
+	}
+
+	if limits.RequestsPerMinute > 0 || limits.TokensPerMinute > 0 {
+		requests, tokens, err := s.Hit(key, window)
+		if err != nil {
+			return nil, err
+		}
+
+		result.RequestRemaining = max(limits.RequestsPerMinute-requests, 0)
+		result.TokenRemaining = max(limits.TokensPerMinute-tokens, 0)
+
+		if limits.RequestsPerMinute > 0 && requests > limits.RequestsPerMinute {
+			result.Allowed = false
+			result.Reason = "requests"
+		} else if limits.TokensPerMinute > 0 && tokens >= limits.TokensPerMinute {
+			result.Allowed = false
+			result.Reason = "tokens"
+		}
+		if !result.Allowed {
+			result.RetryAfter = result.Reset.Sub(now)
+			return result, nil
+		}
+	}
+
+	if limits.MaxConcurrent > 0 {
+		release, ok, err := s.Acquire(key, limits.MaxConcurrent)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			result.Allowed = false
+			result.Reason = "concurrency"
+			result.RetryAfter = time.Second
+			return result, nil
+		}
+		result.release = release
+	}
+
+	return result, nil
+}
+
+// AddTokens records tokens consumed by a completed request for key
+func AddTokens(key string, tokens int) error {
+	if tokens <= 0 {
+		return nil
+	}
+	return currentStore().AddTokens(key, windowStart(time.Now()), tokens)
+}
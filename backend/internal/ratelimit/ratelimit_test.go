@@ -0,0 +1,140 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+// useMemoryStore gives a test a fresh in-memory store and waits out the end
+// of the current window, so the calls of the test share one window
+func useMemoryStore(t *testing.T) {
+	t.Helper()
+	if left := time.Until(windowStart(time.Now()).Add(Window)); left < 2*time.Second {
+		time.Sleep(left)
+	}
+	mu.Lock()
+	previous := store
+	store = NewMemoryStore()
+	mu.Unlock()
+	t.Cleanup(func() {
+		mu.Lock()
+		store = previous
+		mu.Unlock()
+	})
+}
+
+func TestAllowRequests(t *testing.T) {
+	useMemoryStore(t)
+	limits := Limits{RequestsPerMinute: 3}
+
+	for i := 1; i <= 3; i++ {
+		result, err := Allow("p", limits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !result.Allowed || result.RequestRemaining != 3-i {
+			t.Fatalf("request %d: allowed %v with %d remaining", i, result.Allowed, result.RequestRemaining)
+		}
+	}
+
+	result, err := Allow("p", limits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Allowed || result.Reason != "requests" {
+		t.Fatalf("fourth request: allowed %v, reason %q", result.Allowed, result.Reason)
+	}
+	if result.RetryAfter <= 0 || result.RetryAfter > Window || !result.Reset.Equal(windowStart(time.Now()).Add(Window)) {
+		t.Errorf("retry after %s, reset %s", result.RetryAfter, result.Reset)
+	}
+}
+
+func TestAllowTokens(t *testing.T) {
+	useMemoryStore(t)
+	limits := Limits{TokensPerMinute: 100}
+
+	tests := []struct {
+		tokens        int
+		wantAllowed   bool
+		wantRemaining int
+	}{
+		// Tokens are counted after a request, so a request is refused only
+		// once the budget is used up
+		{tokens: 60, wantAllowed: true, wantRemaining: 100},
+		{tokens: 39, wantAllowed: true, wantRemaining: 40},
+		{tokens: 1, wantAllowed: true, wantRemaining: 1},
+		{wantAllowed: false, wantRemaining: 0},
+	}
+	for i, tt := range tests {
+		result, err := Allow("p", limits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Allowed != tt.wantAllowed || result.TokenRemaining != tt.wantRemaining {
+			t.Fatalf("request %d: allowed %v with %d tokens remaining, want %v, %d",
+				i+1, result.Allowed, result.TokenRemaining, tt.wantAllowed, tt.wantRemaining)
+		}
+		if !result.Allowed && result.Reason != "tokens" {
+			t.Errorf("request %d: reason %q, want tokens", i+1, result.Reason)
+		}
+		if err := AddTokens("p", tt.tokens); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestAllowConcurrency(t *testing.T) {
+	useMemoryStore(t)
+	limits := Limits{MaxConcurrent: 2}
+
+	first, _ := Allow("p", limits)
+	second, _ := Allow("p", limits)
+	if !first.Allowed || !second.Allowed {
+		t.Fatal("requests within the concurrency limit were refused")
+	}
+
+	third, err := Allow("p", limits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if third.Allowed || third.Reason != "concurrency" || third.RetryAfter != time.Second {
+		t.Fatalf("third request: allowed %v, reason %q, retry after %s", third.Allowed, third.Reason, third.RetryAfter)
+	}
+
+	first.Release()
+	first.Release()
+	fourth, _ := Allow("p", limits)
+	if !fourth.Allowed {
+		t.Fatal("a released slot was not granted again")
+	}
+	if fifth, _ := Allow("p", limits); fifth.Allowed {
+		t.Fatal("a slot released twice was counted twice")
+	}
+	second.Release()
+	fourth.Release()
+}
+
+func TestAllowRefusedRequestHoldsNoSlot(t *testing.T) {
+	useMemoryStore(t)
+	limits := Limits{RequestsPerMinute: 1, MaxConcurrent: 1}
+
+	first, _ := Allow("p", limits)
+	first.Release()
+	if refused, _ := Allow("p", limits); refused.Allowed {
+		t.Fatal("a request over the request limit was allowed")
+	}
+	if n := len(currentStore().(*MemoryStore).inflight); n != 0 {
+		t.Errorf("a refused request holds %d concurrency slots", n)
+	}
+}
+
+func TestAllowUnlimited(t *testing.T) {
+	useMemoryStore(t)
+	for i := 0; i < 100; i++ {
+		result, err := Allow("p", Limits{})
+		if err != nil || !result.Allowed {
+			t.Fatalf("request %d refused without limits: %v", i, err)
+		}
+		result.Release()
+	}
+}
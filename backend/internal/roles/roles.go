@@ -0,0 +1,38 @@
+package roles
+
+// Roles of admin users, from least to most privileged. Every role may do
+// everything the roles before it may do.
+const (
+	// Viewer can read projects, keys, models, backends and usage
+	Viewer = "viewer"
+	// Operator can also pull and unload models and manage which models
+	// projects may use
+	Operator = "operator"
+	// Admin can also manage projects, API keys, quotas and backends, delete
+	// models and read the audit log
+	Admin = "admin"
+	// Owner can also manage users and their roles
+	Owner = "owner"
+)
+
+// All lists every role from least to most privileged
+var All = []string{Viewer, Operator, Admin, Owner}
+
+func rank(role string) int {
+	for i, r := range All {
+		if r == role {
+			return i
+		}
+	}
+	return -1
+}
+
+// Valid reports whether role is a known role
+func Valid(role string) bool {
+	return rank(role) >= 0
+}
+
+// AtLeast reports whether role has the privileges of minimum
+func AtLeast(role, minimum string) bool {
+	return Valid(role) && rank(role) >= rank(minimum)
+}
@@ -0,0 +1,250 @@
+package sessions
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/ollama-web-api/internal/audit"
+	"github.com/ollama-web-api/internal/database"
+	"github.com/ollama-web-api/internal/middleware"
+	"github.com/ollama-web-api/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInvalidToken is returned for an unknown, expired or revoked refresh
+// token
+var ErrInvalidToken = errors.New("invalid refresh token")
+
+// ErrTokenReused is returned when a refresh token that was already replaced
+// is used again. The session is revoked and the reuse is written to the audit
+// log, since the token was probably stolen.
+var ErrTokenReused = errors.New("refresh token reused")
+
+var (
+	accessTTL  = 15 * time.Minute
+	refreshTTL = 7 * 24 * time.Hour
+)
+
+// Tokens are the access and refresh tokens of a session
+type Tokens struct {
+	Session      *models.Session
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    int
+}
+
+// Setup reads ACCESS_TOKEN_TTL, how long an access token is valid (default
+// 15m), and REFRESH_TOKEN_TTL, how long a session lasts without being
+// refreshed (default 168h)
+func Setup() {
+	if v := os.Getenv("ACCESS_TOKEN_TTL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			accessTTL = d
+		} else {
+			log.Printf("Invalid ACCESS_TOKEN_TTL %q, using %s", v, accessTTL)
+		}
+	}
+	if v := os.Getenv("REFRESH_TOKEN_TTL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			refreshTTL = d
+		} else {
+			log.Printf("Invalid REFRESH_TOKEN_TTL %q, using %s", v, refreshTTL)
+		}
+	}
+	log.Printf("Access tokens last %s, sessions %s without refreshing", accessTTL, refreshTTL)
+}
+
+// Start creates a session for a user who logged in
+func Start(user *models.User, ip, userAgent string) (*Tokens, error) {
+	cleanup()
+
+	refresh, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	session := &models.Session{
+		UserID:      user.ID,
+		RefreshHash: hash(refresh),
+		IP:          ip,
+		UserAgent:   userAgent,
+		ExpiresAt:   now.Add(refreshTTL),
+		LastUsedAt:  now,
+	}
+	if err := database.DB.Create(session).Error; err != nil {
+		return nil, err
+	}
+
+	access, err := middleware.GenerateToken(user, session.ID, accessTTL)
+	if err != nil {
+		return nil, err
+	}
+	err = database.DB.Model(session).Updates(map[string]interface{}{
+		"access_token_id":         access.ID,
+		"access_token_expires_at": access.ExpiresAt,
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+	return newTokens(session, access, refresh), nil
+}
+
+// Refresh replaces a refresh token with a new one and issues a new access
+// token for the same session
+func Refresh(refreshToken, ip string) (*Tokens, *models.User, error) {
+	if refreshToken == "" {
+		return nil, nil, ErrInvalidToken
+	}
+	presented := hash(refreshToken)
+
+	var session models.Session
+	if err := database.DB.Where("refresh_hash = ?", presented).Limit(1).Find(&session).Error; err != nil {
+		return nil, nil, err
+	}
+	if session.ID == 0 {
+		// A replaced token: whoever used it first has the session now
+		if err := database.DB.Where("previous_refresh_hash = ? AND revoked_at IS NULL", presented).Limit(1).Find(&session).Error; err != nil {
+			return nil, nil, err
+		}
+		if session.ID != 0 {
+			if err := Revoke(&session); err != nil {
+				return nil, nil, err
+			}
+			audit.Record(&models.AuditEvent{
+				Action: audit.ActionRefreshTokenReused,
+				IP:     ip,
+				Detail: fmt.Sprintf("session %d of user %d revoked", session.ID, session.UserID),
+			})
+			return nil, nil, ErrTokenReused
+		}
+		return nil, nil, ErrInvalidToken
+	}
+	if session.RevokedAt != nil || !session.ExpiresAt.After(time.Now()) {
+		return nil, nil, ErrInvalidToken
+	}
+
+	var user models.User
+	if err := database.DB.Limit(1).Find(&user, session.UserID).Error; err != nil {
+		return nil, nil, err
+	}
+	if user.ID == 0 || !user.IsActive {
+		return nil, nil, ErrInvalidToken
+	}
+
+	refresh, err := newRefreshToken()
+	if err != nil {
+		return nil, nil, err
+	}
+	access, err := middleware.GenerateToken(&user, session.ID, accessTTL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The hash condition makes concurrent refreshes with the same token
+	// rotate only once, and the revocation condition keeps a refresh racing
+	// a revoke from reviving the session
+	now := time.Now()
+	result := database.DB.Model(&session).Where("refresh_hash = ? AND revoked_at IS NULL", presented).Updates(map[string]interface{}{
+		"refresh_hash":            hash(refresh),
+		"previous_refresh_hash":   presented,
+		"access_token_id":         access.ID,
+		"access_token_expires_at": access.ExpiresAt,
+		"ip":                      ip,
+		"expires_at":              now.Add(refreshTTL),
+		"last_used_at":            now,
+	})
+	if result.Error != nil {
+		return nil, nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil, ErrInvalidToken
+	}
+	session.ExpiresAt = now.Add(refreshTTL)
+	session.LastUsedAt = now
+	return newTokens(&session, access, refresh), &user, nil
+}
+
+// Find returns the active session with the ID, or nil
+func Find(id uint) (*models.Session, error) {
+	var session models.Session
+	err := database.DB.Where("id = ? AND revoked_at IS NULL AND expires_at > ?", id, time.Now()).Limit(1).Find(&session).Error
+	if err != nil || session.ID == 0 {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// Revoke ends a session. Its refresh token stops working and its current
+// access token is put on the revocation list.
+func Revoke(session *models.Session) error {
+	now := time.Now()
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(session).Update("revoked_at", now).Error; err != nil {
+			return err
+		}
+		if session.AccessTokenID == "" || !session.AccessTokenExpiresAt.After(now) {
+			return nil
+		}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.RevokedToken{
+			JTI:       session.AccessTokenID,
+			ExpiresAt: session.AccessTokenExpiresAt,
+		}).Error
+	})
+}
+
+// RevokeUser ends all sessions of a user
+func RevokeUser(userID uint) error {
+	var active []models.Session
+	if err := database.DB.Where("user_id = ? AND revoked_at IS NULL", userID).Find(&active).Error; err != nil {
+		return err
+	}
+	for i := range active {
+		if err := Revoke(&active[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cleanup deletes sessions and revoked tokens that expired, as new sessions
+// start
+func cleanup() {
+	now := time.Now()
+	if err := database.DB.Where("expires_at < ?", now).Delete(&models.RevokedToken{}).Error; err != nil {
+		log.Printf("Failed to delete expired revoked tokens: %v", err)
+	}
+	if err := database.DB.Where("expires_at < ?", now).Delete(&models.Session{}).Error; err != nil {
+		log.Printf("Failed to delete expired sessions: %v", err)
+	}
+}
+
+func newTokens(session *models.Session, access *middleware.AccessToken, refresh string) *Tokens {
+	return &Tokens{
+		Session:      session,
+		AccessToken:  access.Token,
+		RefreshToken: refresh,
+		ExpiresIn:    int(time.Until(access.ExpiresAt).Round(time.Second).Seconds()),
+	}
+}
+
+func newRefreshToken() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
+
+// hash returns the hex SHA-256 of a refresh token. Refresh tokens carry 256
+// bits of randomness, so they need no salt and can be looked up by hash.
+func hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,102 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 seed of the RFC 6238 test vectors,
+// "12345678901234567890", in base32
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCodeRFC6238(t *testing.T) {
+	// The RFC lists 8-digit codes; 6-digit codes are their last six digits
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		step := Step(time.Unix(tt.unix, 0))
+		got, err := Code(rfcSecret, step)
+		if err != nil {
+			t.Fatalf("Code(%d): %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("Code at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestCodeLowercaseSecret(t *testing.T) {
+	got, err := Code(strings.ToLower(rfcSecret), 1)
+	if err != nil || got != "287082" {
+		t.Errorf("Code of a lowercase secret = %q, %v", got, err)
+	}
+	if _, err := Code("not base32!", 1); err == nil {
+		t.Error("Code accepted an invalid secret")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := Step(now)
+	code := func(step int64) string {
+		c, err := Code(rfcSecret, step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	tests := []struct {
+		name     string
+		code     string
+		lastStep int64
+		wantStep int64
+		wantOK   bool
+	}{
+		{name: "current step", code: code(current), wantStep: current, wantOK: true},
+		{name: "one step early", code: code(current - 1), wantStep: current - 1, wantOK: true},
+		{name: "one step late", code: code(current + 1), wantStep: current + 1, wantOK: true},
+		{name: "two steps early", code: code(current - 2)},
+		{name: "two steps late", code: code(current + 2)},
+		{name: "spaces", code: code(current)[:3] + " " + code(current)[3:] + " ", wantStep: current, wantOK: true},
+		{name: "too short", code: code(current)[:5]},
+		{name: "wrong code", code: "000000"},
+		{name: "replayed", code: code(current), lastStep: current},
+		{name: "earlier step after a later one was used", code: code(current - 1), lastStep: current},
+		{name: "later step after an earlier one was used", code: code(current + 1), lastStep: current, wantStep: current + 1, wantOK: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := Validate(rfcSecret, tt.code, now, tt.lastStep)
+			if ok != tt.wantOK || step != tt.wantStep {
+				t.Errorf("Validate(%q, lastStep %d) = %d, %v, want %d, %v", tt.code, tt.lastStep, step, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestValidateOnce(t *testing.T) {
+	now := time.Now()
+	code, err := Code(rfcSecret, Step(now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	step, ok := Validate(rfcSecret, code, now, 0)
+	if !ok {
+		t.Fatal("the current code was refused")
+	}
+	// Callers store the matched step, which turns the code away next time
+	if _, ok := Validate(rfcSecret, code, now, step); ok {
+		t.Error("a code was accepted twice")
+	}
+}
@@ -0,0 +1,314 @@
+package users
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ollama-web-api/internal/database"
+	"github.com/ollama-web-api/internal/models"
+	"github.com/ollama-web-api/internal/totp"
+	"gorm.io/gorm"
+)
+
+const (
+	// recoveryCodeCount is how many recovery codes a user gets at a time
+	recoveryCodeCount = 10
+
+	// challengeTTL is how long a user has to enter the two-factor code after
+	// the password
+	challengeTTL = 5 * time.Minute
+
+	// maxChallengeAttempts is how many wrong codes end a challenge
+	maxChallengeAttempts = 5
+
+	// policyCacheTTL is how long the security policy is cached, so that
+	// replicas pick up changes within it
+	policyCacheTTL = 10 * time.Second
+)
+
+// ErrInvalidCode is returned for a wrong, reused or expired two-factor code
+var ErrInvalidCode = errors.New("invalid two-factor code")
+
+// ErrInvalidChallenge is returned for an unknown or expired login challenge
+var ErrInvalidChallenge = errors.New("invalid or expired login challenge")
+
+var (
+	policyMu       sync.Mutex
+	policyRequire  bool
+	policyCachedAt time.Time
+)
+
+// totpIssuer names the service in authenticator apps, from TOTP_ISSUER
+func totpIssuer() string {
+	if issuer := os.Getenv("TOTP_ISSUER"); issuer != "" {
+		return issuer
+	}
+	return "Ollama Web API"
+}
+
+// BeginTwoFactor gives the user a new secret, which is only used for logins
+// once EnableTwoFactor confirmed a code of it
+func BeginTwoFactor(user *models.User) (*models.TwoFactorSetupResponse, error) {
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := database.DB.Model(user).Updates(map[string]interface{}{"totp_secret": secret, "totp_last_step": 0}).Error; err != nil {
+		return nil, err
+	}
+	return &models.TwoFactorSetupResponse{
+		Secret:     secret,
+		OTPAuthURI: totp.URI(totpIssuer(), user.Username, secret),
+	}, nil
+}
+
+// EnableTwoFactor turns on two-factor authentication when the code matches
+// the secret from BeginTwoFactor, and returns the user's recovery codes
+func EnableTwoFactor(user *models.User, code string) ([]string, error) {
+	if user.TOTPSecret == "" {
+		return nil, ErrInvalidCode
+	}
+	step, ok := totp.Validate(user.TOTPSecret, code, time.Now(), user.TOTPLastStep)
+	if !ok {
+		return nil, ErrInvalidCode
+	}
+
+	var codes []string
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(user).Updates(map[string]interface{}{"totp_enabled": true, "totp_last_step": step}).Error
+		if err != nil {
+			return err
+		}
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// DisableTwoFactor turns off two-factor authentication and deletes the
+// secret and recovery codes
+func DisableTwoFactor(user *models.User) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(user).Updates(map[string]interface{}{"totp_enabled": false, "totp_secret": "", "totp_last_step": 0}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{}).Error
+	})
+}
+
+// CheckSecondFactor checks a code from the user's authenticator or one of
+// their recovery codes. Either works only once. usedRecovery reports whether
+// a recovery code was used.
+func CheckSecondFactor(user *models.User, code string) (usedRecovery bool, err error) {
+	if !user.TOTPEnabled {
+		return false, ErrInvalidCode
+	}
+
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if len(code) == totp.Digits {
+		step, ok := totp.Validate(user.TOTPSecret, code, time.Now(), user.TOTPLastStep)
+		if !ok {
+			return false, ErrInvalidCode
+		}
+		// The condition makes concurrent logins with the same code succeed once
+		result := database.DB.Model(&models.User{}).
+			Where("id = ? AND totp_last_step < ?", user.ID, step).
+			Update("totp_last_step", step)
+		if result.Error != nil {
+			return false, result.Error
+		}
+		if result.RowsAffected == 0 {
+			return false, ErrInvalidCode
+		}
+		user.TOTPLastStep = step
+		return false, nil
+	}
+
+	result := database.DB.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND hash = ? AND used_at IS NULL", user.ID, hashRecoveryCode(code)).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, ErrInvalidCode
+	}
+	return true, nil
+}
+
+// NewRecoveryCodes replaces the recovery codes of a user
+func NewRecoveryCodes(user *models.User) ([]string, error) {
+	var codes []string
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	return codes, err
+}
+
+// RecoveryCodesLeft returns how many unused recovery codes a user has
+func RecoveryCodesLeft(userID uint) (int, error) {
+	var count int64
+	err := database.DB.Model(&models.RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userID).Count(&count).Error
+	return int(count), err
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	rows := make([]models.RecoveryCode, recoveryCodeCount)
+	for i := range codes {
+		bytes := make([]byte, 8)
+		if _, err := rand.Read(bytes); err != nil {
+			return nil, err
+		}
+		h := hex.EncodeToString(bytes)
+		codes[i] = h[0:4] + "-" + h[4:8] + "-" + h[8:12] + "-" + h[12:16]
+		rows[i] = models.RecoveryCode{UserID: userID, Hash: hashRecoveryCode(codes[i])}
+	}
+	if err := tx.Create(&rows).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// hashRecoveryCode returns the hex SHA-256 of a recovery code, ignoring case
+// and dashes. Codes carry 64 random bits, so they need no salt.
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.ReplaceAll(code, "-", ""))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewChallenge starts the second step of a login whose password was right
+// and returns its token
+func NewChallenge(user *models.User) (string, error) {
+	database.DB.Where("expires_at < ?", time.Now()).Delete(&models.LoginChallenge{})
+
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(bytes)
+	challenge := models.LoginChallenge{
+		TokenHash: hashToken(token),
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(challengeTTL),
+	}
+	if err := database.DB.Create(&challenge).Error; err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Challenge returns the pending login challenge of a token and its user
+func Challenge(token string) (*models.LoginChallenge, *models.User, error) {
+	var challenge models.LoginChallenge
+	err := database.DB.Where("token_hash = ? AND expires_at > ? AND attempts < ?", hashToken(token), time.Now(), maxChallengeAttempts).
+		Limit(1).Find(&challenge).Error
+	if err != nil {
+		return nil, nil, err
+	}
+	if challenge.ID == 0 {
+		return nil, nil, ErrInvalidChallenge
+	}
+
+	var user models.User
+	if err := database.DB.Limit(1).Find(&user, challenge.UserID).Error; err != nil {
+		return nil, nil, err
+	}
+	if user.ID == 0 || !user.IsActive {
+		return nil, nil, ErrInvalidChallenge
+	}
+	return &challenge, &user, nil
+}
+
+// AttemptChallenge counts an attempt at a challenge before its code is
+// checked, and returns ErrInvalidChallenge once there were too many. The
+// conditional update makes parallel attempts count one by one.
+func AttemptChallenge(challenge *models.LoginChallenge) error {
+	result := database.DB.Model(&models.LoginChallenge{}).
+		Where("id = ? AND attempts < ?", challenge.ID, maxChallengeAttempts).
+		Update("attempts", gorm.Expr("attempts + 1"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvalidChallenge
+	}
+	return nil
+}
+
+// CompleteChallenge ends a challenge whose code was right
+func CompleteChallenge(challenge *models.LoginChallenge) error {
+	return database.DB.Delete(challenge).Error
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Policy returns the security policy
+func Policy() (*models.SecurityPolicy, error) {
+	var policy models.SecurityPolicy
+	if err := database.DB.Limit(1).Find(&policy, 1).Error; err != nil {
+		return nil, err
+	}
+	policy.ID = 1
+	return &policy, nil
+}
+
+// SetPolicy changes the security policy
+func SetPolicy(require2FA bool, updatedBy string) (*models.SecurityPolicy, error) {
+	policy := models.SecurityPolicy{ID: 1, Require2FA: require2FA, UpdatedBy: updatedBy}
+	if err := database.DB.Save(&policy).Error; err != nil {
+		return nil, err
+	}
+
+	policyMu.Lock()
+	policyRequire, policyCachedAt = require2FA, time.Now()
+	policyMu.Unlock()
+	return &policy, nil
+}
+
+// TwoFactorRequired reports whether the policy makes two-factor
+// authentication mandatory
+func TwoFactorRequired() (bool, error) {
+	policyMu.Lock()
+	defer policyMu.Unlock()
+	if time.Since(policyCachedAt) < policyCacheTTL {
+		return policyRequire, nil
+	}
+
+	policy, err := Policy()
+	if err != nil {
+		return false, err
+	}
+	policyRequire, policyCachedAt = policy.Require2FA, time.Now()
+	return policyRequire, nil
+}
+
+// NeedsTwoFactorSetup reports whether the policy requires the user to set up
+// two-factor authentication before using the admin API. Single sign-on users
+// are left to their identity provider.
+func NeedsTwoFactorSetup(user *models.User) (bool, error) {
+	if user.TOTPEnabled || user.Provider == ProviderOIDC {
+		return false, nil
+	}
+	return TwoFactorRequired()
+}
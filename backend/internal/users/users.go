@@ -0,0 +1,190 @@
+package users
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/ollama-web-api/internal/database"
+	"github.com/ollama-web-api/internal/models"
+	"github.com/ollama-web-api/internal/roles"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// MinPasswordLength is the shortest password an admin may set
+const MinPasswordLength = 12
+
+// Providers a user can sign in with
+const (
+	ProviderLocal = "local"
+	ProviderOIDC  = "oidc"
+)
+
+// ErrInvalidCredentials is returned for an unknown user or a wrong password
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// ErrDisabled is returned when the credentials are right but the user is
+// disabled
+var ErrDisabled = errors.New("user is disabled")
+
+// ErrUsernameTaken is returned when a single sign-on user would get the
+// username of another user
+var ErrUsernameTaken = errors.New("username is taken by another user")
+
+// dummyHash is compared against when a user does not exist, so that unknown
+// and known usernames take as long to check
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+
+// HashPassword returns the bcrypt hash of a password
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// ValidatePassword checks that a new password is acceptable
+func ValidatePassword(password string) error {
+	if len(password) < MinPasswordLength {
+		return fmt.Errorf("password must be at least %d characters", MinPasswordLength)
+	}
+	if len(password) > 72 {
+		// bcrypt ignores everything after 72 bytes
+		return fmt.Errorf("password must be at most 72 bytes")
+	}
+	return nil
+}
+
+// Authenticate returns the user with the username and password
+func Authenticate(username, password string) (*models.User, error) {
+	var user models.User
+	err := database.DB.Where("username = ?", username).Limit(1).Find(&user).Error
+	if err != nil {
+		return nil, err
+	}
+
+	hash := dummyHash
+	if user.ID != 0 {
+		hash = []byte(user.PasswordHash)
+	}
+	if bcrypt.CompareHashAndPassword(hash, []byte(password)) != nil || user.ID == 0 {
+		return nil, ErrInvalidCredentials
+	}
+	if !user.IsActive {
+		return nil, ErrDisabled
+	}
+
+	now := time.Now()
+	if err := database.DB.Model(&user).Update("last_login_at", now).Error; err != nil {
+		log.Printf("Failed to record login of user %d: %v", user.ID, err)
+	}
+	return &user, nil
+}
+
+// SignInExternal returns the user of a single sign-on identity, creating it on
+// the first login. The role comes from the provider's role mappings on every
+// login, so changes in the provider take effect the next time the user signs
+// in. created reports whether the user is new.
+func SignInExternal(provider, externalID, username, role string) (user *models.User, created bool, err error) {
+	user = &models.User{}
+	if err := database.DB.Where("external_id = ?", externalID).Limit(1).Find(user).Error; err != nil {
+		return nil, false, err
+	}
+
+	now := time.Now()
+	if user.ID == 0 {
+		var existing int64
+		if err := database.DB.Model(&models.User{}).Where("username = ?", username).Count(&existing).Error; err != nil {
+			return nil, false, err
+		}
+		if existing > 0 {
+			return nil, false, ErrUsernameTaken
+		}
+		user = &models.User{
+			Username:    username,
+			Role:        role,
+			Provider:    provider,
+			ExternalID:  &externalID,
+			IsActive:    true,
+			LastLoginAt: &now,
+			CreatedBy:   provider,
+		}
+		if err := database.DB.Create(user).Error; err != nil {
+			return nil, false, err
+		}
+		return user, true, nil
+	}
+
+	if !user.IsActive {
+		return nil, false, ErrDisabled
+	}
+	user.Role = role
+	user.LastLoginAt = &now
+	if err := database.DB.Model(user).Updates(map[string]interface{}{"role": role, "last_login_at": now}).Error; err != nil {
+		return nil, false, err
+	}
+	return user, false, nil
+}
+
+// Bootstrap creates the first user from ADMIN_USER and ADMIN_PASSWORD as an
+// owner when there are no users yet. Afterwards the variables are ignored and
+// users are managed through the API.
+func Bootstrap() error {
+	var count int64
+	if err := database.DB.Model(&models.User{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ensureOwner()
+	}
+
+	username := os.Getenv("ADMIN_USER")
+	password := os.Getenv("ADMIN_PASSWORD")
+	if username == "" || password == "" {
+		log.Println("No users exist; set ADMIN_USER and ADMIN_PASSWORD to create the first one")
+		return nil
+	}
+	if err := ValidatePassword(password); err != nil {
+		log.Printf("Warning: ADMIN_PASSWORD is weak (%v); change it after logging in", err)
+	}
+
+	hash, err := HashPassword(password)
+	if err != nil {
+		return err
+	}
+	user := models.User{Username: username, PasswordHash: hash, Role: roles.Owner, Provider: ProviderLocal, IsActive: true}
+	if err := database.DB.Create(&user).Error; err != nil {
+		return err
+	}
+	log.Printf("Created user %q from ADMIN_USER", username)
+	return nil
+}
+
+// ensureOwner makes the oldest active user an owner when there is no active
+// owner, such as after upgrading from a version without roles, so that users
+// can still be managed
+func ensureOwner() error {
+	var owners int64
+	if err := database.DB.Model(&models.User{}).Where("role = ? AND is_active = ?", roles.Owner, true).Count(&owners).Error; err != nil {
+		return err
+	}
+	if owners > 0 {
+		return nil
+	}
+
+	var user models.User
+	if err := database.DB.Where("is_active = ?", true).Order("id").Limit(1).Find(&user).Error; err != nil {
+		return err
+	}
+	if user.ID == 0 {
+		return nil
+	}
+	if err := database.DB.Model(&user).Update("role", roles.Owner).Error; err != nil {
+		return err
+	}
+	log.Printf("Made user %q an owner, since there was no active owner", user.Username)
+	return nil
+}
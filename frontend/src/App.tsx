@@ -5,13 +5,22 @@ import Dashboard from './components/Dashboard';
 import Projects from './components/Projects';
 import Models from './components/Models';
 import Chat from './components/Chat';
+import Security from './components/Security';
 import Navigation from './components/Navigation';
+import { logout, storeTokens } from './api';
 
 function App() {
   const [isAuthenticated, setIsAuthenticated] = useState<boolean>(false);
   const [sidebarOpen, setSidebarOpen] = useState<boolean>(false);
 
   useEffect(() => {
+    // A single sign-on login comes back with #token=...
+    const params = new URLSearchParams(window.location.hash.slice(1));
+    const ssoToken = params.get('token');
+    if (ssoToken) {
+      storeTokens(ssoToken, params.get('refresh_token'));
+      window.history.replaceState(null, '', window.location.pathname);
+    }
     const token = localStorage.getItem('token');
     setIsAuthenticated(!!token);
   }, []);
@@ -31,8 +40,7 @@ function App() {
   };
 
   const handleLogout = () => {
-    localStorage.removeItem('token');
-    setIsAuthenticated(false);
+    logout().then(() => setIsAuthenticated(false));
   };
 
   const toggleSidebar = () => {
@@ -54,6 +62,7 @@ function App() {
             <Route path="/projects" element={<Projects />} />
             <Route path="/models" element={<Models />} />
             <Route path="/chat" element={<Chat />} />
+            <Route path="/security" element={<Security />} />
             <Route path="*" element={<Navigate to="/" replace />} />
           </Routes>
         </main>
@@ -15,33 +15,188 @@ api.interceptors.request.use((config) => {
   return config;
 });
 
-// On 401 responses, clear the stored token and notify the app
+// Stores the tokens of a login or refresh
+export const storeTokens = (token: string, refreshToken?: string | null) => {
+  localStorage.setItem('token', token);
+  if (refreshToken) {
+    localStorage.setItem('refresh_token', refreshToken);
+  }
+};
+
+const clearTokens = () => {
+  localStorage.removeItem('token');
+  localStorage.removeItem('refresh_token');
+};
+
+// Concurrent 401s share one refresh, since each refresh token works once
+let refreshing: Promise<boolean> | null = null;
+
+const refreshTokens = (): Promise<boolean> => {
+  const refreshToken = localStorage.getItem('refresh_token');
+  if (!refreshToken) {
+    return Promise.resolve(false);
+  }
+  if (!refreshing) {
+    refreshing = axios
+      .post(`${API_BASE_URL}/auth/refresh`, { refresh_token: refreshToken })
+      .then((response) => {
+        storeTokens(response.data.token, response.data.refresh_token);
+        return true;
+      })
+      .catch(() => false)
+      .finally(() => {
+        refreshing = null;
+      });
+  }
+  return refreshing;
+};
+
+// On 401 responses, try once with refreshed tokens; otherwise clear the
+// stored tokens and notify the app
 api.interceptors.response.use(
   (response) => response,
-  (error) => {
+  async (error) => {
+    const config = error.config;
+    if (error.response?.status === 401 && config && !config._retried && !config.url?.startsWith('/auth/')) {
+      config._retried = true;
+      if (await refreshTokens()) {
+        return api(config);
+      }
+    }
+    // The security policy requires two-factor authentication before anything else
+    if (error.response?.data?.code === 'two_factor_setup_required' && window.location.pathname !== '/security') {
+      window.location.assign('/security');
+    }
     if (error.response?.status === 401 || error?.response.status === 502) {
-      localStorage.removeItem('token');
+      clearTokens();
       window.dispatchEvent(new Event('auth:unauthorized'));
     }
     return Promise.reject(error);
   }
 );
 
+export interface GenerationSettings {
+  default_system?: string;
+  default_temperature?: number;
+  default_num_ctx?: number;
+  max_num_predict?: number;
+  max_num_ctx?: number;
+  max_prompt_length?: number;
+  max_images?: number;
+  clamp_to_limits?: boolean;
+}
+
+export interface QuotaSettings {
+  daily_soft_limit?: number;
+  daily_hard_limit?: number;
+  monthly_soft_limit?: number;
+  monthly_hard_limit?: number;
+}
+
+export interface QuotaStatus {
+  period: 'daily' | 'monthly';
+  period_start: string;
+  resets_at: string;
+  used: number;
+  top_up: number;
+  soft_limit?: number;
+  hard_limit?: number;
+  remaining?: number;
+  soft_exceeded: boolean;
+  hard_exceeded: boolean;
+}
+
+export interface SchedulingSettings {
+  priority?: 'high' | 'normal' | 'low';
+  weight?: number;
+}
+
+export interface QueueStatus {
+  model: string;
+  waiting: number;
+  in_flight: number;
+  oldest_wait_seconds: number;
+  projects: {
+    project_id: number;
+    priority?: string;
+    waiting: number;
+    in_flight: number;
+  }[];
+}
+
 export interface Project {
   id: number;
   name: string;
   description: string;
-  api_key: string;
+  api_key?: string; // only returned when the project is created
+  api_keys?: APIKey[];
   is_active: boolean;
+  settings?: GenerationSettings;
+  quotas?: QuotaSettings;
+  scheduling?: SchedulingSettings;
+  webhook_url?: string;
+  webhook_secret?: string; // only returned when the secret is created or rotated
+  allowed_cidrs?: string[]; // networks the project's keys work from; empty allows all
   models?: ProjectModel[];
   created_at: string;
   updated_at: string;
 }
 
+export interface APIKey {
+  id: number;
+  project_id: number;
+  name: string;
+  key?: string; // only returned when the key is created or rotated
+  prefix: string;
+  scopes?: string[]; // generate, chat, embed, models:read or batches; empty allows all
+  models?: string[]; // subset of the project's models; empty allows all
+  allowed_cidrs?: string[];
+  created_by?: string;
+  expires_at?: string;
+  last_used_at?: string;
+  revoked_at?: string;
+  created_at: string;
+}
+
+export type Role = 'viewer' | 'operator' | 'admin' | 'owner';
+
+export interface User {
+  id: number;
+  username: string;
+  role: Role;
+  provider: 'local' | 'oidc';
+  totp_enabled: boolean;
+  is_active: boolean;
+  last_login_at?: string;
+  created_by?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface AuditEvent {
+  id: number;
+  action: string;
+  actor?: string;
+  project_id?: number;
+  api_key_id?: number;
+  ip?: string;
+  detail?: string;
+  created_at: string;
+}
+
+export interface AuditQuery {
+  action?: string;
+  project_id?: number;
+  from?: string;
+  to?: string;
+  limit?: number;
+}
+
 export interface ProjectModel {
   id: number;
   project_id: number;
   model_name: string;
+  settings?: GenerationSettings;
   created_at: string;
 }
 
@@ -51,10 +206,28 @@ export interface OllamaModel {
   size: number;
 }
 
+export interface GenerateOptions {
+  temperature?: number;
+  top_p?: number;
+  top_k?: number;
+  num_ctx?: number;
+  num_predict?: number;
+  seed?: number;
+  stop?: string[];
+  [key: string]: unknown;
+}
+
 export interface OllamaRequest {
   model: string;
   prompt: string;
   stream?: boolean;
+  format?: 'json' | Record<string, unknown>;
+  options?: GenerateOptions;
+  system?: string;
+  template?: string;
+  raw?: boolean;
+  context?: number[];
+  keep_alive?: string | number;
 }
 
 export interface OllamaResponse {
@@ -64,12 +237,86 @@ export interface OllamaResponse {
   done: boolean;
 }
 
+export interface GenerationJob {
+  id: string;
+  project_id: number;
+  model: string;
+  status: 'queued' | 'running' | 'succeeded' | 'failed' | 'cancelled';
+  response?: OllamaResponse;
+  error?: string;
+  webhook_status?: 'delivered' | 'failed';
+  created_at: string;
+  started_at?: string;
+  completed_at?: string;
+}
+
+export interface Batch {
+  id: string;
+  project_id: number;
+  filename?: string;
+  status: 'queued' | 'in_progress' | 'completed' | 'failed' | 'cancelled';
+  total: number;
+  completed: number;
+  failed: number;
+  error?: string;
+  created_at: string;
+  started_at?: string;
+  completed_at?: string;
+}
+
+export interface UsageSummary {
+  bucket: string;
+  project_id: number;
+  project_name: string;
+  model: string;
+  requests: number;
+  errors: number;
+  prompt_tokens: number;
+  completion_tokens: number;
+  total_tokens: number;
+  total_duration: number;
+  eval_duration: number;
+}
+
+export interface UsageQuery {
+  from?: string;
+  to?: string;
+  bucket?: 'hour' | 'day' | 'month';
+  project_id?: number;
+  model?: string;
+}
+
 // Auth API
 export const login = async (username: string, password: string) => {
   const response = await api.post('/auth/login', { username, password });
   return response.data;
 };
 
+// Ends the session on the server; the tokens are dropped even when that fails
+export const logout = async () => {
+  try {
+    await api.post('/auth/logout');
+  } catch {
+    // The session expires on its own
+  }
+  clearTokens();
+};
+
+// Completes a login that returned two_factor_required with a code from the
+// authenticator app or a recovery code
+export const verifyTwoFactor = async (challenge: string, code: string) => {
+  const response = await api.post('/auth/2fa/verify', { challenge, code });
+  return response.data;
+};
+
+export const getOIDCConfig = async (): Promise<{ enabled: boolean }> => {
+  const response = await api.get('/auth/oidc/config');
+  return response.data;
+};
+
+// The browser navigates here to log in through single sign-on
+export const oidcLoginURL = `${API_BASE_URL}/auth/oidc/login`;
+
 // Project API
 export const listProjects = async (): Promise<Project[]> => {
   const response = await api.get('/projects');
@@ -96,6 +343,11 @@ export const toggleProjectStatus = async (id: number): Promise<Project> => {
   return response.data;
 };
 
+export const rotateWebhookSecret = async (id: number): Promise<Project> => {
+  const response = await api.post(`/projects/${id}/webhook/rotate`);
+  return response.data;
+};
+
 export const deleteProject = async (id: number): Promise<void> => {
   await api.delete(`/projects/${id}`);
 };
@@ -111,11 +363,216 @@ export const assignModel = async (projectId: number, modelName: string): Promise
   return response.data;
 };
 
+export const updateModelSettings = async (
+  projectId: number,
+  modelId: number,
+  settings: GenerationSettings
+): Promise<ProjectModel> => {
+  const response = await api.put(`/projects/${projectId}/models/${modelId}`, settings);
+  return response.data;
+};
+
 export const unassignModel = async (projectId: number, modelId: number): Promise<void> => {
   await api.delete(`/projects/${projectId}/models/${modelId}`);
 };
 
+// API Keys
+export const listAPIKeys = async (projectId: number): Promise<APIKey[]> => {
+  const response = await api.get(`/projects/${projectId}/keys`);
+  return response.data;
+};
+
+export const createAPIKey = async (
+  projectId: number,
+  name: string,
+  expiresAt?: string,
+  scopes?: string[],
+  models?: string[]
+): Promise<APIKey> => {
+  const response = await api.post(`/projects/${projectId}/keys`, { name, expires_at: expiresAt, scopes, models });
+  return response.data;
+};
+
+export const revokeAPIKey = async (projectId: number, keyId: number): Promise<void> => {
+  await api.delete(`/projects/${projectId}/keys/${keyId}`);
+};
+
+export const rotateAPIKey = async (
+  projectId: number,
+  keyId: number,
+  gracePeriod?: string,
+  expiresAt?: string
+): Promise<APIKey> => {
+  const response = await api.post(`/projects/${projectId}/keys/${keyId}/rotate`, {
+    grace_period: gracePeriod,
+    expires_at: expiresAt,
+  });
+  return response.data;
+};
+
+export const getProjectQuota = async (projectId: number): Promise<QuotaStatus[]> => {
+  const response = await api.get(`/projects/${projectId}/quota`);
+  return response.data;
+};
+
+export const grantQuotaTopUp = async (
+  projectId: number,
+  period: 'daily' | 'monthly',
+  tokens: number,
+  note?: string
+) => {
+  const response = await api.post(`/projects/${projectId}/quota/topups`, { period, tokens, note });
+  return response.data;
+};
+
+// Usage API
+export const getUsage = async (query: UsageQuery = {}): Promise<UsageSummary[]> => {
+  const response = await api.get('/usage', { params: query });
+  return response.data;
+};
+
+export const exportUsage = async (query: UsageQuery = {}): Promise<Blob> => {
+  const response = await api.get('/usage/export', { params: query, responseType: 'blob' });
+  return response.data;
+};
+
+// Users API
+export const listUsers = async (): Promise<User[]> => {
+  const response = await api.get('/users');
+  return response.data;
+};
+
+export const createUser = async (username: string, password: string, role?: Role): Promise<User> => {
+  const response = await api.post('/users', { username, password, role });
+  return response.data;
+};
+
+export const updateUserRole = async (id: number, role: Role): Promise<User> => {
+  const response = await api.put(`/users/${id}/role`, { role });
+  return response.data;
+};
+
+export const toggleUserStatus = async (id: number): Promise<User> => {
+  const response = await api.patch(`/users/${id}/toggle`);
+  return response.data;
+};
+
+export const resetUserPassword = async (id: number, password: string): Promise<void> => {
+  await api.post(`/users/${id}/password`, { password });
+};
+
+export const resetUserTwoFactor = async (id: number): Promise<void> => {
+  await api.delete(`/users/${id}/2fa`);
+};
+
+// Two-factor API
+export interface TwoFactorStatus {
+  enabled: boolean;
+  required: boolean;
+  recovery_codes_left: number;
+}
+
+export interface TwoFactorSetup {
+  secret: string;
+  otpauth_uri: string; // shown as a QR code by authenticator apps
+}
+
+export interface SecurityPolicy {
+  require_2fa: boolean;
+  updated_by?: string;
+  updated_at?: string;
+}
+
+export const getTwoFactorStatus = async (): Promise<TwoFactorStatus> => {
+  const response = await api.get('/auth/2fa');
+  return response.data;
+};
+
+export const setupTwoFactor = async (): Promise<TwoFactorSetup> => {
+  const response = await api.post('/auth/2fa/setup');
+  return response.data;
+};
+
+// Returns the recovery codes, which are only shown once
+export const enableTwoFactor = async (code: string): Promise<string[]> => {
+  const response = await api.post('/auth/2fa/enable', { code });
+  return response.data.recovery_codes;
+};
+
+export const disableTwoFactor = async (code: string): Promise<void> => {
+  await api.post('/auth/2fa/disable', { code });
+};
+
+export const regenerateRecoveryCodes = async (code: string): Promise<string[]> => {
+  const response = await api.post('/auth/2fa/recovery-codes', { code });
+  return response.data.recovery_codes;
+};
+
+export const getSecurityPolicy = async (): Promise<SecurityPolicy> => {
+  const response = await api.get('/security/policy');
+  return response.data;
+};
+
+export const updateSecurityPolicy = async (require2FA: boolean): Promise<SecurityPolicy> => {
+  const response = await api.put('/security/policy', { require_2fa: require2FA });
+  return response.data;
+};
+
+// Sessions API
+export interface Session {
+  id: number;
+  user_id: number;
+  user?: User;
+  ip?: string;
+  user_agent?: string;
+  expires_at: string;
+  last_used_at: string;
+  created_at: string;
+}
+
+export const listSessions = async (userId?: number): Promise<Session[]> => {
+  const response = await api.get('/sessions', { params: userId ? { user_id: userId } : undefined });
+  return response.data;
+};
+
+export const revokeSession = async (id: number) => {
+  const response = await api.delete(`/sessions/${id}`);
+  return response.data;
+};
+
+// Login lockouts API
+export interface LoginThrottle {
+  id: number;
+  kind: 'ip' | 'username';
+  key: string;
+  failures: number;
+  last_failure_at: string;
+  blocked_until: string;
+  locked: boolean;
+}
+
+export const listLockouts = async (): Promise<LoginThrottle[]> => {
+  const response = await api.get('/lockouts');
+  return response.data;
+};
+
+export const clearLockout = async (id: number) => {
+  const response = await api.delete(`/lockouts/${id}`);
+  return response.data;
+};
+
+// Audit API
+export const listAuditEvents = async (query: AuditQuery = {}): Promise<AuditEvent[]> => {
+  const response = await api.get('/audit', { params: query });
+  return response.data;
+};
+
 // Ollama API
+export const getQueueStatus = async (): Promise<QueueStatus[]> => {
+  const response = await api.get('/queue');
+  return response.data;
+};
+
 export const listOllamaModels = async () => {
   const response = await api.get('/ollama/models');
   return response.data;
@@ -131,6 +588,11 @@ export const pullOllamaModel = async (modelName: string) => {
   return response.data;
 };
 
+export const unloadOllamaModel = async (modelName: string) => {
+  const response = await api.post('/ollama/models/unload', { name: modelName });
+  return response.data;
+};
+
 export const deleteOllamaModel = async (modelName: string) => {
   const response = await api.delete('/ollama/models/delete', { data: { name: modelName } });
   return response.data;
@@ -145,6 +607,73 @@ export const generateText = async (apiKey: string, request: OllamaRequest): Prom
   return response.data;
 };
 
+export const createGenerationJob = async (apiKey: string, request: OllamaRequest): Promise<GenerationJob> => {
+  const response = await axios.post(`${API_BASE_URL}/ollama/jobs`, request, {
+    headers: {
+      'X-API-Key': apiKey,
+    },
+  });
+  return response.data;
+};
+
+export const getGenerationJob = async (apiKey: string, id: string): Promise<GenerationJob> => {
+  const response = await axios.get(`${API_BASE_URL}/ollama/jobs/${id}`, {
+    headers: {
+      'X-API-Key': apiKey,
+    },
+  });
+  return response.data;
+};
+
+export const cancelGenerationJob = async (apiKey: string, id: string): Promise<GenerationJob> => {
+  const response = await axios.post(`${API_BASE_URL}/ollama/jobs/${id}/cancel`, null, {
+    headers: {
+      'X-API-Key': apiKey,
+    },
+  });
+  return response.data;
+};
+
+export const createBatch = async (apiKey: string, file: File): Promise<Batch> => {
+  const form = new FormData();
+  form.append('file', file);
+  const response = await axios.post(`${API_BASE_URL}/ollama/batches`, form, {
+    headers: {
+      'X-API-Key': apiKey,
+    },
+  });
+  return response.data;
+};
+
+export const getBatch = async (apiKey: string, id: string): Promise<Batch> => {
+  const response = await axios.get(`${API_BASE_URL}/ollama/batches/${id}`, {
+    headers: {
+      'X-API-Key': apiKey,
+    },
+  });
+  return response.data;
+};
+
+export const cancelBatch = async (apiKey: string, id: string): Promise<Batch> => {
+  const response = await axios.post(`${API_BASE_URL}/ollama/batches/${id}/cancel`, null, {
+    headers: {
+      'X-API-Key': apiKey,
+    },
+  });
+  return response.data;
+};
+
+// Downloads the JSONL results of a batch as a Blob
+export const downloadBatchResults = async (apiKey: string, id: string): Promise<Blob> => {
+  const response = await axios.get(`${API_BASE_URL}/ollama/batches/${id}/results`, {
+    headers: {
+      'X-API-Key': apiKey,
+    },
+    responseType: 'blob',
+  });
+  return response.data;
+};
+
 // Streamed generate endpoint with attachments support.
 // onChunk will be called for each decoded text chunk received from the server.
 export const streamGenerate = async (
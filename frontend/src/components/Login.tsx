@@ -1,5 +1,5 @@
-import React, { useState } from 'react';
-import { login } from '../api';
+import React, { useState, useEffect } from 'react';
+import { login, verifyTwoFactor, storeTokens, getOIDCConfig, oidcLoginURL } from '../api';
 
 interface LoginProps {
   onLogin: () => void;
@@ -8,8 +8,22 @@ interface LoginProps {
 const Login: React.FC<LoginProps> = ({ onLogin }) => {
   const [username, setUsername] = useState('');
   const [password, setPassword] = useState('');
-  const [error, setError] = useState('');
+  // A failed single sign-on login comes back with #error=...
+  const [error, setError] = useState(() => new URLSearchParams(window.location.hash.slice(1)).get('error') || '');
   const [loading, setLoading] = useState(false);
+  const [ssoEnabled, setSsoEnabled] = useState(false);
+  // Set when the password was right and a two-factor code is needed
+  const [challenge, setChallenge] = useState('');
+  const [code, setCode] = useState('');
+
+  useEffect(() => {
+    if (window.location.hash) {
+      window.history.replaceState(null, '', window.location.pathname);
+    }
+    getOIDCConfig()
+      .then((config) => setSsoEnabled(config.enabled))
+      .catch(() => setSsoEnabled(false));
+  }, []);
 
   const handleSubmit = async (e: React.FormEvent) => {
     e.preventDefault();
@@ -17,11 +31,26 @@ const Login: React.FC<LoginProps> = ({ onLogin }) => {
     setLoading(true);
 
     try {
-      const response = await login(username, password);
-      localStorage.setItem('token', response.token);
+      const response = challenge
+        ? await verifyTwoFactor(challenge, code)
+        : await login(username, password);
+      if (response.two_factor_required) {
+        setChallenge(response.challenge);
+        return;
+      }
+      storeTokens(response.token, response.refresh_token);
       onLogin();
     } catch (err: any) {
-      setError(err.response?.data?.error || 'Login failed');
+      if (challenge && err.response?.data?.error === 'Invalid challenge') {
+        // Too many wrong codes or too slow; start over with the password
+        setChallenge('');
+        setCode('');
+        setError(err.response.data.message);
+      } else if (err.response?.status === 429) {
+        setError(`${err.response.data.error}. ${err.response.data.message}.`);
+      } else {
+        setError(err.response?.data?.error || 'Login failed');
+      }
     } finally {
       setLoading(false);
     }
@@ -43,28 +72,46 @@ const Login: React.FC<LoginProps> = ({ onLogin }) => {
         {error && <div className="error-message">{error}</div>}
         
         <form onSubmit={handleSubmit}>
-          <div className="form-group">
-            <label className="form-label">Username</label>
-            <input
-              type="text"
-              className="input"
-              value={username}
-              onChange={(e) => setUsername(e.target.value)}
-              required
-              autoFocus
-            />
-          </div>
+          {challenge ? (
+            <div className="form-group">
+              <label className="form-label">Two-factor code</label>
+              <input
+                type="text"
+                className="input"
+                value={code}
+                onChange={(e) => setCode(e.target.value)}
+                placeholder="Code from your authenticator app or a recovery code"
+                autoComplete="one-time-code"
+                required
+                autoFocus
+              />
+            </div>
+          ) : (
+            <>
+              <div className="form-group">
+                <label className="form-label">Username</label>
+                <input
+                  type="text"
+                  className="input"
+                  value={username}
+                  onChange={(e) => setUsername(e.target.value)}
+                  required
+                  autoFocus
+                />
+              </div>
           
-          <div className="form-group">
-            <label className="form-label">Password</label>
-            <input
-              type="password"
-              className="input"
-              value={password}
-              onChange={(e) => setPassword(e.target.value)}
-              required
-            />
-          </div>
+              <div className="form-group">
+                <label className="form-label">Password</label>
+                <input
+                  type="password"
+                  className="input"
+                  value={password}
+                  onChange={(e) => setPassword(e.target.value)}
+                  required
+                />
+              </div>
+            </>
+          )}
           
           <button 
             type="submit" 
@@ -72,9 +119,19 @@ const Login: React.FC<LoginProps> = ({ onLogin }) => {
             style={{ width: '100%', marginTop: '16px' }}
             disabled={loading}
           >
-            {loading ? 'Logging in...' : 'Login'}
+            {loading ? 'Logging in...' : challenge ? 'Verify' : 'Login'}
           </button>
         </form>
+
+        {ssoEnabled && (
+          <a
+            href={oidcLoginURL}
+            className="button button-glass"
+            style={{ display: 'block', width: '100%', marginTop: '12px', textAlign: 'center', textDecoration: 'none' }}
+          >
+            Sign in with SSO
+          </a>
+        )}
       </div>
     </div>
   );
@@ -41,6 +41,13 @@ const ChatIcon = () => (
   </svg>
 );
 
+const SecurityIcon = () => (
+  <svg width="20" height="20" viewBox="0 0 24 24" fill="none" stroke="currentColor" strokeWidth="2">
+    <rect x="5" y="11" width="14" height="10" rx="2"/>
+    <path d="M8 11V7a4 4 0 018 0v4"/>
+  </svg>
+);
+
 const LogoutIcon = () => (
   <svg width="20" height="20" viewBox="0 0 24 24" fill="none" stroke="currentColor" strokeWidth="2">
     <path d="M17 16l4-4m0 0l-4-4m4 4H7m6 4v1a3 3 0 01-3 3H6a3 3 0 01-3-3V7a3 3 0 013-3h4a3 3 0 013 3v1"/>
@@ -96,6 +103,16 @@ const Navigation: React.FC<NavigationProps> = ({ onLogout, isOpen = false, onTog
           </span>
           <span className="sidebar-text">Models</span>
         </Link>
+
+        <Link
+          to="/security"
+          className={`sidebar-link ${location.pathname === '/security' ? 'active' : ''}`}
+        >
+          <span className="sidebar-icon">
+            <SecurityIcon />
+          </span>
+          <span className="sidebar-text">Security</span>
+        </Link>
       </div>
 
       <div className="sidebar-footer">
@@ -23,6 +23,7 @@ const Projects: React.FC = () => {
   const [formData, setFormData] = useState({ name: '', description: '' });
   const [loading, setLoading] = useState(true);
   const [error, setError] = useState('');
+  const [createdKey, setCreatedKey] = useState<{ project: string; key: string } | null>(null);
 
   useEffect(() => {
     loadProjects();
@@ -51,7 +52,10 @@ const Projects: React.FC = () => {
 
   const handleCreate = async () => {
     try {
-      await createProject(formData.name, formData.description);
+      const project = await createProject(formData.name, formData.description);
+      if (project.api_key) {
+        setCreatedKey({ project: project.name, key: project.api_key });
+      }
       setShowCreateModal(false);
       setFormData({ name: '', description: '' });
       loadProjects();
@@ -171,7 +175,11 @@ const Projects: React.FC = () => {
                   </td>
                   <td>{project.models?.length || 0}</td>
                   <td>
-                    <code style={{ fontSize: '12px' }}>{project.api_key.substring(0, 20)}...</code>
+                    {project.api_keys?.map((key) => (
+                      <div key={key.id}>
+                        <code style={{ fontSize: '12px' }}>{key.prefix}...</code> {key.name}
+                      </div>
+                    ))}
                   </td>
                   <td>
                     <div style={{ display: 'flex', gap: '8px' }}>
@@ -200,6 +208,29 @@ const Projects: React.FC = () => {
       </div>
 
       {/* Create Modal */}
+      {createdKey && (
+        <div className="modal-overlay" onClick={() => setCreatedKey(null)}>
+          <div className="modal" onClick={(e) => e.stopPropagation()}>
+            <div className="modal-header">
+              <h2 className="modal-title">API Key for {createdKey.project}</h2>
+              <button className="modal-close" onClick={() => setCreatedKey(null)}>&times;</button>
+            </div>
+            <p>Copy this key now. Only a hash is stored, so it cannot be shown again.</p>
+            <div className="form-group">
+              <input type="text" className="input" value={createdKey.key} readOnly onFocus={(e) => e.target.select()} />
+            </div>
+            <div className="modal-footer">
+              <button className="button button-secondary" onClick={() => navigator.clipboard.writeText(createdKey.key)}>
+                Copy
+              </button>
+              <button className="button button-primary" onClick={() => setCreatedKey(null)}>
+                Done
+              </button>
+            </div>
+          </div>
+        </div>
+      )}
+
       {showCreateModal && (
         <div className="modal-overlay" onClick={() => setShowCreateModal(false)}>
           <div className="modal" onClick={(e) => e.stopPropagation()}>
@@ -0,0 +1,214 @@
+import React, { useState, useEffect, useCallback } from 'react';
+import {
+  getTwoFactorStatus,
+  setupTwoFactor,
+  enableTwoFactor,
+  disableTwoFactor,
+  regenerateRecoveryCodes,
+  getSecurityPolicy,
+  updateSecurityPolicy,
+  TwoFactorStatus,
+  TwoFactorSetup,
+  SecurityPolicy,
+} from '../api';
+
+const Security: React.FC = () => {
+  const [status, setStatus] = useState<TwoFactorStatus | null>(null);
+  // Only admins can see the policy
+  const [policy, setPolicy] = useState<SecurityPolicy | null>(null);
+  const [setup, setSetup] = useState<TwoFactorSetup | null>(null);
+  const [recoveryCodes, setRecoveryCodes] = useState<string[]>([]);
+  const [code, setCode] = useState<string>('');
+  const [loading, setLoading] = useState<boolean>(true);
+  const [busy, setBusy] = useState<boolean>(false);
+  const [error, setError] = useState<string>('');
+
+  const loadData = useCallback(async () => {
+    try {
+      setStatus(await getTwoFactorStatus());
+    } catch (err: any) {
+      setError(err.response?.data?.message || 'Failed to fetch two-factor status');
+    }
+    try {
+      setPolicy(await getSecurityPolicy());
+    } catch {
+      setPolicy(null);
+    }
+    setLoading(false);
+  }, []);
+
+  useEffect(() => {
+    loadData();
+  }, [loadData]);
+
+  // Runs an action that needs a code, then reloads the status
+  const run = async (action: () => Promise<void>) => {
+    setBusy(true);
+    setError('');
+    try {
+      await action();
+      setCode('');
+      await loadData();
+    } catch (err: any) {
+      setError(err.response?.data?.message || 'Request failed');
+    } finally {
+      setBusy(false);
+    }
+  };
+
+  const handleSetup = () => run(async () => {
+    setRecoveryCodes([]);
+    setSetup(await setupTwoFactor());
+  });
+
+  const handleEnable = () => run(async () => {
+    setRecoveryCodes(await enableTwoFactor(code));
+    setSetup(null);
+  });
+
+  const handleDisable = () => run(async () => {
+    await disableTwoFactor(code);
+    setRecoveryCodes([]);
+  });
+
+  const handleRegenerate = () => run(async () => {
+    setRecoveryCodes(await regenerateRecoveryCodes(code));
+  });
+
+  const handlePolicy = (require2FA: boolean) => run(async () => {
+    setPolicy(await updateSecurityPolicy(require2FA));
+  });
+
+  if (loading) {
+    return (
+      <div className="page-container">
+        <h1>Security</h1>
+        <div className="loading">Loading security settings...</div>
+      </div>
+    );
+  }
+
+  const codeInput = (placeholder: string) => (
+    <input
+      type="text"
+      value={code}
+      onChange={(e) => setCode(e.target.value)}
+      placeholder={placeholder}
+      autoComplete="one-time-code"
+      className="input"
+      style={{ flex: 1 }}
+    />
+  );
+
+  return (
+    <div className="page-container">
+      <div className="page-header">
+        <h1>Security</h1>
+      </div>
+
+      {error && (
+        <div className="error-message">
+          {error}
+        </div>
+      )}
+
+      {status?.required && !status.enabled && (
+        <div className="error-message">
+          Two-factor authentication is required. Set it up to continue using the admin API.
+        </div>
+      )}
+
+      {recoveryCodes.length > 0 && (
+        <div className="success-message">
+          <p>Store these recovery codes somewhere safe. Each one replaces a code from your authenticator app once, and they are not shown again.</p>
+          <pre>{recoveryCodes.join('\n')}</pre>
+        </div>
+      )}
+
+      <div className="card">
+        <h2>Two-Factor Authentication</h2>
+        {status?.enabled ? (
+          <>
+            <p>
+              Two-factor authentication is on. {status.recovery_codes_left} recovery codes left.
+            </p>
+            <div style={{ display: 'flex', gap: '10px', alignItems: 'center' }}>
+              {codeInput('Code from your authenticator app or a recovery code')}
+              <button
+                onClick={handleRegenerate}
+                disabled={busy || !code.trim()}
+                className="button button-secondary"
+              >
+                New Recovery Codes
+              </button>
+              {!status.required && (
+                <button
+                  onClick={handleDisable}
+                  disabled={busy || !code.trim()}
+                  className="button button-danger"
+                >
+                  Disable
+                </button>
+              )}
+            </div>
+          </>
+        ) : setup ? (
+          <>
+            <p>
+              Add this account to your authenticator app by opening the link on your phone or entering the secret, then enter the code it shows.
+            </p>
+            <p>
+              <a href={setup.otpauth_uri}>{setup.otpauth_uri}</a>
+            </p>
+            <p>
+              Secret: <code>{setup.secret}</code>
+            </p>
+            <div style={{ display: 'flex', gap: '10px', alignItems: 'center' }}>
+              {codeInput('6-digit code')}
+              <button
+                onClick={handleEnable}
+                disabled={busy || !code.trim()}
+                className="button button-primary"
+              >
+                Enable
+              </button>
+            </div>
+          </>
+        ) : (
+          <>
+            <p>Protect your account with a code from an authenticator app in addition to your password.</p>
+            <button
+              onClick={handleSetup}
+              disabled={busy}
+              className="button button-primary"
+            >
+              Set Up
+            </button>
+          </>
+        )}
+      </div>
+
+      {policy && (
+        <div className="card">
+          <h2>Security Policy</h2>
+          <label style={{ display: 'flex', gap: '10px', alignItems: 'center' }}>
+            <input
+              type="checkbox"
+              checked={policy.require_2fa}
+              onChange={(e) => handlePolicy(e.target.checked)}
+              disabled={busy}
+            />
+            Require two-factor authentication for all users (single sign-on users are exempt)
+          </label>
+          {policy.updated_by && (
+            <p style={{ fontSize: '14px', color: '#666', marginTop: '8px' }}>
+              Last changed by {policy.updated_by}
+            </p>
+          )}
+        </div>
+      )}
+    </div>
+  );
+};
+
+export default Security;